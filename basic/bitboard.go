@@ -2,83 +2,131 @@ package basic
 
 import "math/bits"
 
-// Bitboard is a set representing the 8x8 chess board squares
-type Bitboard uint64
+// Bitboard is a set representing the board squares
+// squares 0 - 63 are stored in Lo, squares 64 - 127 are stored in Hi
+type Bitboard struct {
+	Lo uint64
+	Hi uint64
+}
+
+// BbEmpty is the empty set
+var BbEmpty = Bitboard{}
+
+// BbRanks and BbFiles are the squares of ranks and files
+var BbRanks, BbFiles = rankAndFileBbs()
+
+func rankAndFileBbs() ([NUM_RANKS]Bitboard, [NUM_FILES]Bitboard) {
+	var ranks [NUM_RANKS]Bitboard
+	var files [NUM_FILES]Bitboard
+
+	for rank := 0; rank < NUM_RANKS; rank++ {
+		for file := 0; file < NUM_FILES; file++ {
+			bb := Square(rank*NUM_FILES + file).Bitboard()
+
+			ranks[rank] = ranks[rank].Or(bb)
+			files[file] = files[file].Or(bb)
+		}
+	}
+
+	return ranks, files
+}
 
 // useful bitboards
-const (
-	BbEmpty              Bitboard = 0x0000000000000000
-	BbFull               Bitboard = 0xffffffffffffffff
-	BbBorder             Bitboard = 0xff818181818181ff
-	BbPawnStartRank      Bitboard = 0x00ff00000000ff00
-	BbPawnStartRankBlack Bitboard = 0x00ff000000000000
-	BbPawnStartRankWhite Bitboard = 0x000000000000ff00
-	BbPawnDoubleRank     Bitboard = 0x000000ffff000000
-	BbBlackSquares       Bitboard = 0xaa55aa55aa55aa55
-	BbWhiteSquares       Bitboard = 0x55aa55aa55aa55aa
+var (
+	BbRank1 = BbRanks[Rank1]
+	BbRank2 = BbRanks[Rank2]
+	BbRank3 = BbRanks[Rank3]
+	BbRank4 = BbRanks[Rank4]
+	BbRank5 = BbRanks[Rank5]
+	BbRank6 = BbRanks[Rank6]
+	BbRank7 = BbRanks[Rank7]
+	BbRank8 = BbRanks[Rank8]
+
+	BbFileA    = BbFiles[FileA]
+	BbFileLast = BbFiles[LAST_FILE]
+
+	BbFull   = BbRank1.Or(BbRank2).Or(BbRank3).Or(BbRank4).Or(BbRank5).Or(BbRank6).Or(BbRank7).Or(BbRank8)
+	BbBorder = BbRank1.Or(BbRank8).Or(BbFileA).Or(BbFileLast)
 )
 
-const (
-	BbFileA Bitboard = 0x101010101010101 << iota
-	BbFileB
-	BbFileC
-	BbFileD
-	BbFileE
-	BbFileF
-	BbFileG
-	BbFileH
-)
+// Or returns the union of bitboards
+func (bb Bitboard) Or(other Bitboard) Bitboard {
+	return Bitboard{bb.Lo | other.Lo, bb.Hi | other.Hi}
+}
 
-const (
-	BbRank1 Bitboard = 0x0000000000000FF << (8 * iota)
-	BbRank2
-	BbRank3
-	BbRank4
-	BbRank5
-	BbRank6
-	BbRank7
-	BbRank8
-)
+// And returns the intersection of bitboards
+func (bb Bitboard) And(other Bitboard) Bitboard {
+	return Bitboard{bb.Lo & other.Lo, bb.Hi & other.Hi}
+}
+
+// AndNot returns the squares of bb not in other
+func (bb Bitboard) AndNot(other Bitboard) Bitboard {
+	return Bitboard{bb.Lo &^ other.Lo, bb.Hi &^ other.Hi}
+}
+
+// Xor returns the symmetric difference of bitboards
+func (bb Bitboard) Xor(other Bitboard) Bitboard {
+	return Bitboard{bb.Lo ^ other.Lo, bb.Hi ^ other.Hi}
+}
+
+// Not returns the squares of the board not in bb
+func (bb Bitboard) Not() Bitboard {
+	return BbFull.AndNot(bb)
+}
+
+// Shl shifts the bitboard towards higher squares, squares shifted off the board are lost
+func (bb Bitboard) Shl(n uint) Bitboard {
+	if n >= 64 {
+		return Bitboard{0, bb.Lo << (n - 64)}.And(BbFull)
+	}
+
+	return Bitboard{bb.Lo << n, bb.Hi<<n | bb.Lo>>(64-n)}.And(BbFull)
+}
+
+// Shr shifts the bitboard towards lower squares
+func (bb Bitboard) Shr(n uint) Bitboard {
+	if n >= 64 {
+		return Bitboard{bb.Hi >> (n - 64), 0}
+	}
+
+	return Bitboard{bb.Lo>>n | bb.Hi<<(64-n), bb.Hi >> n}
+}
 
 // RankBb returns a bitboard with all bits on rank set
 func RankBb(rank int) Bitboard {
-	return BbRank1 << uint(8*rank)
+	return BbRanks[rank]
 }
 
 // FileBb returns a bitboard with all bits on file set
 func FileBb(file int) Bitboard {
-	return BbFileA << uint(file)
+	return BbFiles[file]
 }
 
 // North shifts all squares one rank up
 func North(bb Bitboard) Bitboard {
-	return bb << 8
+	return bb.Shl(NUM_FILES)
 }
 
 // South shifts all squares one rank down
 func South(bb Bitboard) Bitboard {
-	return bb >> 8
+	return bb.Shr(NUM_FILES)
 }
 
-// meaning of &^ operator
-// https://stackoverflow.com/questions/34459450/what-is-the-operator-in-golang
-// turn mask bits off
-
 // East shifts all squares one file right
-// delete h-file, then shift left
+// delete last file, then shift left
 func East(bb Bitboard) Bitboard {
-	return bb &^ BbFileH << 1
+	return bb.AndNot(BbFileLast).Shl(1)
 }
 
 // West shifts all squares one file left
 // delete a-file, then shift right
 func West(bb Bitboard) Bitboard {
-	return bb &^ BbFileA >> 1
+	return bb.AndNot(BbFileA).Shr(1)
 }
 
 // Fill returns a bitboard with all files with squares filled.
 func Fill(bb Bitboard) Bitboard {
-	return NorthFill(bb) | SouthFill(bb)
+	return NorthFill(bb).Or(SouthFill(bb))
 }
 
 // ForwardSpan computes forward span wrt color.
@@ -127,9 +175,9 @@ func BackwardFill(col Color, bb Bitboard) Bitboard {
 
 // NorthFill returns a bitboard with all north bits set
 func NorthFill(bb Bitboard) Bitboard {
-	bb |= (bb << 8)
-	bb |= (bb << 16)
-	bb |= (bb << 32)
+	for rank := 1; rank < NUM_RANKS; rank++ {
+		bb = bb.Or(North(bb))
+	}
 	return bb
 }
 
@@ -140,9 +188,9 @@ func NorthSpan(bb Bitboard) Bitboard {
 
 // SouthFill returns a bitboard with all south bits set
 func SouthFill(bb Bitboard) Bitboard {
-	bb |= (bb >> 8)
-	bb |= (bb >> 16)
-	bb |= (bb >> 32)
+	for rank := 1; rank < NUM_RANKS; rank++ {
+		bb = bb.Or(South(bb))
+	}
 	return bb
 }
 
@@ -151,35 +199,49 @@ func SouthSpan(bb Bitboard) Bitboard {
 	return SouthFill(South(bb))
 }
 
-// Has returns bb if sq is occupied in bitboard
+// Has tells whether sq is occupied in bitboard
 func (bb Bitboard) Has(sq Square) bool {
-	return bb>>sq&1 != 0
+	return bb.And(sq.Bitboard()) != BbEmpty
 }
 
 // AsSquare returns the occupied square if the bitboard has a single piece
-// if the board has more then one piece the result is undefined
+// if the board has more then one piece the lowest square is returned
 // https://golang.org/pkg/math/bits/#TrailingZeros64
 func (bb Bitboard) AsSquare() Square {
-	return Square(bits.TrailingZeros64(uint64(bb)) & 0x3f)
+	if bb.Lo != 0 {
+		return Square(bits.TrailingZeros64(bb.Lo))
+	}
+
+	return Square(64+bits.TrailingZeros64(bb.Hi)) & 0x7f
 }
 
 // LSB picks a square in the board
 // returns empty board for empty board
 func (bb Bitboard) LSB() Bitboard {
-	return bb & (-bb)
+	if bb.Lo != 0 {
+		return Bitboard{bb.Lo & -bb.Lo, 0}
+	}
+
+	return Bitboard{0, bb.Hi & -bb.Hi}
 }
 
 // count returns the number of squares set in bb
 // https://golang.org/pkg/math/bits/#OnesCount64
 func (bb Bitboard) Count() int32 {
-	return int32(bits.OnesCount64(uint64(bb)))
+	return int32(bits.OnesCount64(bb.Lo) + bits.OnesCount64(bb.Hi))
 }
 
 // Pop pops a set square from the bitboard
 func (bb *Bitboard) Pop() Square {
-	sq := *bb & (-*bb)
-	*bb -= sq
-	return Square(bits.TrailingZeros64(uint64(sq)) & 0x3f)
+	if bb.Lo != 0 {
+		sq := Square(bits.TrailingZeros64(bb.Lo))
+		bb.Lo &= bb.Lo - 1
+		return sq
+	}
+
+	sq := Square(64+bits.TrailingZeros64(bb.Hi)) & 0x7f
+	bb.Hi &= bb.Hi - 1
+	return sq
 }
 
 // PopAll pops all set squares from the bitboard
@@ -195,15 +257,18 @@ func (bb *Bitboard) PopAll() []Square {
 
 // String return the string representation of a bitboard
 func (bb Bitboard) String() string {
-	buff := "**********\n"
+	frame := "**"
+	for file := 0; file < NUM_FILES; file++ {
+		frame += "*"
+	}
+	frame += "\n"
+
+	buff := frame
 
 	for rank := LAST_RANK; rank >= 0; rank-- {
 		buff += "*"
 		for file := 0; file < NUM_FILES; file++ {
-			sq := RankFile[rank][file]
-			mask := sq.Bitboard()
-
-			if bb&mask != 0 {
+			if bb.Has(RankFile[rank][file]) {
 				buff += "1"
 			} else {
 				buff += "0"
@@ -213,5 +278,5 @@ func (bb Bitboard) String() string {
 		buff += "\n"
 	}
 
-	return buff + "**********\n"
+	return buff + frame
 }
@@ -0,0 +1,273 @@
+package basic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+)
+
+// polyglot book format
+// http://hgm.nubati.net/book_format.html
+// entries of 16 bytes, big endian, sorted by key
+
+const POLYGLOT_ENTRY_SIZE = 16
+
+const DEFAULT_BOOK_FILE = "book.bin"
+const DEFAULT_BOOK_DEPTH = 255
+
+type PolyglotEntry struct{
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+type PolyglotBook struct{
+	// path of the loaded book file, set also if loading failed
+	Path    string
+	Entries []PolyglotEntry
+}
+
+// Load loads a polyglot book file
+func (book *PolyglotBook) Load(path string) error{
+	book.Path = path
+	book.Entries = []PolyglotEntry{}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil{
+		return err
+	}
+
+	if len(content) % POLYGLOT_ENTRY_SIZE != 0{
+		return fmt.Errorf("%s is not a polyglot book", path)
+	}
+
+	for i := 0; i < len(content); i += POLYGLOT_ENTRY_SIZE{
+		book.Entries = append(book.Entries, PolyglotEntry{
+			Key: binary.BigEndian.Uint64(content[i:]),
+			Move: binary.BigEndian.Uint16(content[i + 8:]),
+			Weight: binary.BigEndian.Uint16(content[i + 10:]),
+			Learn: binary.BigEndian.Uint32(content[i + 12:]),
+		})
+	}
+
+	return nil
+}
+
+// Probe returns the entries of the book with the given key
+func (book *PolyglotBook) Probe(key uint64) []PolyglotEntry{
+	i := sort.Search(len(book.Entries), func(i int) bool{
+		return book.Entries[i].Key >= key
+	})
+
+	entries := []PolyglotEntry{}
+
+	for ; i < len(book.Entries) && book.Entries[i].Key == key; i++{
+		entries = append(entries, book.Entries[i])
+	}
+
+	return entries
+}
+
+// polyglot promotion pieces, 0 is no promotion
+var polyglotPromotionFigures = []Figure{NoFigure, Knight, Bishop, Rook, Queen}
+
+// PolyglotToMove converts a polyglot move to a legal move of the state
+// castling is encoded as king takes rook in polyglot, the same as in gobbit
+func (st *State) PolyglotToMove(pm uint16) (Move, bool){
+	toSq := RankFile[( pm >> 3 ) & 7][pm & 7]
+	fromSq := RankFile[( pm >> 9 ) & 7][( pm >> 6 ) & 7]
+
+	promotion := ( pm >> 12 ) & 7
+
+	if int(promotion) >= len(polyglotPromotionFigures){
+		return NullMove, false
+	}
+
+	for _, move := range st.LegalMoves(false){
+		if move.FromSq() != fromSq || move.ToSq() != toSq{
+			continue
+		}
+
+		if promotion == 0 && move.MoveType() != Promotion{
+			return move, true
+		}
+
+		if move.MoveType() == Promotion && FigureOf[move.PromotionPiece()] == polyglotPromotionFigures[promotion]{
+			return move, true
+		}
+	}
+
+	return NullMove, false
+}
+
+type BookMove struct{
+	Move   Move
+	San    string
+	Weight int
+}
+
+type BookMoves []BookMove
+
+func (bms BookMoves) Len() int{
+	return len(bms)
+}
+
+func (bms BookMoves) Swap(i, j int){
+	bms[i], bms[j] = bms[j], bms[i]
+}
+
+func (bms BookMoves) Less(i, j int) bool{
+	return bms[i].Weight > bms[j].Weight
+}
+
+// TotalWeight returns the sum of the weights of the book moves
+func (bms BookMoves) TotalWeight() int{
+	total := 0
+
+	for _, bm := range bms{
+		total += bm.Weight
+	}
+
+	return total
+}
+
+// Pick returns the book move with the highest weight if best is set, otherwise a random book move by weight
+func (bms BookMoves) Pick(best bool) (Move, bool){
+	if len(bms) == 0{
+		return NullMove, false
+	}
+
+	total := bms.TotalWeight()
+
+	if best || total == 0{
+		return bms[0].Move, true
+	}
+
+	r := rand.Intn(total)
+
+	for _, bm := range bms{
+		r -= bm.Weight
+		if r < 0{
+			return bm.Move, true
+		}
+	}
+
+	return bms[0].Move, true
+}
+
+// polyglot books exist for Standard and Chess960 only
+func (st *State) HasPolyglotBook() bool{
+	return st.Variant == VariantStandard || st.Variant == VariantChess960
+}
+
+// BookMoves returns the legal moves of the state found in the book, sorted by weight
+func (st *State) BookMoves(book *PolyglotBook) BookMoves{
+	bms := BookMoves{}
+
+	if !st.HasPolyglotBook(){
+		return bms
+	}
+
+	for _, entry := range book.Probe(st.PolyglotKey()){
+		move, ok := st.PolyglotToMove(entry.Move)
+
+		if ok{
+			bms = append(bms, BookMove{
+				Move: move,
+				San: st.MoveToSan(move),
+				Weight: int(entry.Weight),
+			})
+		}
+	}
+
+	sort.Stable(bms)
+
+	return bms
+}
+
+// books loaded so far by path, a book is loaded once and then shared read only by the positions
+var books = map[string]*PolyglotBook{}
+var booksMutex sync.Mutex
+
+// LoadBook returns the book of the file, loading it on first use, a book that failed to load stays empty
+func LoadBook(path string) (*PolyglotBook, error){
+	booksMutex.Lock()
+	defer booksMutex.Unlock()
+
+	if book, ok := books[path]; ok{
+		return book, nil
+	}
+
+	book := &PolyglotBook{}
+
+	err := book.Load(path)
+
+	books[path] = book
+
+	return book, err
+}
+
+// GamePly returns the number of plies played from the start of the game in the current position
+func (pos *Position) GamePly() int{
+	st := pos.Current()
+
+	ply := 2 * ( st.FullmoveNumber - 1 )
+
+	if st.Turn == Black{
+		ply++
+	}
+
+	return ply
+}
+
+// Book returns the book of the book file of the position, loading it if needed
+func (pos *Position) Book() *PolyglotBook{
+	book, err := LoadBook(pos.BookFile)
+
+	if err != nil{
+		pos.Log(fmt.Sprintf("info string %v", err))
+	}
+
+	return book
+}
+
+// BookMove returns a book move for the current position if own book is used and the game is within book depth
+func (pos *Position) BookMove() (Move, bool){
+	if !pos.OwnBook || pos.GamePly() >= pos.BookDepth || !pos.Current().HasPolyglotBook(){
+		return NullMove, false
+	}
+
+	return pos.Current().BookMoves(pos.Book()).Pick(pos.BestBookMove)
+}
+
+// ExecBookCommand lists the book moves of the current position
+func (pos *Position) ExecBookCommand(){
+	st := pos.Current()
+
+	if !st.HasPolyglotBook(){
+		pos.Println("no book for", VariantInfos[st.Variant].DisplayName)
+		return
+	}
+
+	bms := st.BookMoves(pos.Book())
+
+	if len(bms) == 0{
+		pos.Println("no book moves")
+		return
+	}
+
+	total := bms.TotalWeight()
+
+	for _, bm := range bms{
+		percent := 0.0
+		if total > 0{
+			percent = 100 * float64(bm.Weight) / float64(total)
+		}
+		pos.Printf("%-8s %6d %5.1f%%\n", bm.San, bm.Weight, percent)
+	}
+}
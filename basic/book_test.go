@@ -0,0 +1,139 @@
+package basic
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPolyglotKey(t *testing.T) {
+	// reference keys from the polyglot book format specification
+	keys := []struct {
+		moves []string
+		key   uint64
+	}{
+		{[]string{}, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	}
+
+	for _, k := range keys {
+		pos := Position{}
+		pos.Init(VariantStandard)
+
+		for _, uci := range k.moves {
+			move, ok := pos.Current().UciToMove(uci)
+			if !ok {
+				t.Fatalf("illegal move %s", uci)
+			}
+			pos.Push(move)
+		}
+
+		if key := pos.Current().PolyglotKey(); key != k.key {
+			t.Errorf("%v : expected key %016x, got %016x", k.moves, k.key, key)
+		}
+	}
+}
+
+func writeBook(t *testing.T, name string, entries []PolyglotEntry) string {
+	content := []byte{}
+	for _, entry := range entries {
+		buff := make([]byte, POLYGLOT_ENTRY_SIZE)
+		binary.BigEndian.PutUint64(buff, entry.Key)
+		binary.BigEndian.PutUint16(buff[8:], entry.Move)
+		binary.BigEndian.PutUint16(buff[10:], entry.Weight)
+		content = append(content, buff...)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestBookMoves(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	key := pos.Current().PolyglotKey()
+
+	// e2e4 weight 1, d2d4 weight 3, entries sorted by key
+	path := writeBook(t, "book.bin", []PolyglotEntry{
+		{Key: key, Move: 12<<6 | 28, Weight: 1},
+		{Key: key, Move: 11<<6 | 27, Weight: 3},
+		{Key: key + 1, Move: 12<<6 | 28, Weight: 5},
+	})
+
+	pos.OwnBook = true
+	pos.BookFile = path
+	pos.BookDepth = DEFAULT_BOOK_DEPTH
+	pos.BestBookMove = true
+
+	bms := pos.Current().BookMoves(pos.Book())
+
+	if len(bms) != 2 || bms[0].San != "d4" || bms[1].San != "e4" || bms.TotalWeight() != 4 {
+		t.Errorf("wrong book moves %+v", bms)
+	}
+
+	if move, ok := pos.BookMove(); !ok || move.UCI() != "d2d4" {
+		t.Errorf("expected best book move d2d4, got %s", move.UCI())
+	}
+
+	pos.BookDepth = 0
+
+	if _, ok := pos.BookMove(); ok {
+		t.Errorf("book move beyond book depth")
+	}
+}
+
+func TestBooksOfPositions(t *testing.T) {
+	st := State{}
+	st.Init(VariantStandard)
+
+	key := st.PolyglotKey()
+
+	// positions with different book files search side by side
+	paths := map[string]string{
+		"e2e4": writeBook(t, "e4.bin", []PolyglotEntry{{Key: key, Move: 12<<6 | 28, Weight: 1}}),
+		"d2d4": writeBook(t, "d4.bin", []PolyglotEntry{{Key: key, Move: 11<<6 | 27, Weight: 1}}),
+	}
+
+	wg := sync.WaitGroup{}
+
+	for expected, path := range paths {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+
+			go func(expected, path string) {
+				defer wg.Done()
+
+				pos := Position{}
+				pos.Init(VariantStandard)
+				pos.OwnBook = true
+				pos.BookFile = path
+				pos.BookDepth = DEFAULT_BOOK_DEPTH
+
+				if move, ok := pos.BookMove(); !ok || move.UCI() != expected {
+					t.Errorf("%s : expected book move %s, got %s", path, expected, move.UCI())
+				}
+			}(expected, path)
+		}
+	}
+
+	wg.Wait()
+
+	if book, _ := LoadBook(paths["e2e4"]); book != (&Position{BookFile: paths["e2e4"]}).Book() {
+		t.Errorf("a book should be loaded once")
+	}
+}
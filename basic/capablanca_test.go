@@ -0,0 +1,52 @@
+package basic
+
+import "testing"
+
+func TestCapablancaPerft(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantCapablanca)
+
+	if fen := pos.Current().ReportFen(); fen != "rnabqkbcnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNABQKBCNR w KQkq - 0 1" {
+		t.Errorf("wrong capablanca start fen %s", fen)
+	}
+
+	for i, expected := range []int{28, 784, 25228} {
+		if leaves := pos.Perft(i + 1); leaves != expected {
+			t.Errorf("capablanca depth %d expected %d got %d", i+1, expected, leaves)
+		}
+	}
+}
+
+func TestWideCastling(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+		move    string
+		result  string
+	}{
+		{VariantCapablanca, "r4k3r/10/10/10/10/10/10/R4K3R w KQkq - 0 1", "f1i1", "r4k3r/10/10/10/10/10/10/R6RK1 b kq - 0 1"},
+		{VariantCapablanca, "r4k3r/10/10/10/10/10/10/R4K3R b KQkq - 0 1", "f8a8", "2kr5r/10/10/10/10/10/10/R4K3R w KQ - 0 2"},
+		{VariantEmbassy, "r3k4r/10/10/10/10/10/10/R3K4R w KQkq - 0 1", "e1j1", "r3k4r/10/10/10/10/10/10/R5RK2 b kq - 0 1"},
+		{VariantEmbassy, "r3k4r/10/10/10/10/10/10/R3K4R w KQkq - 0 1", "e1b1", "r3k4r/10/10/10/10/10/10/1KR6R b kq - 0 1"},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(test.variant)
+		pos.ParseFen(test.fen)
+
+		move, ok := pos.Current().UciToMove(test.move)
+
+		if !ok {
+			t.Errorf("%s : %s should be legal", test.fen, test.move)
+
+			continue
+		}
+
+		pos.Push(move)
+
+		if fen := pos.Current().ReportFen(); fen != test.result {
+			t.Errorf("%s : after %s expected %s got %s", test.fen, test.move, test.result, fen)
+		}
+	}
+}
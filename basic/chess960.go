@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"math/rand"
+	"strings"
+)
+
+const CHESS960_NUM_POSITIONS = 960
+
+// standard chess start position in Scharnagl numbering
+const CHESS960_STANDARD_POSITION = 518
+
+// knight placements over the five squares left after placing bishops and queen
+var chess960KnightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960BackRank returns the white back rank of chess960 position n ( 0 - 959 ) using Scharnagl numbering
+// https://en.wikipedia.org/wiki/Fischer_random_chess_numbering_scheme
+func Chess960BackRank(n int) string{
+	n = ( ( n % CHESS960_NUM_POSITIONS ) + CHESS960_NUM_POSITIONS ) % CHESS960_NUM_POSITIONS
+
+	rank := make([]byte, STANDARD_NUM_FILES)
+
+	// light square bishop on b, d, f or h file
+	rank[2 * ( n % 4 ) + 1] = 'B'
+	n /= 4
+
+	// dark square bishop on a, c, e or g file
+	rank[2 * ( n % 4 )] = 'B'
+	n /= 4
+
+	emptyFiles := func() []int{
+		files := []int{}
+		for file, c := range rank{
+			if c == 0{
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+
+	rank[emptyFiles()[n % 6]] = 'Q'
+	n /= 6
+
+	empty := emptyFiles()
+	for _, i := range chess960KnightPlacements[n]{
+		rank[empty[i]] = 'N'
+	}
+
+	// king goes between the two rooks
+	for i, file := range emptyFiles(){
+		rank[file] = "RKR"[i]
+	}
+
+	return string(rank)
+}
+
+// Chess960StartFen returns the start fen of chess960 position n
+// castling rights are reported in X-FEN format, which is KQkq for every start position
+func Chess960StartFen(n int) string{
+	backRank := Chess960BackRank(n)
+
+	return strings.ToLower(backRank) + "/pppppppp/8/8/8/8/PPPPPPPP/" + backRank + " w KQkq - 0 1"
+}
+
+// RandomChess960StartFen returns the start fen of a random chess960 position
+func RandomChess960StartFen() string{
+	return Chess960StartFen(rand.Intn(CHESS960_NUM_POSITIONS))
+}
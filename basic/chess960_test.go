@@ -0,0 +1,59 @@
+package basic
+
+import "testing"
+
+func TestChess960BackRank(t *testing.T) {
+	if br := Chess960BackRank(CHESS960_STANDARD_POSITION); br != "RNBQKBNR" {
+		t.Errorf("expected standard back rank for position 518, got %s", br)
+	}
+
+	if br := Chess960BackRank(0); br != "BBQNNRKR" {
+		t.Errorf("expected BBQNNRKR for position 0, got %s", br)
+	}
+
+	seen := map[string]bool{}
+
+	for n := 0; n < CHESS960_NUM_POSITIONS; n++ {
+		seen[Chess960BackRank(n)] = true
+	}
+
+	if len(seen) != CHESS960_NUM_POSITIONS {
+		t.Errorf("expected %d distinct start positions, got %d", CHESS960_NUM_POSITIONS, len(seen))
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	tests := []struct {
+		fen    string
+		leaves []int
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(VariantChess960)
+		pos.ParseFen(test.fen)
+
+		for i, expected := range test.leaves {
+			if leaves := pos.Perft(i+1); leaves != expected {
+				t.Errorf("%s depth %d expected %d got %d", test.fen, i+1, expected, leaves)
+			}
+		}
+	}
+}
+
+func TestShredderFen(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantChess960)
+	pos.ParseFen("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9")
+
+	if fen := pos.Current().ReportShredderFen(); fen != "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9" {
+		t.Errorf("wrong shredder fen %s", fen)
+	}
+
+	if fen := pos.Current().ReportFen(); fen != "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9" {
+		t.Errorf("wrong x-fen %s", fen)
+	}
+}
@@ -0,0 +1,299 @@
+package basic
+
+import (
+	"fmt"
+)
+
+// plies without capture or pawn move after which the game is drawn
+const FIFTY_MOVE_RULE_PLIES = 100
+
+// a1, c1, ... , b2, d2, ...
+var DARK_SQUARES = darkSquares()
+
+func darkSquares() Bitboard{
+	bb := BbEmpty
+
+	for rank := 0; rank < NUM_RANKS; rank++{
+		for file := rank % 2; file < NUM_FILES; file += 2{
+			bb = bb.Or(Square(rank * NUM_FILES + file).Bitboard())
+		}
+	}
+
+	return bb
+}
+
+// InsufficientMaterial tells whether neither side can win
+func (st *State) InsufficientMaterial() bool{
+	kings := st.ByFigure[King]
+	all := st.ByColor[White].Or(st.ByColor[Black])
+	minors := st.ByFigure[Knight].Or(st.ByFigure[Bishop])
+
+	if st.Rules().Win == WinAntichess{
+		// kings are ordinary pieces, any piece can be lost
+		return false
+	}
+
+	if st.Rules().Win == WinKingCapture{
+		// any piece can capture a king that the duck does not shield, only bare kings are a draw
+		return all == kings
+	}
+
+	if st.Rules().FlagRegion != BbEmpty || st.Rules().Win == WinRace{
+		// a bare king can still walk to the center or the eighth rank
+		return false
+	}
+
+	if st.Rules().Win == WinHorde{
+		// black wins by capturing the horde, white pawns may still promote
+		return false
+	}
+
+	if st.Rules().CheckLimit > 0 && all != kings{
+		// any piece can give checks
+		return false
+	}
+
+	if st.Rules().Drops{
+		// captured pieces change sides and can be dropped, only bare kings with empty pockets are a draw
+		return all == kings && st.Pockets == [ColorArraySize][FigureArraySize]int{}
+	}
+
+	if all == kings{
+		// bare kings
+		return true
+	}
+
+	if all.AndNot(kings.Or(minors)) != BbEmpty{
+		return false
+	}
+
+	if all.AndNot(kings).Count() == 1{
+		// king and minor against bare king
+		// in atomic the bare king can not be exploded either, as it has no pieces around it
+		return true
+	}
+
+	if st.Rules().Explosions{
+		// capturing a piece next to the enemy king wins, minor pieces on both sides can still decide the game
+		return false
+	}
+
+	bishops := st.ByFigure[Bishop]
+
+	// only bishops on squares of the same color
+	return minors == bishops && ( bishops.And(DARK_SQUARES) == BbEmpty || bishops.AndNot(DARK_SQUARES) == BbEmpty )
+}
+
+// number of checks that win a three-check game
+const THREE_CHECK_LIMIT = 3
+
+// the squares a king has to reach to win a king of the hill game
+var HILL = SquareD4.Bitboard().Or(SquareE4.Bitboard()).Or(SquareD5.Bitboard()).Or(SquareE5.Bitboard())
+
+// the rank the kings race to in racing kings
+var RACING_KINGS_GOAL = BbRank8
+
+// RoyalKingCaptured tells whether the side to move lost its king, kings are not royal in antichess and horde white has none
+func (st *State) RoyalKingCaptured() bool{
+	if st.Rules().Win == WinAntichess || ( st.Rules().Win == WinHorde && st.Turn == White ){
+		return false
+	}
+
+	return st.KingInfos[st.Turn].IsCaptured
+}
+
+// VariantEnd tells whether the game ended by a rule of the variant, with the result for the side to move ( 1 win, 0 draw, -1 loss ) and the reason
+// the side that made the last move gave the last check counted, brought its king to the flag region or won the race to the eighth rank
+func (st *State) VariantEnd() (bool, int, string){
+	us := st.Turn
+	them := us.Inverse()
+
+	rules := st.Rules()
+
+	if rules.CheckLimit > 0 && st.Checks[them] >= rules.CheckLimit{
+		if rules.CheckLimit == THREE_CHECK_LIMIT{
+			return true, -1, "third check"
+		}
+
+		return true, -1, fmt.Sprintf("%d checks", rules.CheckLimit)
+	}
+
+	if st.ByFigure[King].And(st.ByColor[them]).And(rules.FlagRegion) != BbEmpty{
+		if rules.FlagRegion == HILL{
+			return true, -1, "king reached the hill"
+		}
+
+		return true, -1, "king reached the flag region"
+	}
+
+	switch rules.Win{
+	case WinAntichess:
+		if st.ByColor[us] == BbEmpty{
+			if us == White{
+				return true, 1, "white lost all pieces"
+			}
+
+			return true, 1, "black lost all pieces"
+		}
+	case WinHorde:
+		if us == White && st.ByColor[White] == BbEmpty{
+			return true, -1, "white lost all pieces"
+		}
+	case WinRace:
+		whiteGoal := st.ByFigure[King].And(st.ByColor[White]).And(RACING_KINGS_GOAL) != BbEmpty
+		blackGoal := st.ByFigure[King].And(st.ByColor[Black]).And(RACING_KINGS_GOAL) != BbEmpty
+
+		if whiteGoal && blackGoal{
+			return true, 0, "both kings reached the eighth rank"
+		}
+
+		if blackGoal{
+			// black reached the goal first, white had no chance to answer
+			return true, -1, "king reached the eighth rank"
+		}
+
+		if whiteGoal{
+			if us == White{
+				// black could not follow
+				return true, 1, "king reached the eighth rank"
+			}
+
+			if !st.CanReachRacingGoal(){
+				return true, -1, "king reached the eighth rank"
+			}
+		}
+	}
+
+	return false, 0, ""
+}
+
+// CanReachRacingGoal tells whether the king of the side to move can legally step to the eighth rank
+func (st *State) CanReachRacingGoal() bool{
+	king := st.ByFigure[King].And(st.ByColor[st.Turn])
+
+	if king == BbEmpty{
+		return false
+	}
+
+	ksq := king.AsSquare()
+
+	targets := KingAttack[ksq].And(RACING_KINGS_GOAL).AndNot(st.ByColor[st.Turn])
+
+	undo := Undo{}
+
+	for targets != BbEmpty{
+
+		if st.IsLegalByMaking(MakeMoveFT(ksq, targets.Pop()), &undo){
+			return true
+		}
+	}
+
+	return false
+}
+
+// Repetitions tells how many times the current position occurred earlier in the game, including history dropped by Rebase
+// inTree is true if an earlier occurrence is inside the search tree, that is after the search root
+func (pos *Position) Repetitions() (int, bool){
+	st := pos.Current()
+
+	count := 0
+
+	inTree := false
+
+	// positions before the last capture or pawn move can not repeat, only the same side to move is checked
+	plies := st.HalfmoveClock
+
+	ptr := pos.StatePtr - 2
+
+	for ; ptr >= 0 && plies >= 2; ptr -= 2{
+		plies -= 2
+
+		if pos.Undos[ptr].Zobrist == st.Zobrist{
+			count++
+			inTree = inTree || ptr > pos.SearchRootPtr
+		}
+	}
+
+	// continue in history, ptr is the state index counted back from the first state, -1 is the last history entry
+	for i := len(pos.History) + ptr; i >= 0 && plies >= 2; i -= 2{
+		plies -= 2
+
+		if pos.History[i] == st.Zobrist{
+			count++
+		}
+	}
+
+	return count, inTree
+}
+
+// GameResult determines the result of the game in the current position and the reason of the result
+// result is "*" if the game is not over
+func (pos *Position) GameResult() (string, string){
+	st := pos.Current()
+
+	winner := "0-1"
+	side := "white"
+
+	if st.Turn == Black{
+		winner = "1-0"
+		side = "black"
+	}
+
+	if end, result, reason := st.VariantEnd(); end{
+		switch{
+		case result == 0:
+			return "1/2-1/2", reason
+		case ( result > 0 ) == ( st.Turn == White ):
+			return "1-0", reason
+		default:
+			return "0-1", reason
+		}
+	}
+
+	if st.RoyalKingCaptured(){
+		return winner, side + " king captured"
+	}
+
+	if st.Rules().StalemateWins() && !st.HasLegalMove(){
+		// the side to move wins by being stalemated
+		if st.Turn == White{
+			return "1-0", side + " stalemated"
+		}
+
+		return "0-1", side + " stalemated"
+	}
+
+	hasLegalMove := st.HasLegalMove()
+
+	if !hasLegalMove{
+		if st.IsCheckedUs(){
+			return winner, side + " checkmated"
+		}
+
+		return "1/2-1/2", "stalemate"
+	}
+
+	if st.HalfmoveClock >= FIFTY_MOVE_RULE_PLIES{
+		return "1/2-1/2", "fifty move rule"
+	}
+
+	// the game is not being searched, so the search tree is ignored
+	repetitions, _ := pos.Repetitions()
+
+	if repetitions >= 2{
+		return "1/2-1/2", "threefold repetition"
+	}
+
+	if st.InsufficientMaterial(){
+		return "1/2-1/2", "insufficient material"
+	}
+
+	return "*", "game in progress"
+}
+
+// ExecResultCommand prints the result of the game with the reason
+func (pos *Position) ExecResultCommand(){
+	result, reason := pos.GameResult()
+
+	pos.Log(result + " {" + reason + "}")
+}
@@ -0,0 +1,170 @@
+package basic
+
+import "testing"
+
+func TestInsufficientMaterial(t *testing.T) {
+	for _, test := range []struct {
+		variant      Variant
+		fen          string
+		insufficient bool
+	}{
+		{VariantStandard, "8/8/4k3/8/8/8/8/K7 w - - 0 1", true},
+		{VariantStandard, "8/8/4k3/8/8/2N5/8/K7 w - - 0 1", true},
+		{VariantStandard, "8/8/2b1k3/8/8/2B5/8/K7 w - - 0 1", false},
+		{VariantStandard, "8/8/3bk3/8/8/2B5/8/K7 w - - 0 1", true},
+		{VariantStandard, "8/8/4k3/8/8/2NN4/8/K7 w - - 0 1", false},
+		{VariantStandard, "8/8/4k3/8/8/2P5/8/K7 w - - 0 1", false},
+		{VariantAtomic, "8/8/4k3/8/8/2B5/8/K7 w - - 0 1", true},
+		{VariantAtomic, "8/8/3bk3/8/8/2B5/8/K7 w - - 0 1", false},
+		{VariantEightPiece, "8/8/4k3/8/8/2S5/8/K7 w - - 0 1 -", false},
+		{VariantKingOfTheHill, "8/8/4k3/8/8/8/8/K7 w - - 0 1", false},
+		{VariantThreeCheck, "8/8/4k3/8/8/2N5/8/K7 w - - 0 1 +0+0", false},
+		{VariantThreeCheck, "8/8/4k3/8/8/8/8/K7 w - - 0 1 +0+0", true},
+	} {
+		st := State{}
+		st.Init(test.variant)
+		st.ParseFen(test.fen)
+
+		if st.InsufficientMaterial() != test.insufficient {
+			t.Errorf("%s %s : expected insufficient material %v", VariantInfos[test.variant].DisplayName, test.fen, test.insufficient)
+		}
+	}
+}
+
+func TestRepetitions(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+
+	// longer than MAX_GAME_PLIES, so that early repetitions are in the history
+	plies := 60
+
+	for i := 0; i < plies; i++ {
+		pos.PushUci(shuffle[i%len(shuffle)])
+	}
+
+	if len(pos.History) != plies-MAX_GAME_PLIES {
+		t.Errorf("expected %d history entries, got %d", plies-MAX_GAME_PLIES, len(pos.History))
+	}
+
+	if repetitions, _ := pos.Repetitions(); repetitions != plies/len(shuffle) {
+		t.Errorf("expected %d repetitions, got %d", plies/len(shuffle), repetitions)
+	}
+
+	if result, reason := pos.GameResult(); result != "1/2-1/2" || reason != "threefold repetition" {
+		t.Errorf("expected threefold repetition, got %s %s", result, reason)
+	}
+
+	pos.PushUci("e2e4")
+
+	if repetitions, _ := pos.Repetitions(); repetitions != 0 {
+		t.Errorf("expected no repetition after pawn move, got %d", repetitions)
+	}
+}
+
+func TestGameResult(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	for _, test := range []struct {
+		fen    string
+		result string
+		reason string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "*", "game in progress"},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", "1/2-1/2", "stalemate"},
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", "1-0", "black checkmated"},
+		{"7k/8/6K1/8/8/8/8/7R w - - 100 90", "1/2-1/2", "fifty move rule"},
+		{"7k/8/6K1/8/8/8/8/7R w - - 99 90", "*", "game in progress"},
+	} {
+		pos.ParseFen(test.fen)
+
+		if result, reason := pos.GameResult(); result != test.result || reason != test.reason {
+			t.Errorf("%s : expected %s %s, got %s %s", test.fen, test.result, test.reason, result, reason)
+		}
+	}
+}
+
+func TestVariantEnd(t *testing.T) {
+	for _, test := range []struct {
+		variant Variant
+		fen     string
+		uci     string
+		san     string
+		result  string
+		reason  string
+	}{
+		{VariantThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+0", "a1a8", "Ra8#", "1-0", "third check"},
+		{VariantThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +1+0", "a1a8", "Ra8+", "*", "game in progress"},
+		// lichess style counters give the checks remaining
+		{VariantThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", "a1a8", "Ra8#", "1-0", "third check"},
+		{VariantThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", "a1a8", "Ra8+", "*", "game in progress"},
+		{VariantKingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1", "d3e4", "Ke4#", "1-0", "king reached the hill"},
+		{VariantKingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1", "d3c4", "Kc4", "*", "game in progress"},
+		{VariantHorde, "4k3/8/8/8/8/8/8/q6P b - - 0 1", "a1h1", "Qxh1#", "0-1", "white lost all pieces"},
+		{VariantRacingKings, "8/6K1/8/8/8/8/k7/8 w - - 0 1", "g7g8", "Kg8#", "1-0", "king reached the eighth rank"},
+		{VariantRacingKings, "8/1k4K1/8/8/8/8/8/8 w - - 0 1", "g7g8", "Kg8", "*", "game in progress"},
+		{VariantRacingKings, "6K1/1k6/8/8/8/8/8/8 b - - 0 1", "b7b8", "Kb8", "1/2-1/2", "both kings reached the eighth rank"},
+		{VariantRacingKings, "8/1k6/8/8/8/8/6K1/8 b - - 0 1", "b7b8", "Kb8#", "0-1", "king reached the eighth rank"},
+	} {
+		pos := Position{}
+		pos.Init(test.variant)
+		pos.ParseFen(test.fen)
+
+		move, ok := pos.Current().UciToMove(test.uci)
+		if !ok {
+			t.Fatalf("illegal move %s", test.uci)
+		}
+
+		if san := pos.Current().MoveToSan(move); san != test.san {
+			t.Errorf("%s : expected san %s, got %s", test.fen, test.san, san)
+		}
+
+		pos.Push(move)
+
+		if result, reason := pos.GameResult(); result != test.result || reason != test.reason {
+			t.Errorf("%s %s : expected %s %s, got %s %s", test.fen, test.uci, test.result, test.reason, result, reason)
+		}
+
+		if end, _ := pos.GameEnd(1); end != ( test.result != "*" ) {
+			t.Errorf("%s %s : game end %v", test.fen, test.uci, end)
+		}
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	st := State{}
+	st.Init(VariantAntichess)
+	st.ParseFen("rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 2")
+
+	// the capture is compulsory
+	if lms := st.LegalMoves(false); len(lms) != 1 || lms[0].UCI() != "e4d5" {
+		t.Errorf("expected only e4d5, got %v", lms)
+	}
+
+	st.ParseFen("8/2P5/8/8/8/8/8/k7 w - - 0 1")
+
+	if lms := st.LegalMoves(false); len(lms) != 5 {
+		t.Errorf("expected 5 promotions including the king, got %v", lms)
+	}
+
+	pos := Position{}
+	pos.Init(VariantAntichess)
+
+	for _, test := range []struct {
+		fen    string
+		result string
+		reason string
+	}{
+		{"8/8/8/8/8/8/8/k7 w - - 0 1", "1-0", "white lost all pieces"},
+		{"8/8/8/8/8/p7/P7/8 b - - 0 1", "0-1", "black stalemated"},
+		{"8/8/8/8/8/8/P7/k7 w - - 0 1", "*", "game in progress"},
+	} {
+		pos.ParseFen(test.fen)
+
+		if result, reason := pos.GameResult(); result != test.result || reason != test.reason {
+			t.Errorf("%s : expected %s %s, got %s %s", test.fen, test.result, test.reason, result, reason)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package basic
+
+// duck chess, after each move the mover places the duck on an empty square other than the one it stood on
+// the duck blocks every piece and can not be captured, there is no check and the king is captured instead of mated
+// on the board the duck is the DummyPiece, it belongs to neither side, so it is in Pieces and Duck, but not in ByColor
+
+// the FEN symbol of the duck
+const DUCK_SYMBOL = "*"
+
+// a duck move is a base move with the duck square in the promotion square field, sentry pushes never have a duck
+// the field holds duck square + 1, 0 means no duck placement
+func (move Move) WithDuck(sq Square) Move{
+	return move.BaseMove() | Move(sq+1)<<PROMOTION_SQUARE_SHIFT
+}
+
+// DuckSquare tells where the move places the duck
+func (move Move) DuckSquare() (Square, bool){
+	if move.MoveType() == SentryPush{
+		return SquareA1, false
+	}
+
+	field := Square((move >> PROMOTION_SQUARE_SHIFT) & SQUARE_MASK)
+
+	if field == 0{
+		return SquareA1, false
+	}
+
+	return field - 1, true
+}
+
+// BaseMove returns the move without the duck placement
+func (move Move) BaseMove() Move{
+	if move.MoveType() == SentryPush{
+		return move
+	}
+
+	return move &^ (SQUARE_MASK << PROMOTION_SQUARE_SHIFT)
+}
+
+// DuckSuffix returns the @square suffix of a duck move for UCI and SAN, empty for other moves
+func (move Move) DuckSuffix() string{
+	if duckSq, ok := move.DuckSquare(); ok{
+		return "@" + duckSq.UCI()
+	}
+
+	return ""
+}
+
+// PlaceDuck moves the duck to the square given by the move, to be called by MakeMove after the base move
+func (st *State) PlaceDuck(move Move, undo *Undo){
+	duckSq, ok := move.DuckSquare()
+
+	if !ok{
+		return
+	}
+
+	if st.Duck != BbEmpty{
+		oldSq := st.Duck.AsSquare()
+
+		undo.Save(st, oldSq)
+		st.Remove(oldSq)
+	}
+
+	undo.Save(st, duckSq)
+	st.Put(DummyPiece, duckSq)
+
+	if st.EpSquare == duckSq{
+		// the capturing pawn could not go to the square of the duck
+		st.SetEpSquare(SquareA1)
+	}
+}
+
+// DuckTargets returns the squares the duck can be placed on after the base move
+func (st *State) DuckTargets(move Move) Bitboard{
+	undo := Undo{}
+
+	st.MakeMove(move, &undo)
+
+	targets := st.Rules().Board.AndNot(st.ByColor[White].Or(st.ByColor[Black]).Or(st.Duck))
+
+	st.UnmakeMove(&undo)
+
+	return targets
+}
+
+// AddDuckSquares expands each base move to all its duck placements
+func (st *State) AddDuckSquares(moves []Move) []Move{
+	duckMoves := []Move{}
+
+	for _, move := range moves{
+		targets := st.DuckTargets(move)
+
+		for targets != BbEmpty{
+			duckMoves = append(duckMoves, move.WithDuck(targets.Pop()))
+		}
+	}
+
+	return duckMoves
+}
+
+// duck square score of blocking an attack on the king of the mover
+const DUCK_KING_SHIELD_SCORE = 10000
+
+// duck square score of taking a square the opponent could move to
+const DUCK_MOBILITY_SCORE = 1
+
+// default number of duck squares searched for a base move
+const DEFAULT_DUCK_SEARCH_SQUARES = 3
+
+// BestDuckSquares returns at most n of the squares the duck can be placed on after the base move, best first
+// the search can not afford all placements, so the duck goes between the opponent pieces and the pieces they attack,
+// above all the king, or to the squares the opponent could move to
+func (st *State) BestDuckSquares(move Move, n int) []Square{
+	undo := Undo{}
+
+	st.MakeMove(move, &undo)
+
+	targets := st.Rules().Board.AndNot(st.ByColor[White].Or(st.ByColor[Black]).Or(st.Duck))
+
+	mover := st.Turn.Inverse()
+
+	scores := [BOARD_AREA]int{}
+
+	if !st.KingInfos[st.Turn].IsCaptured{
+		for _, reply := range st.Pslms(Violent | Quiet){
+			if reply.MoveType() == Castling || reply.MoveType() == Drop{
+				continue
+			}
+
+			fromSq := reply.FromSq()
+			toSq := reply.ToSq()
+
+			victim := st.PieceAtSquare(toSq)
+
+			if victim == NoPiece{
+				scores[toSq] += DUCK_MOBILITY_SCORE
+
+				continue
+			}
+
+			if ColorOf[victim] != mover{
+				continue
+			}
+
+			score := int(PieceMaterialTables[victim][toSq].M)
+
+			if FigureOf[victim] == King{
+				score = DUCK_KING_SHIELD_SCORE
+			}
+
+			block := Between[fromSq][toSq]
+
+			for block != BbEmpty{
+				scores[block.Pop()] += score
+			}
+		}
+	}
+
+	st.UnmakeMove(&undo)
+
+	best := []Square{}
+
+	for targets != BbEmpty{
+		sq := targets.Pop()
+
+		i := len(best)
+
+		for i > 0 && scores[best[i-1]] < scores[sq]{
+			i--
+		}
+
+		if i >= n{
+			continue
+		}
+
+		best = append(best, sq)
+		copy(best[i+1:], best[i:])
+		best[i] = sq
+
+		if len(best) > n{
+			best = best[:n]
+		}
+	}
+
+	return best
+}
+
+// AddBestDuckSquares expands each base move to its n best duck placements
+func (st *State) AddBestDuckSquares(moves []Move, n int) []Move{
+	duckMoves := []Move{}
+
+	for _, move := range moves{
+		for _, sq := range st.BestDuckSquares(move, n){
+			duckMoves = append(duckMoves, move.WithDuck(sq))
+		}
+	}
+
+	return duckMoves
+}
+
+// SearchMoves returns the moves searched by alpha beta, in duck chess only the best duckSquares placements of each base move
+func (st *State) SearchMoves(duckSquares int) []Move{
+	if !st.Rules().Duck{
+		return st.GenerateMoves()
+	}
+
+	if duckSquares <= 0{
+		duckSquares = DEFAULT_DUCK_SEARCH_SQUARES
+	}
+
+	return st.AddBestDuckSquares(st.GenerateBaseMoves(), duckSquares)
+}
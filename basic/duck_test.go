@@ -0,0 +1,130 @@
+package basic
+
+import "testing"
+
+func TestDuckPerft(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantDuck)
+
+	// 20 moves, each with 32 empty squares for the duck
+	for i, expected := range []int{640, 379440} {
+		if leaves := pos.Perft(i + 1); leaves != expected {
+			t.Errorf("duck depth %d expected %d got %d", i+1, expected, leaves)
+		}
+	}
+}
+
+func TestDuckMoves(t *testing.T) {
+	tests := []struct {
+		fen    string
+		move   string
+		san    string
+		result string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4@e3", "e4@e3", "rnbqkbnr/pppppppp/8/8/4P3/4*3/PPPP1PPP/RNBQKBNR b KQkq - 0 1"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/4*3/PPPP1PPP/RNBQKBNR b KQkq - 0 1", "d7d5@e2", "d5@e2", "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP*PPP/RNBQKBNR w KQkq - 0 2"},
+		{"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1@e1", "O-O-O@e1", "4k3/8/8/8/8/8/8/2KR*3 b - - 0 1"},
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "h1h8@a1", "Rh8@a1", "4k2R/8/8/8/8/8/8/*3K3 b - - 1 1"},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(VariantDuck)
+		pos.ParseFen(test.fen)
+
+		if fen := pos.Current().ReportFen(); fen != test.fen {
+			t.Errorf("fen %s read as %s", test.fen, fen)
+		}
+
+		move, ok := pos.Current().UciToMove(test.move)
+
+		if !ok {
+			t.Errorf("%s : %s should be legal", test.fen, test.move)
+
+			continue
+		}
+
+		if san := pos.Current().MoveToSan(move); san != test.san {
+			t.Errorf("%s : %s expected san %s got %s", test.fen, test.move, test.san, san)
+		}
+
+		pos.Push(move)
+
+		if fen := pos.Current().ReportFen(); fen != test.result {
+			t.Errorf("%s : after %s expected %s got %s", test.fen, test.move, test.result, fen)
+		}
+	}
+}
+
+func TestDuckBlocks(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantDuck)
+	// the duck on d1 shields the king from the rook
+	pos.ParseFen("3rk3/8/8/8/8/8/8/3*K3 b - - 0 1")
+
+	st := pos.Current()
+
+	if _, ok := st.UciToMove("d8d1@a1"); ok {
+		t.Errorf("the duck can not be captured")
+	}
+
+	if _, ok := st.UciToMove("d8e8@a1"); ok {
+		t.Errorf("the rook can not capture its own king")
+	}
+
+	if _, ok := st.UciToMove("d8d2@d1"); ok {
+		t.Errorf("the duck has to move")
+	}
+
+	move, ok := st.UciToMove("d8d2@a1")
+
+	if !ok {
+		t.Errorf("d8d2@a1 should be legal")
+
+		return
+	}
+
+	pos.Push(move)
+
+	if _, ok := pos.Current().UciToMove("e1d1@a2"); !ok {
+		t.Errorf("the king may step next to the rook, there is no check")
+	}
+
+	move, _ = pos.Current().UciToMove("e1f1@a2")
+
+	pos.Push(move)
+
+	move, ok = pos.Current().UciToMove("d2d1@a3")
+
+	if !ok {
+		t.Errorf("d2d1@a3 should be legal")
+
+		return
+	}
+
+	pos.Push(move)
+
+	move, ok = pos.Current().UciToMove("f1e1@a4")
+
+	if !ok {
+		t.Errorf("f1e1@a4 should be legal")
+
+		return
+	}
+
+	pos.Push(move)
+
+	move, ok = pos.Current().UciToMove("d1e1@a5")
+
+	if !ok {
+		t.Errorf("the king can be captured")
+
+		return
+	}
+
+	pos.Push(move)
+
+	if result, reason := pos.GameResult(); result != "0-1" {
+		t.Errorf("king capture expected 0-1 got %s %s", result, reason)
+	}
+}
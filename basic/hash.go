@@ -1,6 +1,9 @@
 package basic
 
 import (
+	"sync/atomic"
+	"unsafe"
+
 	"github.com/spaolacci/murmur3"
 )
 
@@ -29,7 +32,10 @@ func Uint32ToByteSlice(v uint32) []byte{
 
 //https://github.com/spaolacci/murmur3/blob/539464a789e9b9f01bc857458ffe2c5c1a2ed382/murmur32.go#L106
 func Mix32Uint64AndUint32(v64 uint64, v32 uint32) uint32{
-	return murmur3.Sum32(append(Uint64ToByteSlice(v64), Uint32ToByteSlice(v32)...))
+	// streaming hasher, Sum32 does pointer arithmetic that trips checkptr under -race
+	h := murmur3.New32()
+	h.Write(append(Uint64ToByteSlice(v64), Uint32ToByteSlice(v32)...))
+	return h.Sum32()
 }
 
 const POS_MOVE_HASH_KEY_SIZE_IN_BITS = 25
@@ -43,11 +49,18 @@ type PosMoveEntry struct{
 }
 
 type PosMoveHash struct{
-	Entries         [POS_MOVE_HASH_SIZE]PosMoveEntry
+	Entries         []PosMoveEntry
+	Mask            uint32
+}
+
+// Alloc allocates the entries of the hash, size is the number of entries, has to be a power of two
+func (pmh *PosMoveHash) Alloc(size int){
+	pmh.Entries = make([]PosMoveEntry, size)
+	pmh.Mask = uint32(size - 1)
 }
 
 func (pmh *PosMoveHash) Get(zobrist uint64, move Move) (uint32, PosMoveEntry){
-	key := Mix32Uint64AndUint32(zobrist, uint32(move)) & POS_MOVE_HASH_MASK
+	key := Mix32Uint64AndUint32(zobrist, uint32(move)) & pmh.Mask
 
 	return key, pmh.Entries[key]
 }
@@ -82,11 +95,22 @@ type PvEntry struct{
 }
 
 type PvHash struct{
-	Entries         [PV_HASH_SIZE]PvEntry
+	Entries         []PvEntry
+	Mask            uint64
+}
+
+// Alloc allocates the entries of the hash, size is the number of entries, has to be a power of two
+func (pvh *PvHash) Alloc(size int){
+	pvh.Entries = make([]PvEntry, size)
+	pvh.Mask = uint64(size - 1)
+
+	for i := range pvh.Entries{
+		pvh.Entries[i].Depth = INFINITE_DEPTH
+	}
 }
 
 func (pvh *PvHash) Get(zobrist uint64) (uint32, PvEntry, bool){
-	key := uint32(zobrist & PV_HASH_MASK)
+	key := uint32(zobrist & pvh.Mask)
 
 	entry := pvh.Entries[key]
 
@@ -102,3 +126,177 @@ func (pvh *PvHash) Set(zobrist uint64, pve PvEntry){
 		pvh.Entries[key] = pve
 	}
 }
+
+// transposition table bound types
+// https://www.chessprogramming.org/Node_Types
+const (
+	BoundNone = uint8(iota)
+	BoundExact
+	BoundLower
+	BoundUpper
+)
+
+type TranspositionTableEntry struct{
+	Zobrist         uint64
+	Move            Move
+	Score           Score
+	RemDepth        int8
+	Bound           uint8
+	Age             uint8
+}
+
+// packed entry : [Age 6 bits][Bound 2 bits][Remaining Depth 8 bits][Score 16 bits][Move 32 bits]
+
+const TT_AGE_MASK = (1 << 6) - 1
+
+func (tte TranspositionTableEntry) Pack() uint64{
+	return uint64(tte.Move) | uint64(uint16(tte.Score)) << 32 | uint64(uint8(tte.RemDepth)) << 48 | uint64(tte.Bound & 3) << 56 | uint64(tte.Age & TT_AGE_MASK) << 58
+}
+
+func UnpackTranspositionTableEntry(zobrist uint64, data uint64) TranspositionTableEntry{
+	return TranspositionTableEntry{
+		Zobrist: zobrist,
+		Move: Move(uint32(data)),
+		Score: Score(int16(uint16(data >> 32))),
+		RemDepth: int8(uint8(data >> 48)),
+		Bound: uint8(data >> 56) & 3,
+		Age: uint8(data >> 58) & TT_AGE_MASK,
+	}
+}
+
+// TranspositionTableSlot stores an entry as key xor data and data, so that it can be shared between threads without locking
+// https://www.chessprogramming.org/Shared_Hash_Table#Lock-less
+type TranspositionTableSlot struct{
+	Key             uint64
+	Data            uint64
+}
+
+const DEFAULT_HASH_SIZE_IN_MB = 16
+
+// TranspositionTable stores search results by position
+// https://www.chessprogramming.org/Transposition_Table
+type TranspositionTable struct{
+	Slots           []TranspositionTableSlot
+	Mask            uint64
+	Age             uint8
+}
+
+// Resize allocates the largest power of two number of entries that fits into sizeInMb megabytes
+func (tt *TranspositionTable) Resize(sizeInMb int){
+	if sizeInMb < 1{
+		sizeInMb = 1
+	}
+
+	maxEntries := uint64(sizeInMb) * 1024 * 1024 / uint64(unsafe.Sizeof(TranspositionTableSlot{}))
+
+	numEntries := uint64(1)
+
+	for numEntries * 2 <= maxEntries{
+		numEntries *= 2
+	}
+
+	if uint64(len(tt.Slots)) == numEntries{
+		return
+	}
+
+	tt.Slots = make([]TranspositionTableSlot, numEntries)
+	tt.Mask = numEntries - 1
+}
+
+func (tt *TranspositionTable) Clear(){
+	for i := range tt.Slots{
+		atomic.StoreUint64(&tt.Slots[i].Key, 0)
+		atomic.StoreUint64(&tt.Slots[i].Data, 0)
+	}
+
+	tt.Age = 0
+}
+
+// NewSearch ages the table, so that entries of previous searches are replaced first
+func (tt *TranspositionTable) NewSearch(){
+	tt.Age = (tt.Age + 1) & TT_AGE_MASK
+}
+
+func (tt *TranspositionTable) Get(zobrist uint64) (TranspositionTableEntry, bool){
+	if len(tt.Slots) == 0{
+		return TranspositionTableEntry{}, false
+	}
+
+	slot := &tt.Slots[zobrist & tt.Mask]
+
+	key := atomic.LoadUint64(&slot.Key)
+	data := atomic.LoadUint64(&slot.Data)
+
+	if key ^ data != zobrist{
+		// empty, other position or torn write
+		return TranspositionTableEntry{}, false
+	}
+
+	entry := UnpackTranspositionTableEntry(zobrist, data)
+
+	return entry, entry.Bound != BoundNone
+}
+
+func (tt *TranspositionTable) Set(zobrist uint64, tte TranspositionTableEntry){
+	if len(tt.Slots) == 0{
+		return
+	}
+
+	slot := &tt.Slots[zobrist & tt.Mask]
+
+	oldKey := atomic.LoadUint64(&slot.Key)
+	oldData := atomic.LoadUint64(&slot.Data)
+
+	oldZobrist := oldKey ^ oldData
+	oldTte := UnpackTranspositionTableEntry(oldZobrist, oldData)
+
+	if oldZobrist != zobrist && oldTte.Bound != BoundNone && oldTte.Age == tt.Age && oldTte.RemDepth > tte.RemDepth{
+		// keep deeper entry of the current search
+		return
+	}
+
+	if oldZobrist == zobrist && tte.Move == NullMove{
+		// keep best move of the same position
+		tte.Move = oldTte.Move
+	}
+
+	tte.Zobrist = zobrist
+	tte.Age = tt.Age
+
+	data := tte.Pack()
+
+	atomic.StoreUint64(&slot.Key, zobrist ^ data)
+	atomic.StoreUint64(&slot.Data, data)
+}
+
+// ScoreToTT converts a mate score relative to the root to a mate score relative to the node at ply
+func ScoreToTT(score Score, ply int) Score{
+	if score > MAX_SCORE{
+		return score + Score(ply)
+	}
+
+	if score < -MAX_SCORE{
+		return score - Score(ply)
+	}
+
+	return score
+}
+
+// ScoreFromTT converts a mate score relative to the node at ply to a mate score relative to the root
+func ScoreFromTT(score Score, ply int) Score{
+	if score > MAX_SCORE{
+		return score - Score(ply)
+	}
+
+	if score < -MAX_SCORE{
+		return score + Score(ply)
+	}
+
+	return score
+}
+
+// the global tables used by the main searcher
+func init(){
+	PvTable.Alloc(PV_HASH_SIZE)
+	PosMoveTable.Alloc(POS_MOVE_HASH_SIZE)
+}
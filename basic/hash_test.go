@@ -0,0 +1,63 @@
+package basic
+
+import "testing"
+
+func TestTranspositionTable(t *testing.T) {
+	tt := TranspositionTable{}
+
+	tt.Resize(1)
+
+	if len(tt.Slots) == 0 || len(tt.Slots)&(len(tt.Slots)-1) != 0 {
+		t.Errorf("expected power of two entries, got %d", len(tt.Slots))
+	}
+
+	zobrist := uint64(0x123456789abcdef)
+
+	if _, ok := tt.Get(zobrist); ok {
+		t.Errorf("empty table should have no entry")
+	}
+
+	move := MakeMoveFT(SquareE2, SquareE4)
+
+	tt.Set(zobrist, TranspositionTableEntry{Move: move, Score: 50, RemDepth: 4, Bound: BoundLower})
+
+	tte, ok := tt.Get(zobrist)
+
+	if !ok || tte.Move != move || tte.Score != 50 || tte.Bound != BoundLower {
+		t.Errorf("stored entry not found, got %v %v", tte, ok)
+	}
+
+	tt.Set(zobrist^(tt.Mask+1), TranspositionTableEntry{Score: 10, RemDepth: 2, Bound: BoundExact})
+
+	if _, ok := tt.Get(zobrist); !ok {
+		t.Errorf("shallower entry should not replace deeper entry of the same search")
+	}
+
+	tt.Clear()
+
+	if _, ok := tt.Get(zobrist); ok {
+		t.Errorf("cleared table should have no entry")
+	}
+}
+
+func TestMateScoreAdjustment(t *testing.T) {
+	mateAtPly5 := MATE_SCORE - 5
+
+	stored := ScoreToTT(mateAtPly5, 3)
+
+	if stored != MATE_SCORE-2 {
+		t.Errorf("expected mate score relative to node %d, got %d", MATE_SCORE-2, stored)
+	}
+
+	if ScoreFromTT(stored, 1) != MATE_SCORE-3 {
+		t.Errorf("expected mate score relative to root %d, got %d", MATE_SCORE-3, ScoreFromTT(stored, 1))
+	}
+
+	if ScoreToTT(-mateAtPly5, 3) != -(MATE_SCORE - 2) {
+		t.Errorf("wrong adjustment of mated score")
+	}
+
+	if ScoreToTT(120, 7) != 120 {
+		t.Errorf("normal score should not be adjusted")
+	}
+}
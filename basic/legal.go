@@ -0,0 +1,401 @@
+package basic
+
+// legal move generation with check and pin masks
+// https://www.chessprogramming.org/Checks_and_Pinned_Pieces_(Bitboards)
+// crazyhouse drops only have to block a check
+// racing kings moves are all made, as giving check is illegal
+// moves the masks can not decide ( en passant, atomic captures, eightpiece sentry pushes and jailer moves ) are tested by making them
+// sentries of the opponent check by pushing one of our pieces onto the king, moves that may change such a push are made as well
+
+// Between[sq1][sq2] are the squares strictly between two squares on a common line, empty if not on a line
+var Between [BOARD_AREA][BOARD_AREA]Bitboard
+
+// initBetween needs RankFile, so it is called from the init of the attack tables
+func initBetween(){
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++{
+		for _, delta := range KING_DELTAS{
+			ray := BbEmpty
+
+			rank := RankOf[sq] + delta.dRank
+			file := FileOf[sq] + delta.dFile
+
+			for rank >= 0 && rank < NUM_RANKS && file >= 0 && file < NUM_FILES{
+				testSq := RankFile[rank][file]
+
+				Between[sq][testSq] = ray
+
+				ray = ray.Or(testSq.Bitboard())
+
+				rank += delta.dRank
+				file += delta.dFile
+			}
+		}
+	}
+}
+
+// max number of pieces pinned to the king, one for each direction
+const MAX_PINS = 8
+
+type Pin struct{
+	Square Square
+	// squares the pinned piece can move to without exposing the king, pinner included
+	Ray    Bitboard
+}
+
+// LegalityInfo holds the check and pin masks of a state
+type LegalityInfo struct{
+	// the masks can not decide, all moves have to be made to test them
+	MakeAll   bool
+	// kings are ordinary pieces or the side to move has none, all pseudo legal moves are legal
+	AllLegal  bool
+	King      Square
+	Checkers  Bitboard
+	// squares where a move of a piece other than the king resolves the check, all squares if not in check
+	Evasions  Bitboard
+	Pinned    Bitboard
+	Pins      [MAX_PINS]Pin
+	NumPins   int
+	// squares of the opponent sentries, their diagonals and the lines from there to the king
+	// a move from or to these squares may allow or prevent a sentry push onto the king, empty if there are no free sentries
+	SentryZone Bitboard
+}
+
+// KnightJumpers returns the pieces that jump like a knight, archbishops and chancellors included
+func (st *State) KnightJumpers() Bitboard{
+	return st.ByFigure[Knight].Or(st.ByFigure[Archbishop]).Or(st.ByFigure[Chancellor])
+}
+
+// DiagonalSliders returns the pieces that slide like a bishop
+func (st *State) DiagonalSliders() Bitboard{
+	return st.ByFigure[Bishop].Or(st.ByFigure[Queen]).Or(st.ByFigure[Archbishop])
+}
+
+// OrthogonalSliders returns the pieces that slide like a rook
+func (st *State) OrthogonalSliders() Bitboard{
+	return st.ByFigure[Rook].Or(st.ByFigure[Queen]).Or(st.ByFigure[Chancellor])
+}
+
+// AttackersOf returns the pieces of color attacking sq with the given occupancy
+// sentries are not considered, jailed pieces do not attack
+func (st *State) AttackersOf(sq Square, color Color, occup Bitboard) Bitboard{
+	bishops := st.DiagonalSliders()
+	rooks := st.OrthogonalSliders()
+
+	attackers := KnightAttack[sq].And(st.KnightJumpers())
+	attackers = attackers.Or(KingAttack[sq].And(st.ByFigure[King]))
+	attackers = attackers.Or(BishopMobility(Violent|Quiet, sq, BbEmpty, occup).And(bishops))
+	attackers = attackers.Or(RookMobility(Violent|Quiet, sq, BbEmpty, occup).And(rooks))
+
+	for _, captInfo := range PawnInfos[sq][color.Inverse()].Captures{
+		attackers = attackers.Or(captInfo.CheckSq.Bitboard().And(st.ByFigure[Pawn]))
+	}
+
+	if st.Rules().FairyPieces{
+		attackers = attackers.Or(st.LancersAttacking(sq, color, occup))
+	}
+
+	attackers = attackers.And(st.ByColor[color]).And(occup)
+
+	if st.Rules().FairyPieces && st.ByFigure[Jailer].And(st.ByColor[color.Inverse()]) != BbEmpty{
+		jailed := attackers
+
+		for jailed != BbEmpty{
+			asq := jailed.Pop()
+
+			if st.IsSquareJailedForColor(asq, color){
+				attackers = attackers.AndNot(asq.Bitboard())
+			}
+		}
+	}
+
+	return attackers
+}
+
+// LancersAttacking returns the lancers of color pointing at sq, lancers pass the pieces of their own color
+func (st *State) LancersAttacking(sq Square, color Color, occup Bitboard) Bitboard{
+	attackers := BbEmpty
+
+	lancers := st.ByLancer.And(st.ByColor[color]).And(occup).And(QueenAttack[sq])
+
+	for lancers != BbEmpty{
+		lancerSq := lancers.Pop()
+
+		if !LancerAttack[lancerSq][st.PieceAtSquare(lancerSq).LancerDirection()].Has(sq){
+			continue
+		}
+
+		if Between[lancerSq][sq].And(occup).AndNot(st.ByColor[color]) == BbEmpty{
+			attackers = attackers.Or(lancerSq.Bitboard())
+		}
+	}
+
+	return attackers
+}
+
+// SentryInfo computes the sentry zone of the opponent sentries that are not jailed, and the sentries that can push one of our pieces onto the king
+func (st *State) SentryInfo(king Square) (Bitboard, Bitboard){
+	us := st.Turn
+	them := us.Inverse()
+
+	zone := BbEmpty
+	checkers := BbEmpty
+
+	// pushes are tested in the position after our move, when no move of ours is disabled any more
+	hasDisabledMove := st.HasDisabledMove
+	st.HasDisabledMove = false
+
+	sentries := st.ByFigure[Sentry].And(st.ByColor[them])
+
+	for sentries != BbEmpty{
+		sentrySq := sentries.Pop()
+
+		if st.IsSquareJailedForColor(sentrySq, them){
+			continue
+		}
+
+		zone = zone.Or(sentrySq.Bitboard()).Or(BishopAttack[sentrySq])
+
+		diagonals := BishopAttack[sentrySq]
+
+		for diagonals != BbEmpty{
+			zone = zone.Or(Between[diagonals.Pop()][king])
+		}
+
+		for _, move := range st.GenSentryMoves(Violent, them, sentrySq, st.ByColor[them], st.ByColor[us], us){
+			if move.PromotionSquare() == king{
+				checkers = checkers.Or(sentrySq.Bitboard())
+				break
+			}
+		}
+	}
+
+	st.HasDisabledMove = hasDisabledMove
+
+	return zone, checkers
+}
+
+// LegalityInfo computes the check and pin masks for the side to move
+func (st *State) LegalityInfo() LegalityInfo{
+	li := LegalityInfo{
+		Evasions: BbFull,
+	}
+
+	us := st.Turn
+	them := us.Inverse()
+
+	if st.Rules().Win == WinAntichess || st.Rules().Win == WinKingCapture || ( st.Rules().Win == WinHorde && us == White ){
+		li.AllLegal = true
+		return li
+	}
+
+	if st.Rules().Win == WinRace{
+		// moves giving check are illegal as well
+		li.MakeAll = true
+		return li
+	}
+
+	if st.KingInfos[us].IsCaptured || ( st.KingInfos[them].IsCaptured && st.Rules().Win != WinHorde ){
+		li.MakeAll = true
+		return li
+	}
+
+	li.King = st.KingInfos[us].Square
+
+	if st.Rules().Explosions && st.KingsAdjacent(){
+		// adjacent kings can not be checked, moves other than king moves keep them adjacent
+		return li
+	}
+
+	occup := st.ByColor[White].Or(st.ByColor[Black])
+
+	li.Checkers = st.AttackersOf(li.King, them, occup)
+
+	switch li.Checkers.Count(){
+	case 0:
+	case 1:
+		li.Evasions = li.Checkers.Or(Between[li.King][li.Checkers.AsSquare()])
+	default:
+		li.Evasions = BbEmpty
+	}
+
+	// sliders that would attack the king if our pieces were removed
+	snipers := BishopMobility(Violent|Quiet, li.King, BbEmpty, st.ByColor[them]).And(st.DiagonalSliders())
+	snipers = snipers.Or(RookMobility(Violent|Quiet, li.King, BbEmpty, st.ByColor[them]).And(st.OrthogonalSliders()))
+
+	snipers = snipers.And(st.ByColor[them])
+
+	if st.Rules().FairyPieces{
+		// lancers pointing at the king behind our pieces, the pieces of their own color do not block them
+		snipers = snipers.Or(st.LancersAttacking(li.King, them, st.ByColor[them]))
+	}
+
+	for snipers != BbEmpty{
+		sniperSq := snipers.Pop()
+
+		if st.Rules().FairyPieces && st.IsSquareJailedForColor(sniperSq, them){
+			continue
+		}
+
+		blockers := Between[li.King][sniperSq].And(occup)
+
+		if st.ByLancer.Has(sniperSq){
+			blockers = blockers.And(st.ByColor[us])
+		}
+
+		// a lancer behind a slider pins the same piece on the same line, it is recorded once
+		if blockers.Count() == 1 && blockers.And(st.ByColor[us]) != BbEmpty && !li.Pinned.Has(blockers.AsSquare()){
+			li.Pinned = li.Pinned.Or(blockers)
+
+			li.Pins[li.NumPins] = Pin{
+				Square: blockers.AsSquare(),
+				Ray: Between[li.King][sniperSq].Or(sniperSq.Bitboard()),
+			}
+
+			li.NumPins++
+		}
+	}
+
+	if st.Rules().FairyPieces{
+		sentryCheckers := BbEmpty
+
+		li.SentryZone, sentryCheckers = st.SentryInfo(li.King)
+
+		if sentryCheckers != BbEmpty{
+			// only moves within the zone can prevent the push, they are made
+			li.Checkers = li.Checkers.Or(sentryCheckers)
+			li.Evasions = li.Evasions.And(li.SentryZone)
+		}
+	}
+
+	return li
+}
+
+// IsLegalByMaking tells whether the pseudo legal move leaves the king in check, by making and taking back the move
+// in racing kings the move must not give check either
+func (st *State) IsLegalByMaking(move Move, undo *Undo) bool{
+	st.MakeMove(move, undo)
+
+	legal := !st.IsCheckedThem()
+
+	if st.Rules().Win == WinRace{
+		legal = legal && !st.IsCheckedUs()
+	}
+
+	st.UnmakeMove(undo)
+
+	return legal
+}
+
+// IsLegal tells whether the pseudo legal move is legal, using the masks of li
+func (st *State) IsLegal(li *LegalityInfo, move Move, undo *Undo) bool{
+	if li.AllLegal{
+		return true
+	}
+
+	if li.MakeAll{
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	if move == NullMove{
+		return li.Checkers == BbEmpty
+	}
+
+	fromSq := move.FromSq()
+	toSq := move.ToSq()
+
+	if move.MoveType() == Drop{
+		// a dropped piece can only block a check, it can not be pinned
+		return li.Evasions.Has(toSq)
+	}
+
+	p := st.PieceAtSquare(fromSq)
+
+	if move.MoveType() == SentryPush || fromSq == toSq{
+		// pushed pieces may block or give check, jailed king pass
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	if move.MoveType() == Castling{
+		if li.SentryZone != BbEmpty{
+			// a sentry may push the castled rook onto the king
+			return st.IsLegalByMaking(move, undo)
+		}
+
+		// the generator tests the squares the king passes
+		return true
+	}
+
+	if FigureOf[p] == Pawn && st.EpSquare != SquareA1 && toSq == st.EpSquare{
+		// en passant removes two pieces from the line of the king
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	if st.Rules().Explosions && st.PieceAtSquare(toSq) != NoPiece{
+		// explosions may remove checkers, pinners and the kings
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	if st.Rules().FairyPieces && FigureOf[p] == Jailer{
+		// jailing and releasing pieces changes the attackers
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	them := st.Turn.Inverse()
+
+	if FigureOf[p] == King{
+		if li.SentryZone != BbEmpty{
+			// the zone of the sentries depends on the square of the king
+			return st.IsLegalByMaking(move, undo)
+		}
+
+		if st.Rules().Explosions && KingAttack[toSq].And(st.ByFigure[King]).And(st.ByColor[them]) != BbEmpty{
+			// king next to the opponent king can not be checked
+			return true
+		}
+
+		occup := st.ByColor[White].Or(st.ByColor[Black]).AndNot(fromSq.Bitboard())
+
+		return st.AttackersOf(toSq, them, occup).AndNot(toSq.Bitboard()) == BbEmpty
+	}
+
+	if li.SentryZone.Has(fromSq) || li.SentryZone.Has(toSq){
+		return st.IsLegalByMaking(move, undo)
+	}
+
+	if !li.Evasions.Has(toSq){
+		return false
+	}
+
+	if li.Pinned.Has(fromSq){
+		for i := 0; i < li.NumPins; i++{
+			if li.Pins[i].Square == fromSq{
+				return li.Pins[i].Ray.Has(toSq)
+
+			}
+		}
+	}
+
+	return true
+}
+
+// LegalMovesByMaking returns the legal moves by making each pseudo legal move, the reference for the legal generator
+func (st *State) LegalMovesByMaking(stopAtFirst bool) []Move {
+	lms := []Move{}
+
+	if end, _, _ := st.VariantEnd(); end{
+		return lms
+	}
+
+	undo := Undo{}
+
+	for _, move := range st.GenerateMoves() {
+		if st.IsLegalByMaking(move, &undo) {
+			lms = append(lms, move)
+			if stopAtFirst {
+				return lms
+			}
+		}
+	}
+
+	return lms
+}
@@ -0,0 +1,134 @@
+package basic
+
+import (
+	"testing"
+)
+
+// compareLegalRec compares the legal generator with making each pseudo legal move in every node of the perft tree
+func compareLegalRec(t *testing.T, pos *Position, depth int) {
+	st := pos.Current()
+
+	moves := st.LegalMoves(false)
+	reference := st.LegalMovesByMaking(false)
+
+	if len(moves) != len(reference) {
+		t.Fatalf("%s : legal generator %d moves, by making %d moves", st.ReportFen(), len(moves), len(reference))
+	}
+
+	for i := range moves {
+		if moves[i] != reference[i] {
+			t.Fatalf("%s : legal generator %s, by making %s", st.ReportFen(), moves[i].UCI(), reference[i].UCI())
+		}
+	}
+
+	if depth <= 1 {
+		return
+	}
+
+	for _, move := range moves {
+		pos.Push(move)
+		compareLegalRec(t, pos, depth-1)
+		pos.Pop()
+	}
+}
+
+func TestLegalGenerator(t *testing.T) {
+	for _, test := range []struct {
+		variant Variant
+		fen     string
+	}{
+		{VariantStandard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+		{VariantStandard, "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"},
+		{VariantStandard, "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1"},
+		{VariantStandard, "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8"},
+		// en passant exposing the king on the rank
+		{VariantStandard, "8/8/8/K1pP3r/8/8/8/7k w - c6 0 1"},
+		{VariantChess960, "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"},
+		{VariantChess960, "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9"},
+		{VariantAtomic, "rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1"},
+		// adjacent kings
+		{VariantAtomic, "8/8/8/3kK3/8/8/2q5/8 w - - 0 1"},
+		{VariantAtomic, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{VariantEightPiece, "jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1 -"},
+		{VariantEightPiece, "j1sqkbnLnw/p1pp1p2/1p4p1/4p3/5lne2/P4P1N/1PPPP1PP/1JSQKB1R w Kq - 0 7 -"},
+		// the knight is pinned by the lancer through the pawn of its own color, the rook is jailed
+		{VariantEightPiece, "4ls2k/8/4p3/8/4N3/Rj6/8/Ln1S1K3 w - - 0 1 -"},
+		// lancer check, the bishop blocks on d2
+		{VariantEightPiece, "k7/8/8/lse7/5B2/2p5/8/4K3 w - - 0 1 -"},
+		// the sentry checks by pushing the knight, the rook blocks on g4
+		{VariantEightPiece, "k7/8/8/7s/8/5N2/8/4K1R1 w - - 0 1 -"},
+		// the sentry would push the castled rook onto the king
+		{VariantEightPiece, "4k3/8/8/8/8/7s/8/4K2R w K - 0 1 -"},
+		// the lancer is jailed, the jailer can not move away
+		{VariantEightPiece, "4k3/8/8/8/8/8/7J/4K2lw w - - 0 1 -"},
+		{VariantEightPiece, "k7/8/8/6Js/8/5N2/8/4K1R1 w - - 0 1 -"},
+		{VariantAntichess, "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w - d6 0 2"},
+		// white has no king
+		{VariantHorde, "4k3/pp4q1/3P2p1/8/P3PP2/PPP2r2/PPP5/PPPP4 b - - 0 1"},
+	} {
+		pos := Position{}
+		pos.Init(test.variant)
+		pos.ParseFen(test.fen)
+
+		compareLegalRec(t, &pos, 3)
+	}
+}
+
+func TestLegalityInfo(t *testing.T) {
+	st := State{}
+	st.Init(VariantStandard)
+	// the bishop on d2 is pinned by the queen on a5, the knight on e2 is not pinned as the pawn on e4 blocks the rook
+	st.ParseFen("4k3/4r3/8/q7/4p3/8/3BN3/4K3 w - - 0 1")
+
+	li := st.LegalityInfo()
+
+	if li.Checkers != BbEmpty || li.Pinned != SquareD2.Bitboard() || li.Pins[0].Ray != SquareA5.Bitboard().Or(SquareB4.Bitboard()).Or(SquareC3.Bitboard()).Or(SquareD2.Bitboard()) {
+		t.Errorf("wrong masks, checkers\n%v pinned\n%v", li.Checkers, li.Pinned)
+	}
+
+	st.ParseFen("4k3/8/8/q7/8/8/8/4K1N1 w - - 0 1")
+
+	li = st.LegalityInfo()
+
+	if li.Checkers != SquareA5.Bitboard() || li.Evasions != SquareA5.Bitboard().Or(SquareB4.Bitboard()).Or(SquareC3.Bitboard()).Or(SquareD2.Bitboard()) {
+		t.Errorf("wrong masks, checkers\n%v evasions\n%v", li.Checkers, li.Evasions)
+	}
+}
+
+func TestEightpieceLegalityInfo(t *testing.T) {
+	st := State{}
+	st.Init(VariantEightPiece)
+
+	st.ParseFen("4ls2k/8/4p3/8/4N3/Rj6/8/Ln1S1K3 w - - 0 1 -")
+
+	li := st.LegalityInfo()
+
+	if li.MakeAll || li.Checkers != BbEmpty || li.Pinned != SquareE4.Bitboard() || li.SentryZone != BbEmpty {
+		t.Errorf("the lancer should pin the knight, pinned\n%v", li.Pinned)
+	}
+
+	st.ParseFen("k7/8/8/lse7/5B2/2p5/8/4K3 w - - 0 1 -")
+
+	li = st.LegalityInfo()
+
+	if li.MakeAll || li.Checkers != SquareA5.Bitboard() || li.Evasions != SquareA5.Bitboard().Or(SquareB4.Bitboard()).Or(SquareC3.Bitboard()).Or(SquareD2.Bitboard()) {
+		t.Errorf("wrong lancer check masks, checkers\n%v evasions\n%v", li.Checkers, li.Evasions)
+	}
+
+	st.ParseFen("k7/8/8/7s/8/5N2/8/4K1R1 w - - 0 1 -")
+
+	li = st.LegalityInfo()
+
+	if li.MakeAll || li.Checkers != SquareH5.Bitboard() || !li.SentryZone.Has(SquareF3) || !li.SentryZone.Has(SquareG4) || li.Evasions.Has(SquareA3) {
+		t.Errorf("wrong sentry check masks, checkers\n%v zone\n%v", li.Checkers, li.SentryZone)
+	}
+
+	// a jailed sentry can not push
+	st.ParseFen("k7/8/8/6Js/8/5N2/8/4K1R1 w - - 0 1 -")
+
+	li = st.LegalityInfo()
+
+	if li.Checkers != BbEmpty || li.SentryZone != BbEmpty {
+		t.Errorf("jailed sentry should not check, checkers\n%v", li.Checkers)
+	}
+}
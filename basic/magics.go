@@ -4,149 +4,184 @@ package basic
 
 import "fmt"
 
+// the key of an occupancy is ( Lo * Magic ^ Hi * MagicHi ) >> ( 64 - Shift ), Mask is set up by init
 type MagicSquare struct{
     Square  Square
     Shift   int
     Magic   uint64
+    MagicHi uint64
+    Mask    Bitboard
     Entries []Bitboard
 }
 
 func (msq MagicSquare) String() string{
-    return fmt.Sprintf("MagicSquare %v %2d %016X %d", msq.Square, msq.Shift, msq.Magic, len(msq.Entries))
+    return fmt.Sprintf("MagicSquare %v %2d %016X %016X %d", msq.Square, msq.Shift, msq.Magic, msq.MagicHi, len(msq.Entries))
 }
 
 type Magics [BOARD_AREA]MagicSquare
 
 var BISHOP_MAGICS = Magics{
-   {SquareA1,   7, 0x00400a4182001490, []Bitboard{}},
-   {SquareB1,   7, 0x0010008829000c00, []Bitboard{}},
-   {SquareC1,   7, 0x0044008180080000, []Bitboard{}},
-   {SquareD1,   7, 0x0094220020000400, []Bitboard{}},
-   {SquareE1,   7, 0x000048d600000100, []Bitboard{}},
-   {SquareF1,   7, 0x0004300082601280, []Bitboard{}},
-   {SquareG1,   7, 0x0044002208600801, []Bitboard{}},
-   {SquareH1,   7, 0x0040602206d64000, []Bitboard{}},
-   {SquareA2,   7, 0x0080461220884010, []Bitboard{}},
-   {SquareB2,   7, 0x0044201800848010, []Bitboard{}},
-   {SquareC2,   7, 0x0000404084224800, []Bitboard{}},
-   {SquareD2,   7, 0x00008802c0400184, []Bitboard{}},
-   {SquareE2,   7, 0x0000009040004002, []Bitboard{}},
-   {SquareF2,   7, 0x000081c202240021, []Bitboard{}},
-   {SquareG2,   7, 0x0001320082011000, []Bitboard{}},
-   {SquareH2,   7, 0x000002e024020080, []Bitboard{}},
-   {SquareA3,   7, 0x000102024c100440, []Bitboard{}},
-   {SquareB3,   7, 0x0001044210020200, []Bitboard{}},
-   {SquareC3,   7, 0x0008040400c40008, []Bitboard{}},
-   {SquareD3,   8, 0x0000800042004800, []Bitboard{}},
-   {SquareE3,   8, 0x0001000020080048, []Bitboard{}},
-   {SquareF3,   8, 0x0000510028004800, []Bitboard{}},
-   {SquareG3,   7, 0x000a000020806100, []Bitboard{}},
-   {SquareH3,   7, 0x000020008c028480, []Bitboard{}},
-   {SquareA4,   7, 0x0004040402018680, []Bitboard{}},
-   {SquareB4,   7, 0x0009010020380110, []Bitboard{}},
-   {SquareC4,   8, 0x0000100318004150, []Bitboard{}},
-   {SquareD4,  12, 0x0004081404060002, []Bitboard{}},
-   {SquareE4,  10, 0x0000108002100400, []Bitboard{}},
-   {SquareF4,   9, 0x0008008020082000, []Bitboard{}},
-   {SquareG4,   7, 0x00080a4000804440, []Bitboard{}},
-   {SquareH4,   7, 0x0002402042148100, []Bitboard{}},
-   {SquareA5,   7, 0x02009040e0080200, []Bitboard{}},
-   {SquareB5,   7, 0x0002010090040808, []Bitboard{}},
-   {SquareC5,   9, 0x0000080041220040, []Bitboard{}},
-   {SquareD5,  11, 0x0000022604040100, []Bitboard{}},
-   {SquareE5,  10, 0x0002004010080200, []Bitboard{}},
-   {SquareF5,   8, 0x0008120020411000, []Bitboard{}},
-   {SquareG5,   7, 0x000081c202240021, []Bitboard{}},
-   {SquareH5,   7, 0x000a022048002201, []Bitboard{}},
-   {SquareA6,   7, 0x0001648a10084840, []Bitboard{}},
-   {SquareB6,   7, 0x0000842100802000, []Bitboard{}},
-   {SquareC6,   8, 0x0201010020804200, []Bitboard{}},
-   {SquareD6,   8, 0x0000002458000400, []Bitboard{}},
-   {SquareE6,   7, 0x0000400091000200, []Bitboard{}},
-   {SquareF6,   7, 0x0040080801200040, []Bitboard{}},
-   {SquareG6,   7, 0x0003100502000a40, []Bitboard{}},
-   {SquareH6,   7, 0x000102024c100440, []Bitboard{}},
-   {SquareA7,   7, 0x0201008043202000, []Bitboard{}},
-   {SquareB7,   7, 0x00080c1414040000, []Bitboard{}},
-   {SquareC7,   7, 0x0282009040424800, []Bitboard{}},
-   {SquareD7,   7, 0x0084400050120000, []Bitboard{}},
-   {SquareE7,   7, 0x0000001002408000, []Bitboard{}},
-   {SquareF7,   7, 0x0000042480604000, []Bitboard{}},
-   {SquareG7,   7, 0x0070228302640200, []Bitboard{}},
-   {SquareH7,   7, 0x0011080300308084, []Bitboard{}},
-   {SquareA8,   7, 0x0000440085272000, []Bitboard{}},
-   {SquareB8,   7, 0x02b0010012018200, []Bitboard{}},
-   {SquareC8,   7, 0x0200000080802114, []Bitboard{}},
-   {SquareD8,   7, 0x0011080300308084, []Bitboard{}},
-   {SquareE8,   7, 0x0002090404008205, []Bitboard{}},
-   {SquareF8,   7, 0x0020081001101121, []Bitboard{}},
-   {SquareG8,   7, 0x0200090d00884010, []Bitboard{}},
-   {SquareH8,   7, 0x00400a4182001490, []Bitboard{}},
+   {SquareA1,   6, 0x00048200100c0026, 0x0284000111000100, Bitboard{}, []Bitboard{}},
+   {SquareB1,   6, 0x8008001028c28084, 0x8841084008801880, Bitboard{}, []Bitboard{}},
+   {SquareC1,   7, 0x0046028043041290, 0x0020002400280011, Bitboard{}, []Bitboard{}},
+   {SquareD1,   7, 0x000100100049000a, 0x8000902408020440, Bitboard{}, []Bitboard{}},
+   {SquareE1,   7, 0x4003010120400260, 0x000000080a818400, Bitboard{}, []Bitboard{}},
+   {SquareF1,   7, 0x120020080e400020, 0x10004000005010b0, Bitboard{}, []Bitboard{}},
+   {SquareG1,   7, 0x8403c01020210120, 0x8008000024080040, Bitboard{}, []Bitboard{}},
+   {SquareH1,   7, 0x00000822100200a4, 0x4204888900108400, Bitboard{}, []Bitboard{}},
+   {SquareI1,   6, 0x2204200400820042, 0x0008308000104400, Bitboard{}, []Bitboard{}},
+   {SquareJ1,   6, 0x108049020a01c101, 0x4180a10800828008, Bitboard{}, []Bitboard{}},
+   {SquareA2,   5, 0x02080401480808a8, 0x0881200008000040, Bitboard{}, []Bitboard{}},
+   {SquareB2,   5, 0x00048200100c0026, 0x0284000111000100, Bitboard{}, []Bitboard{}},
+   {SquareC2,   6, 0x002001048100a040, 0x3101002000000088, Bitboard{}, []Bitboard{}},
+   {SquareD2,   7, 0x0800044104820004, 0x82202018d0000002, Bitboard{}, []Bitboard{}},
+   {SquareE2,   7, 0x4401824004011004, 0x9800000004002000, Bitboard{}, []Bitboard{}},
+   {SquareF2,   7, 0x8000210820131004, 0x24c0000100080000, Bitboard{}, []Bitboard{}},
+   {SquareG2,   7, 0x0310a09004090642, 0x00323008110000a0, Bitboard{}, []Bitboard{}},
+   {SquareH2,   6, 0x002b001802408021, 0x0000092001080415, Bitboard{}, []Bitboard{}},
+   {SquareI2,   5, 0x040400a504008081, 0x40829008240008a0, Bitboard{}, []Bitboard{}},
+   {SquareJ2,   5, 0x08820000a5004104, 0x4600000480000004, Bitboard{}, []Bitboard{}},
+   {SquareA3,   5, 0x001af00d80200440, 0x6000004818800005, Bitboard{}, []Bitboard{}},
+   {SquareB3,   5, 0x2218201120181934, 0x0800410014000004, Bitboard{}, []Bitboard{}},
+   {SquareC3,   7, 0x2001806204740500, 0x4829000006d24011, Bitboard{}, []Bitboard{}},
+   {SquareD3,   8, 0x3010300070040060, 0x0044800002000209, Bitboard{}, []Bitboard{}},
+   {SquareE3,   9, 0x0100484428020010, 0x0460400041020040, Bitboard{}, []Bitboard{}},
+   {SquareF3,   9, 0x0009280450100101, 0x0041060000007042, Bitboard{}, []Bitboard{}},
+   {SquareG3,   8, 0x0221100000490401, 0x0001000220802418, Bitboard{}, []Bitboard{}},
+   {SquareH3,   7, 0x0810020080420881, 0x1858120000490004, Bitboard{}, []Bitboard{}},
+   {SquareI3,   5, 0x8080204001064030, 0x8200c8e000200048, Bitboard{}, []Bitboard{}},
+   {SquareJ3,   5, 0x88002002004a80aa, 0x041e200202400004, Bitboard{}, []Bitboard{}},
+   {SquareA4,   5, 0x2021020000060211, 0x0408000600001004, Bitboard{}, []Bitboard{}},
+   {SquareB4,   5, 0x8004820480100100, 0x0950259600000908, Bitboard{}, []Bitboard{}},
+   {SquareC4,   7, 0x1041120a41310204, 0x08100000204000a1, Bitboard{}, []Bitboard{}},
+   {SquareD4,   9, 0x2804a14000021002, 0x2080090000020c40, Bitboard{}, []Bitboard{}},
+   {SquareE4,  10, 0x6001084008008012, 0x0208a90300048000, Bitboard{}, []Bitboard{}},
+   {SquareF4,  10, 0x008100420085004a, 0x10a4008000000000, Bitboard{}, []Bitboard{}},
+   {SquareG4,   9, 0x0200080410008005, 0x2010000240000900, Bitboard{}, []Bitboard{}},
+   {SquareH4,   7, 0x0026804200531004, 0x8000402001062000, Bitboard{}, []Bitboard{}},
+   {SquareI4,   5, 0x0010c28202010208, 0x1000810022004400, Bitboard{}, []Bitboard{}},
+   {SquareJ4,   5, 0x81004800c0004208, 0x1a80040008100881, Bitboard{}, []Bitboard{}},
+   {SquareA5,   5, 0x0090810010100462, 0x4000000400080040, Bitboard{}, []Bitboard{}},
+   {SquareB5,   5, 0x020062809000c201, 0x0001102004000300, Bitboard{}, []Bitboard{}},
+   {SquareC5,   7, 0x483e8010c2001040, 0x0208480800010002, Bitboard{}, []Bitboard{}},
+   {SquareD5,   9, 0x4029880820000242, 0x0120020c20040580, Bitboard{}, []Bitboard{}},
+   {SquareE5,  10, 0xe004010202042882, 0x0022400005404014, Bitboard{}, []Bitboard{}},
+   {SquareF5,  10, 0x4221008082004001, 0x501011c1020e0004, Bitboard{}, []Bitboard{}},
+   {SquareG5,   9, 0x2404001120100010, 0x0400000100018400, Bitboard{}, []Bitboard{}},
+   {SquareH5,   7, 0x000a272000408220, 0x1121004200188800, Bitboard{}, []Bitboard{}},
+   {SquareI5,   5, 0x4001001820400228, 0x040424a001010340, Bitboard{}, []Bitboard{}},
+   {SquareJ5,   5, 0x2000081000400208, 0x2840080414010000, Bitboard{}, []Bitboard{}},
+   {SquareA6,   5, 0x0100208810408001, 0x6321044024626020, Bitboard{}, []Bitboard{}},
+   {SquareB6,   5, 0x28804c4004040102, 0x700000200c000100, Bitboard{}, []Bitboard{}},
+   {SquareC6,   7, 0x1008c24411080201, 0x0221006006101080, Bitboard{}, []Bitboard{}},
+   {SquareD6,   8, 0x9101400412420001, 0x0410002408090310, Bitboard{}, []Bitboard{}},
+   {SquareE6,   9, 0x4a04504502008023, 0x0080200010022000, Bitboard{}, []Bitboard{}},
+   {SquareF6,   9, 0x0041024440110002, 0x0881000004080800, Bitboard{}, []Bitboard{}},
+   {SquareG6,   8, 0x6001908000802112, 0xc100060070001040, Bitboard{}, []Bitboard{}},
+   {SquareH6,   7, 0x04c100044260a000, 0x1100101001088400, Bitboard{}, []Bitboard{}},
+   {SquareI6,   5, 0x0420202012002201, 0x1000c06000408018, Bitboard{}, []Bitboard{}},
+   {SquareJ6,   5, 0x00010810188c2024, 0x0780090100390004, Bitboard{}, []Bitboard{}},
+   {SquareA7,   5, 0x008100080aa80800, 0x858200100d080084, Bitboard{}, []Bitboard{}},
+   {SquareB7,   5, 0x0240106006902200, 0x0000024000804000, Bitboard{}, []Bitboard{}},
+   {SquareC7,   6, 0x0130080308181102, 0x0000000000000480, Bitboard{}, []Bitboard{}},
+   {SquareD7,   7, 0x0200010204041200, 0x0000060001000000, Bitboard{}, []Bitboard{}},
+   {SquareE7,   7, 0x0202002102009040, 0x0020000018000500, Bitboard{}, []Bitboard{}},
+   {SquareF7,   7, 0x04200a0054405080, 0x8680000000018001, Bitboard{}, []Bitboard{}},
+   {SquareG7,   7, 0x400400406028040c, 0x8002028400004088, Bitboard{}, []Bitboard{}},
+   {SquareH7,   6, 0x0000810115010010, 0x1004000320800000, Bitboard{}, []Bitboard{}},
+   {SquareI7,   5, 0x00048a4401008040, 0x0001006d10010020, Bitboard{}, []Bitboard{}},
+   {SquareJ7,   5, 0x030a022021834004, 0x1002030011480000, Bitboard{}, []Bitboard{}},
+   {SquareA8,   6, 0x1000082001900103, 0x0000010000800050, Bitboard{}, []Bitboard{}},
+   {SquareB8,   6, 0x2204200400820042, 0x0008308000104400, Bitboard{}, []Bitboard{}},
+   {SquareC8,   7, 0x8082100200204051, 0x0414001000400002, Bitboard{}, []Bitboard{}},
+   {SquareD8,   7, 0x0000020200204101, 0x4204000000442028, Bitboard{}, []Bitboard{}},
+   {SquareE8,   7, 0x0450080280200821, 0x08c42084020268a0, Bitboard{}, []Bitboard{}},
+   {SquareF8,   7, 0x002001048100a040, 0x3101002000000088, Bitboard{}, []Bitboard{}},
+   {SquareG8,   7, 0x6080040010984010, 0x0428800000090050, Bitboard{}, []Bitboard{}},
+   {SquareH8,   7, 0x0002048040004486, 0x0080010000012000, Bitboard{}, []Bitboard{}},
+   {SquareI8,   6, 0x8008001028c28084, 0x8841084008801880, Bitboard{}, []Bitboard{}},
+   {SquareJ8,   6, 0x8020200802010240, 0x4200200a04040100, Bitboard{}, []Bitboard{}},
 }
 
 var ROOK_MAGICS = Magics{
-   {SquareA1,  14, 0x0004220080104000, []Bitboard{}},
-   {SquareB1,  14, 0x0018004240681000, []Bitboard{}},
-   {SquareC1,  14, 0x0030000205102000, []Bitboard{}},
-   {SquareD1,  14, 0x0200020110208a00, []Bitboard{}},
-   {SquareE1,  13, 0x0200080401008200, []Bitboard{}},
-   {SquareF1,  13, 0x0200080401008200, []Bitboard{}},
-   {SquareG1,  13, 0x0200042060510200, []Bitboard{}},
-   {SquareH1,  14, 0x0010005080281100, []Bitboard{}},
-   {SquareA2,  13, 0x0000f00021081400, []Bitboard{}},
-   {SquareB2,  13, 0x0000020408401000, []Bitboard{}},
-   {SquareC2,  13, 0x0000900220009000, []Bitboard{}},
-   {SquareD2,  13, 0x0000400140041200, []Bitboard{}},
-   {SquareE2,  14, 0x0000080100480340, []Bitboard{}},
-   {SquareF2,  14, 0x0000040100402210, []Bitboard{}},
-   {SquareG2,  12, 0x0054000080420328, []Bitboard{}},
-   {SquareH2,  14, 0x00840800900200c8, []Bitboard{}},
-   {SquareA3,  13, 0x0000580600148000, []Bitboard{}},
-   {SquareB3,  13, 0x0000810200085000, []Bitboard{}},
-   {SquareC3,  13, 0x0000341002100080, []Bitboard{}},
-   {SquareD3,  13, 0x0000011000104080, []Bitboard{}},
-   {SquareE3,  13, 0x0000230001000098, []Bitboard{}},
-   {SquareF3,  14, 0x0000010400002100, []Bitboard{}},
-   {SquareG3,  14, 0x0001084011000080, []Bitboard{}},
-   {SquareH3,  13, 0x0000081000400100, []Bitboard{}},
-   {SquareA4,  13, 0x0001480804001000, []Bitboard{}},
-   {SquareB4,  14, 0x0000040110000200, []Bitboard{}},
-   {SquareC4,  13, 0x0000089061000200, []Bitboard{}},
-   {SquareD4,  13, 0x0000100500024100, []Bitboard{}},
-   {SquareE4,  14, 0x0000044200004a00, []Bitboard{}},
-   {SquareF4,  13, 0x0000005200018108, []Bitboard{}},
-   {SquareG4,  12, 0x0000009400004118, []Bitboard{}},
-   {SquareH4,  13, 0x0000002030080280, []Bitboard{}},
-   {SquareA5,  13, 0x0000081100288000, []Bitboard{}},
-   {SquareB5,  14, 0x0000024200890880, []Bitboard{}},
-   {SquareC5,  13, 0x0208841042000200, []Bitboard{}},
-   {SquareD5,  14, 0x0090018802000120, []Bitboard{}},
-   {SquareE5,  13, 0x0000230001000098, []Bitboard{}},
-   {SquareF5,  13, 0x000002283000c020, []Bitboard{}},
-   {SquareG5,  12, 0x0001004204000288, []Bitboard{}},
-   {SquareH5,  13, 0x0200014002a40010, []Bitboard{}},
-   {SquareA6,  12, 0x00009080c02a8000, []Bitboard{}},
-   {SquareB6,  13, 0x0001080090080200, []Bitboard{}},
-   {SquareC6,  13, 0x0000040e00a02000, []Bitboard{}},
-   {SquareD6,  13, 0x00002c1201000400, []Bitboard{}},
-   {SquareE6,  13, 0x00000218400a1800, []Bitboard{}},
-   {SquareF6,  13, 0x0000040080010400, []Bitboard{}},
-   {SquareG6,  13, 0x0000010120408004, []Bitboard{}},
-   {SquareH6,  13, 0x0000004000220802, []Bitboard{}},
-   {SquareA7,  12, 0x0000832600442a00, []Bitboard{}},
-   {SquareB7,  13, 0x00004081041a1200, []Bitboard{}},
-   {SquareC7,  13, 0x000c702008c00200, []Bitboard{}},
-   {SquareD7,  12, 0x0000200851040200, []Bitboard{}},
-   {SquareE7,  13, 0x0000100140880200, []Bitboard{}},
-   {SquareF7,  12, 0x0200080401008200, []Bitboard{}},
-   {SquareG7,  11, 0x0000020800815400, []Bitboard{}},
-   {SquareH7,  13, 0x0000001842400320, []Bitboard{}},
-   {SquareA8,  13, 0x0000c060218a0012, []Bitboard{}},
-   {SquareB8,  13, 0x0002000480406152, []Bitboard{}},
-   {SquareC8,  13, 0x00000402080940a2, []Bitboard{}},
-   {SquareD8,  13, 0x0000020201181022, []Bitboard{}},
-   {SquareE8,  14, 0x0008012050008402, []Bitboard{}},
-   {SquareF8,  14, 0x00000081120000aa, []Bitboard{}},
-   {SquareG8,  12, 0x0000044201080084, []Bitboard{}},
-   {SquareH8,  13, 0x0000008031004022, []Bitboard{}},
+   {SquareA1,  14, 0x2820002180502224, 0x0420800002e44018, Bitboard{}, []Bitboard{}},
+   {SquareB1,  13, 0x0010020008100144, 0xa4049ac0a0808000, Bitboard{}, []Bitboard{}},
+   {SquareC1,  13, 0x1060020000482041, 0x183110030c600600, Bitboard{}, []Bitboard{}},
+   {SquareD1,  13, 0x8020008000808321, 0x0400000802848000, Bitboard{}, []Bitboard{}},
+   {SquareE1,  13, 0x09400040c0080090, 0x8000a00290004080, Bitboard{}, []Bitboard{}},
+   {SquareF1,  13, 0x880400c100100242, 0x0400020000220000, Bitboard{}, []Bitboard{}},
+   {SquareG1,  13, 0x4020001001a08080, 0x2210002000018000, Bitboard{}, []Bitboard{}},
+   {SquareH1,  13, 0x0040000824001410, 0x000b000800222800, Bitboard{}, []Bitboard{}},
+   {SquareI1,  13, 0x098001042c020060, 0x2040c00000010200, Bitboard{}, []Bitboard{}},
+   {SquareJ1,  14, 0x20c000024c201210, 0x0012902020040810, Bitboard{}, []Bitboard{}},
+   {SquareA2,  13, 0x0440080008602004, 0x0028000404000c00, Bitboard{}, []Bitboard{}},
+   {SquareB2,  12, 0x0040440010200005, 0x9800004100420432, Bitboard{}, []Bitboard{}},
+   {SquareC2,  12, 0x00a0080200100006, 0x0040004040000100, Bitboard{}, []Bitboard{}},
+   {SquareD2,  12, 0x0040080200080001, 0x8000804000000104, Bitboard{}, []Bitboard{}},
+   {SquareE2,  12, 0x0080200116004202, 0x8248002050001100, Bitboard{}, []Bitboard{}},
+   {SquareF2,  12, 0x6060020084060100, 0x0804040005841094, Bitboard{}, []Bitboard{}},
+   {SquareG2,  12, 0x210048000c001080, 0x0200a00090000042, Bitboard{}, []Bitboard{}},
+   {SquareH2,  12, 0x018808014c000220, 0x02004004200a4000, Bitboard{}, []Bitboard{}},
+   {SquareI2,  12, 0x4201a0000840c060, 0x4014210200000521, Bitboard{}, []Bitboard{}},
+   {SquareJ2,  13, 0x0230280001000888, 0x80824208a0106484, Bitboard{}, []Bitboard{}},
+   {SquareA3,  13, 0x802000010000509e, 0x8200000160101048, Bitboard{}, []Bitboard{}},
+   {SquareB3,  12, 0x000400030002f002, 0x015c003000000000, Bitboard{}, []Bitboard{}},
+   {SquareC3,  12, 0x00a0080200100006, 0x0040004040000100, Bitboard{}, []Bitboard{}},
+   {SquareD3,  12, 0x0004180200180081, 0x0000014002059000, Bitboard{}, []Bitboard{}},
+   {SquareE3,  12, 0x2400100400900200, 0x0010000000500000, Bitboard{}, []Bitboard{}},
+   {SquareF3,  12, 0x0020080a00020100, 0x0010001e00080020, Bitboard{}, []Bitboard{}},
+   {SquareG3,  12, 0x2090200500400440, 0xa00204b000490100, Bitboard{}, []Bitboard{}},
+   {SquareH3,  12, 0xa000a00200008848, 0x1002000002281000, Bitboard{}, []Bitboard{}},
+   {SquareI3,  12, 0x93008c0100304008, 0x0804400800640000, Bitboard{}, []Bitboard{}},
+   {SquareJ3,  13, 0x8880200100002a51, 0x0100802808204040, Bitboard{}, []Bitboard{}},
+   {SquareA4,  13, 0xa00082020a800004, 0x0120200025200102, Bitboard{}, []Bitboard{}},
+   {SquareB4,  12, 0x0010a40000c00801, 0x0460008100000004, Bitboard{}, []Bitboard{}},
+   {SquareC4,  12, 0x4402110000802002, 0x4070200026681054, Bitboard{}, []Bitboard{}},
+   {SquareD4,  12, 0x10202a8000802001, 0x8004000030200060, Bitboard{}, []Bitboard{}},
+   {SquareE4,  12, 0x0042000401000103, 0x4122000440003020, Bitboard{}, []Bitboard{}},
+   {SquareF4,  12, 0x0090102021000100, 0x8400000000000001, Bitboard{}, []Bitboard{}},
+   {SquareG4,  12, 0x0040002003000110, 0x0004504000070020, Bitboard{}, []Bitboard{}},
+   {SquareH4,  12, 0x0004000880800244, 0x0201000040300000, Bitboard{}, []Bitboard{}},
+   {SquareI4,  12, 0x00000044520000a0, 0x0044200040c00100, Bitboard{}, []Bitboard{}},
+   {SquareJ4,  13, 0x1024080088800030, 0x0002608000040810, Bitboard{}, []Bitboard{}},
+   {SquareA5,  13, 0x2020000900001002, 0x0112a48002800100, Bitboard{}, []Bitboard{}},
+   {SquareB5,  12, 0x8a100000a0000801, 0x220408000002002c, Bitboard{}, []Bitboard{}},
+   {SquareC5,  12, 0x0028000208212001, 0x0000000000101800, Bitboard{}, []Bitboard{}},
+   {SquareD5,  12, 0x490002202101c001, 0x6008002085004800, Bitboard{}, []Bitboard{}},
+   {SquareE5,  12, 0x00004049a0108002, 0x0800000000002884, Bitboard{}, []Bitboard{}},
+   {SquareF5,  12, 0x460480c010020100, 0x0500004004020509, Bitboard{}, []Bitboard{}},
+   {SquareG5,  12, 0x0040400040020080, 0x0084c80004000010, Bitboard{}, []Bitboard{}},
+   {SquareH5,  12, 0x0430004008008020, 0x8084008028088948, Bitboard{}, []Bitboard{}},
+   {SquareI5,  12, 0x4000208141001004, 0x060000002100d000, Bitboard{}, []Bitboard{}},
+   {SquareJ5,  13, 0x0080280400002008, 0x0100008020000000, Bitboard{}, []Bitboard{}},
+   {SquareA6,  13, 0x0e11040061222108, 0x4060900000500100, Bitboard{}, []Bitboard{}},
+   {SquareB6,  12, 0x0a68050000a00024, 0x008100c110080000, Bitboard{}, []Bitboard{}},
+   {SquareC6,  12, 0x0004008010030802, 0x0000c40040020001, Bitboard{}, []Bitboard{}},
+   {SquareD6,  12, 0x0422002004000401, 0x0004200035000406, Bitboard{}, []Bitboard{}},
+   {SquareE6,  12, 0x2888004023080010, 0x5040c01000210102, Bitboard{}, []Bitboard{}},
+   {SquareF6,  12, 0x0000138090004010, 0x001b24cd00000084, Bitboard{}, []Bitboard{}},
+   {SquareG6,  12, 0x088cc08110024060, 0x0808024000001403, Bitboard{}, []Bitboard{}},
+   {SquareH6,  12, 0x0003200410010060, 0x1008020408060401, Bitboard{}, []Bitboard{}},
+   {SquareI6,  12, 0x0e880821024c0040, 0x8003050494000490, Bitboard{}, []Bitboard{}},
+   {SquareJ6,  13, 0x0420108000880804, 0x010428a980c00200, Bitboard{}, []Bitboard{}},
+   {SquareA7,  13, 0x0000821040240201, 0x0008008080010058, Bitboard{}, []Bitboard{}},
+   {SquareB7,  12, 0x0004800423100025, 0x0104088400410200, Bitboard{}, []Bitboard{}},
+   {SquareC7,  12, 0x2044002010404801, 0x021000000202c000, Bitboard{}, []Bitboard{}},
+   {SquareD7,  12, 0x0005104002050082, 0x80104010a8480000, Bitboard{}, []Bitboard{}},
+   {SquareE7,  12, 0x0000040062070019, 0x0608010010000004, Bitboard{}, []Bitboard{}},
+   {SquareF7,  12, 0x0000005808481085, 0x9840400044010000, Bitboard{}, []Bitboard{}},
+   {SquareG7,  12, 0x0600124409000649, 0x2840000400200044, Bitboard{}, []Bitboard{}},
+   {SquareH7,  12, 0x010000c820020441, 0x0401000024504000, Bitboard{}, []Bitboard{}},
+   {SquareI7,  12, 0x2044002010404801, 0x021000000202c000, Bitboard{}, []Bitboard{}},
+   {SquareJ7,  13, 0x000900c009251801, 0x8404029470101800, Bitboard{}, []Bitboard{}},
+   {SquareA8,  14, 0x2000068002103008, 0x0800800001202900, Bitboard{}, []Bitboard{}},
+   {SquareB8,  13, 0x00d1a0040010000a, 0x2088080810020000, Bitboard{}, []Bitboard{}},
+   {SquareC8,  13, 0x441084022400040a, 0x0000400080084008, Bitboard{}, []Bitboard{}},
+   {SquareD8,  13, 0x480280050208c101, 0x4500401001000300, Bitboard{}, []Bitboard{}},
+   {SquareE8,  13, 0x0000888014408001, 0x0020100006180004, Bitboard{}, []Bitboard{}},
+   {SquareF8,  13, 0x8042001000020004, 0x4000407002100008, Bitboard{}, []Bitboard{}},
+   {SquareG8,  13, 0x0010808021408140, 0x1008080410008010, Bitboard{}, []Bitboard{}},
+   {SquareH8,  13, 0x0010808021408140, 0x1008080410008010, Bitboard{}, []Bitboard{}},
+   {SquareI8,  13, 0x410080802804500a, 0x0840020101850107, Bitboard{}, []Bitboard{}},
+   {SquareJ8,  14, 0x1002988008200088, 0x0040080020140000, Bitboard{}, []Bitboard{}},
 }
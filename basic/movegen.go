@@ -1,6 +1,9 @@
 package basic
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 func FmtDummy(){
 	fmt.Println("")
@@ -16,6 +19,8 @@ const (
 	SentryPush
 	Castling
 	Null
+	// crazyhouse drop, the dropped piece is the promotion piece, from and to squares are the target square
+	Drop
 )
 
 // stack phases
@@ -26,35 +31,37 @@ const (
 	GenDone
 )
 
-func (st *State) InitStack(nmp bool, pvTable *PvHash, ignoreMoves []Move){	
+func (ss *SearchStack) InitStack(st *State, nmp bool, pvTable *PvHash, ignoreMoves []Move, hashMove Move){	
 	_, entry, ok := pvTable.Get(st.Zobrist)
 
-	st.StackPvMoves = [MAX_PV_MOVES]Move{}
+	ss.StackPvMoves = [MAX_PV_MOVES]Move{}
 
 	if ok{
-		st.StackPvMoves = entry.Moves
+		ss.StackPvMoves = entry.Moves
 	}
 
-	st.StackIgnoreMoves = ignoreMoves
+	ss.StackHashMove = hashMove
+
+	ss.StackIgnoreMoves = ignoreMoves
 
-	st.StackReduceDepth = 0
+	ss.StackReduceDepth = 0
 
-	st.StackPhase = GenAll
+	ss.StackPhase = GenAll
 
 	if nmp{
-		st.StackPhase = PopNull
+		ss.StackPhase = PopNull
 	}
 }
 
-func (st *State) PopStackBuff() (StackBuffEntry, bool){
-	l := len(st.StackBuff)
+func (ss *SearchStack) PopStackBuff() (StackBuffEntry, bool){
+	l := len(ss.StackBuff)
 
 	if l <= 0{
 		return StackBuffEntry{}, false
 	}
 
-	sbe := st.StackBuff[l-1]
-	st.StackBuff = st.StackBuff[0:l-1]
+	sbe := ss.StackBuff[l-1]
+	ss.StackBuff = ss.StackBuff[0:l-1]
 
 	return sbe, true
 }
@@ -63,9 +70,11 @@ const NullMove = Move(Null) << MOVE_TYPE_SHIFT
 
 const MIN_REDUCE_LIMIT = 8
 
-func (st *State) PopStack(pos *Position) Move{
-	if st.StackPhase == PopNull{
-		st.StackPhase = GenAll
+func (ss *SearchStack) PopStack(pos *Position) Move{
+	st := pos.Current()
+
+	if ss.StackPhase == PopNull{
+		ss.StackPhase = GenAll
 
 		_, _, hasPvMove := pos.PvTable.Get(st.Zobrist)
 
@@ -74,9 +83,9 @@ func (st *State) PopStack(pos *Position) Move{
 		}
 	}
 
-	if st.StackPhase == GenAll{
-		st.SetStackBuff(pos, st.GenerateMoves())
-		numAll := len(st.StackBuff)
+	if ss.StackPhase == GenAll{
+		ss.SetStackBuff(pos, st.SearchMoves(pos.DuckSquares))
+		numAll := len(ss.StackBuff)
 
 		rF := 1
 		for rF * rF < numAll{
@@ -93,27 +102,27 @@ func (st *State) PopStack(pos *Position) Move{
 			reduceLimit = 1
 		}
 
-		st.StackReduceFrom = numAll - reduceLimit
+		ss.StackReduceFrom = numAll - reduceLimit
 		
-		st.StackReduceFactor = rF
+		ss.StackReduceFactor = rF
 
-		st.StackPhase = PopAll
+		ss.StackPhase = PopAll
 	}
 
-	if st.StackPhase == PopAll{
-		sbe, ok := st.PopStackBuff()
+	if ss.StackPhase == PopAll{
+		sbe, ok := ss.PopStackBuff()
 		if ok{
-			st.StackReduceDepth = 0
-			if sbe.SubTree > 0 && len(st.StackBuff) <= st.StackReduceFrom{
-				st.StackReduceDepth = pos.PruningReduction
+			ss.StackReduceDepth = 0
+			if sbe.SubTree > 0 && len(ss.StackBuff) <= ss.StackReduceFrom{
+				ss.StackReduceDepth = pos.PruningReduction
 			}
 			return sbe.Move
 		}else{
-			st.StackPhase = GenDone
+			ss.StackPhase = GenDone
 		}
 	}
 
-	if st.StackPhase == GenDone{
+	if ss.StackPhase == GenDone{
 		return Move(0)
 	}
 
@@ -125,16 +134,20 @@ const SQUARE_MASK = (1 << SQUARE_STORAGE_SIZE_IN_BITS) - 1
 const PIECE_STORAGE_SIZE_IN_BITS = 6
 const PIECE_MASK = (1 << PIECE_STORAGE_SIZE_IN_BITS) - 1
 
-const MOVE_TYPE_STORAGE_SIZE_IN_BITS = 8
+const MOVE_TYPE_STORAGE_SIZE_IN_BITS = 4
 const MOVE_TYPE_MASK = (1 << MOVE_TYPE_STORAGE_SIZE_IN_BITS) - 1
 
-// Move : [Move Type 8 bits][Promotion Piece 6 bits][Promotion Square 6 bits][To Square 6 bits][From Square 6 bits]
+// Move : [Move Type 4 bits][Promotion Piece 6 bits][Promotion Square 7 bits][To Square 7 bits][From Square 7 bits]
 
 const FROM_SQUARE_SHIFT = 0                                                        // 0
-const TO_SQUARE_SHIFT = SQUARE_STORAGE_SIZE_IN_BITS                                // 6
-const PROMOTION_SQUARE_SHIFT = TO_SQUARE_SHIFT + SQUARE_STORAGE_SIZE_IN_BITS       // 12
-const PROMOTION_PIECE_SHIFT = PROMOTION_SQUARE_SHIFT + SQUARE_STORAGE_SIZE_IN_BITS // 18
-const MOVE_TYPE_SHIFT = PROMOTION_PIECE_SHIFT + PIECE_STORAGE_SIZE_IN_BITS         // 24
+const TO_SQUARE_SHIFT = SQUARE_STORAGE_SIZE_IN_BITS                                // 7
+const PROMOTION_SQUARE_SHIFT = TO_SQUARE_SHIFT + SQUARE_STORAGE_SIZE_IN_BITS       // 14
+const PROMOTION_PIECE_SHIFT = PROMOTION_SQUARE_SHIFT + SQUARE_STORAGE_SIZE_IN_BITS // 21
+const MOVE_TYPE_SHIFT = PROMOTION_PIECE_SHIFT + PIECE_STORAGE_SIZE_IN_BITS         // 27
+
+// a move has to fit the 32 bits of Move
+const _ = uint32(1<<(MOVE_TYPE_SHIFT+MOVE_TYPE_STORAGE_SIZE_IN_BITS) - 1)
+
 
 func (move Move) FromSq() Square {
 	return Square(move & SQUARE_MASK)
@@ -166,6 +179,10 @@ func (mt MoveType) String() string {
 }
 
 func (move Move) String() string {
+	if move.MoveType() == Drop{
+		return move.UCI()
+	}
+
 	buff := move.FromSq().UCI() + "-" + move.ToSq().UCI()
 
 	if move.MoveType() != Normal {
@@ -176,6 +193,10 @@ func (move Move) String() string {
 }
 
 func (move Move) UCI() string {
+	if move.MoveType() == Drop{
+		return move.PromotionPiece().SanLetter() + "@" + move.ToSq().UCI() + move.DuckSuffix()
+	}
+
 	buff := move.FromSq().UCI() + move.ToSq().UCI()
 
 	if move.MoveType() == Promotion {
@@ -184,7 +205,7 @@ func (move Move) UCI() string {
 		buff += SymbolOf[FigureOf[move.PromotionPiece()]] + "@" + move.PromotionSquare().UCI()
 	}
 
-	return buff
+	return buff + move.DuckSuffix()
 }
 
 func MakeMoveFT(fromSq, toSq Square) Move {
@@ -203,6 +224,10 @@ func MakeMoveFTC(fromSq, toSq Square) Move {
 	return Move(fromSq) + Move(toSq)<<TO_SQUARE_SHIFT + Move(Castling)<<MOVE_TYPE_SHIFT
 }
 
+func MakeMoveDrop(p Piece, toSq Square) Move {
+	return Move(toSq) + Move(toSq)<<TO_SQUARE_SHIFT + Move(p)<<PROMOTION_PIECE_SHIFT + Move(Drop)<<MOVE_TYPE_SHIFT
+}
+
 type MoveKind int
 
 const (
@@ -219,25 +244,22 @@ func (mk MoveKind) IsViolent() bool {
 	return mk&Violent != 0
 }
 
-func (st State) IsSquareJailedForColor(sq Square, color Color) bool{
+func (st *State) IsSquareJailedForColor(sq Square, color Color) bool{
 	ja := JailerAdjacent[sq]
 
-	if (st.ByFigure[Jailer] & st.ByColor[color.Inverse()] & ja) != 0{
+	if st.ByFigure[Jailer].And(st.ByColor[color.Inverse()]).And(ja) != BbEmpty{
 		return true
 	}
 
 	return false
 }
 
-func (st State) PromotionFigures() []Figure{
-	promFigures := []Figure{Queen, Rook, Bishop, Knight}
-	if st.Variant == VariantEightPiece{
-		return append(promFigures, []Figure{LancerN, LancerNE, LancerE, LancerSE, LancerS, LancerSW, LancerW, LancerNW, Sentry, Jailer}...)
-	}
-	return promFigures
+// PromotionFigures returns the figures a pawn can promote to in the variant
+func (st *State) PromotionFigures() []Figure{
+	return st.Rules().PromotionFigures
 }
 
-func (st State) AppendMove(moves *[]Move, move Move, jailColor Color){	
+func (st *State) AppendMove(moves *[]Move, move Move, jailColor Color){	
 	p := st.PieceAtSquare(move.FromSq())
 
 	fromFig := FigureOf[p]
@@ -288,9 +310,12 @@ func (st State) AppendMove(moves *[]Move, move Move, jailColor Color){
 	return
 }
 
-func (st State) GenBitboardMoves(sq Square, mobility Bitboard, jailColor Color) []Move {	
+// GenBitboardMoves generates the moves to the squares of mobility, squares beyond the board of the variant are skipped
+func (st *State) GenBitboardMoves(sq Square, mobility Bitboard, jailColor Color) []Move {	
 	moves := []Move{}
 
+	mobility = mobility.And(st.Rules().Board)
+
 	for _, toSq := range mobility.PopAll() {
 		st.AppendMove(&moves, MakeMoveFT(sq, toSq), jailColor)
 	}
@@ -302,9 +327,11 @@ func MakeLancer(color Color, ld int) Piece {
 	return ColorFigure[color][LancerMinValue+Figure(ld)]
 }
 
-func (st State) GenLancerMoves(color Color, sq Square, mobility Bitboard, keepDir bool, lancerDir int, jailColor Color) []Move {
+func (st *State) GenLancerMoves(color Color, sq Square, mobility Bitboard, keepDir bool, lancerDir int, jailColor Color) []Move {
 	moves := []Move{}
 
+	mobility = mobility.And(st.Rules().Board)
+
 	for _, toSq := range mobility.PopAll() {
 		if keepDir {
 			st.AppendMove(&moves, MakeMoveFTP(sq, toSq, MakeLancer(color, lancerDir)), jailColor)
@@ -318,14 +345,15 @@ func (st State) GenLancerMoves(color Color, sq Square, mobility Bitboard, keepDi
 	return moves
 }
 
-func (st State) GenPawnMoves(kind MoveKind, color Color, sq Square, occupUs, occupThem Bitboard, jailColor Color, disablePushByTwo bool) []Move {
+func (st *State) GenPawnMoves(kind MoveKind, color Color, sq Square, occupUs, occupThem Bitboard, jailColor Color, disablePushByTwo bool) []Move {
 	pi := PawnInfos[sq][color]
 
 	moves := []Move{}
 
 	if kind&Violent != 0 {
 		for _, captInfo := range pi.Captures {			
-			if (captInfo.CheckSq.Bitboard() & occupThem) != 0 || captInfo.CheckSq == st.EpSquare {				
+			// SquareA1 as ep square means no ep square, it is never a valid one
+			if occupThem.Has(captInfo.CheckSq) || ( st.EpSquare != SquareA1 && captInfo.CheckSq == st.EpSquare ) {
 				st.AppendMove(&moves, captInfo.Move, jailColor)
 			}
 		}
@@ -333,7 +361,7 @@ func (st State) GenPawnMoves(kind MoveKind, color Color, sq Square, occupUs, occ
 
 	if kind&Quiet != 0 {
 		for _, pushInfo := range pi.Pushes {
-			if (pushInfo.CheckSq.Bitboard() & (occupUs | occupThem)) == 0 {
+			if !occupUs.Or(occupThem).Has(pushInfo.CheckSq) {
 				st.AppendMove(&moves, pushInfo.Move, jailColor)
 				if disablePushByTwo{
 					break
@@ -342,6 +370,15 @@ func (st State) GenPawnMoves(kind MoveKind, color Color, sq Square, occupUs, occ
 				break
 			}
 		}
+
+		if st.Rules().Win == WinHorde && color == White && RankOf[sq] == 0 && !disablePushByTwo{
+			// horde pawns on the first rank may push by two, without setting an ep square
+			pushTwoSq := RankFile[2][FileOf[sq]]
+
+			if pi.PushOneSq.Bitboard().Or(pushTwoSq.Bitboard()).And(occupUs.Or(occupThem)) == BbEmpty{
+				st.AppendMove(&moves, MakeMoveFT(sq, pushTwoSq), jailColor)
+			}
+		}
 	}
 
 	return moves
@@ -351,7 +388,7 @@ func (l Piece) LancerDirection() int {
 	return int(FigureOf[l]) & LANCER_DIRECTION_MASK
 }
 
-func (st State) GenSentryMoves(kind MoveKind, color Color, sq Square, occupUs, occupThem Bitboard, jailColor Color) []Move{
+func (st *State) GenSentryMoves(kind MoveKind, color Color, sq Square, occupUs, occupThem Bitboard, jailColor Color) []Move{
 	moves := []Move{}
 
 	if jailColor != NoColor{
@@ -370,7 +407,7 @@ func (st State) GenSentryMoves(kind MoveKind, color Color, sq Square, occupUs, o
 		st.Remove(sq)
 
 		// remove sentry from occupation
-		occupUs &^= sq.Bitboard()
+		occupUs = occupUs.AndNot(sq.Bitboard())
 
 		mob := BishopMobility(Violent, sq, occupUs, occupThem)		
 
@@ -419,17 +456,19 @@ func (st State) GenSentryMoves(kind MoveKind, color Color, sq Square, occupUs, o
 	return moves
 }
 
-func (st State) CastlingTargetSquares(color Color, side int) [2]Square{
+func (st *State) CastlingTargetSquares(color Color, side int) [2]Square{
 	cRank := st.CastlingRank(color)
 
+	kingFile := st.Rules().CastlingFiles[side]
+
 	if side == CastlingSideKing{
-		return [2]Square{RankFile[cRank][FileG], RankFile[cRank][FileF]}
+		return [2]Square{RankFile[cRank][kingFile], RankFile[cRank][kingFile - 1]}
 	}else{
-		return [2]Square{RankFile[cRank][FileC], RankFile[cRank][FileD]}	
+		return [2]Square{RankFile[cRank][kingFile], RankFile[cRank][kingFile + 1]}	
 	}
 }
 
-func (st State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs, occupThem Bitboard, jailColor Color) []Move {
+func (st *State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs, occupThem Bitboard, jailColor Color) []Move {
 	switch FigureOf[p] {
 	case Bishop:
 		return st.GenBitboardMoves(sq, BishopMobility(kind, sq, occupUs, occupThem), jailColor)
@@ -439,11 +478,19 @@ func (st State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs
 		return st.GenBitboardMoves(sq, QueenMobility(kind, sq, occupUs, occupThem), jailColor)
 	case Knight:
 		return st.GenBitboardMoves(sq, KnightMobility(kind, sq, occupUs, occupThem), jailColor)
+	case Archbishop:
+		return st.GenBitboardMoves(sq, ArchbishopMobility(kind, sq, occupUs, occupThem), jailColor)
+	case Chancellor:
+		return st.GenBitboardMoves(sq, ChancellorMobility(kind, sq, occupUs, occupThem), jailColor)
 	case King:
 		moves := st.GenBitboardMoves(sq, KingMobility(kind, sq, occupUs, occupThem), jailColor)
 		kCol := ColorOf[p]
 		ccr := st.CastlingRights[kCol]
 		wk := st.KingInfos[kCol].Square
+		if !kind.IsQuiet(){
+			// castling and jailed king pass are quiet moves, generate them only once
+			return moves
+		}
 		for side := CastlingSideKing; side <= CastlingSideQueen; side++{			
 			cr := ccr[side]
 			if cr.CanCastle && !st.IsSquareJailedForColor(cr.RookOrigSq, kCol){
@@ -455,8 +502,9 @@ func (st State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs
 					}
 				}
 				if betweenOrigEmpty{
-					// remove king for tests
+					// remove king and rook for tests, in chess960 the rook may stand on the king's path
 					st.Remove(wk)
+					st.Remove(cr.RookOrigSq)
 
 					checksOk := true					
 
@@ -502,8 +550,9 @@ func (st State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs
 						moves = append(moves, MakeMoveFTC(wk, cr.RookOrigSq))
 					}		
 
-					// put back king
+					// put back king and rook
 					st.Put(p, wk)		
+					st.Put(cr.RookOrigPiece, cr.RookOrigSq)
 				}
 			}			
 		}
@@ -536,7 +585,7 @@ func (st State) PslmsForPieceAtSquare(kind MoveKind, p Piece, sq Square, occupUs
 	return []Move{}
 }
 
-func (st State) PslmsForColor(kind MoveKind, color Color) []Move {
+func (st *State) PslmsForColor(kind MoveKind, color Color) []Move {
 	us := st.ByColor[color]
 	them := st.ByColor[color.Inverse()]
 
@@ -544,31 +593,170 @@ func (st State) PslmsForColor(kind MoveKind, color Color) []Move {
 
 	usbb := us
 
+	// the duck blocks like an own piece, that can not be captured
+	occupUs := us.Or(st.Duck)
+
 	for _, sq := range usbb.PopAll() {
 		p := st.PieceAtSquare(sq)
 
-		moves = append(moves, st.PslmsForPieceAtSquare(kind, p, sq, us, them, color)...)
+		moves = append(moves, st.PslmsForPieceAtSquare(kind, p, sq, occupUs, them, color)...)
+	}
+
+	if st.Rules().Drops && kind.IsQuiet(){
+		moves = append(moves, st.GenDropMoves(color)...)
 	}
 
 	return moves
 }
 
-func (st State) Pslms(kind MoveKind) []Move {
+// GenDropMoves generates the crazyhouse drops of the pieces in the pocket of color
+// pieces can be dropped on any empty square, pawns not on the first and last rank
+func (st *State) GenDropMoves(color Color) []Move{
+	moves := []Move{}
+
+	empty := st.Rules().Board.AndNot(st.ByColor[White].Or(st.ByColor[Black]).Or(st.Duck))
+
+
+	for fig := Pawn; fig <= Queen; fig++{
+		if st.Pockets[color][fig] == 0{
+			continue
+		}
+
+		targets := empty
+
+		if fig == Pawn{
+			targets = targets.AndNot(BbRank1.Or(BbRank8))
+
+		}
+
+		p := ColorFigure[color][fig]
+
+		for _, sq := range targets.PopAll(){
+			moves = append(moves, MakeMoveDrop(p, sq))
+		}
+	}
+
+	return moves
+}
+
+func (st *State) Pslms(kind MoveKind) []Move {
 	return st.PslmsForColor(kind, st.Turn)
 }
 
-func (st State) GenerateMoves() []Move {
+// GenerateMoves returns the pseudo legal moves, in duck chess with every duck placement
+func (st *State) GenerateMoves() []Move {
+	if st.Rules().Duck{
+		return st.AddDuckSquares(st.GenerateBaseMoves())
+	}
+
+	return st.GenerateBaseMoves()
+}
+
+// GenerateBaseMoves returns the pseudo legal moves without duck placements
+func (st *State) GenerateBaseMoves() []Move {
 	//return st.Pslms(Violent | Quiet)
-	return append(st.Pslms(Violent), st.Pslms(Quiet)...)
+	violent := st.Pslms(Violent)
+
+	if st.Rules().Win == WinAntichess && len(violent) > 0{
+		// captures are compulsory in antichess
+		return violent
+	}
+
+	return append(violent, st.Pslms(Quiet)...)
+}
+
+type QuiescenceBuffEntry struct{
+	Move      Move
+	Victim    Score
+	Attacker  Score
+}
+
+type QuiescenceBuff []QuiescenceBuffEntry
+
+func (qb QuiescenceBuff) Len() int{
+	return len(qb)
+}
+
+func (qb QuiescenceBuff) Swap(i, j int){
+	qb[i], qb[j] = qb[j], qb[i]
 }
 
-func (st State) LegalMoves(stopAtFirst bool) []Move {
+// most valuable victim first, least valuable attacker first among equal victims
+func (qb QuiescenceBuff) Less(i, j int) bool{
+	if qb[i].Victim != qb[j].Victim{
+		return qb[i].Victim > qb[j].Victim
+	}
+
+	return qb[i].Attacker < qb[j].Attacker
+}
+
+// QuiescenceMoves returns the pseudo legal violent moves to be searched by quiescence search
+// ordered by MVV-LVA ( https://www.chessprogramming.org/MVV-LVA )
+func (st *State) QuiescenceMoves() []Move {
+	qb := QuiescenceBuff{}
+
+	for _, move := range st.Pslms(Violent){
+		if move.MoveType() == Castling || move.FromSq() == move.ToSq(){
+			// castling and jailed king pass are generated regardless of kind
+			continue
+		}
+
+		victimSq := move.ToSq()
+
+		if move.MoveType() == SentryPush{
+			// sentry push is only violent if the pushed piece captures
+			victimSq = move.PromotionSquare()
+		}
+
+		victim := st.PieceAtSquare(victimSq)
+
+		if victim == NoPiece{
+			if move.MoveType() == SentryPush{
+				continue
+			}
+
+			// en passant
+			victim = ColorFigure[st.Turn.Inverse()][Pawn]
+		}
+
+		attacker := st.PieceAtSquare(move.FromSq())
+
+		qb = append(qb, QuiescenceBuffEntry{
+			Move: move,
+			Victim: PieceMaterialTables[victim][victimSq].M,
+			Attacker: PieceMaterialTables[attacker][move.FromSq()].M,
+		})
+	}
+
+	sort.Sort(qb)
+
+	moves := make([]Move, len(qb))
+
+	for i, qbe := range qb{
+		moves[i] = qbe.Move
+	}
+
+	if st.Rules().Duck{
+		// captures are resolved with the best duck placement only
+		return st.AddBestDuckSquares(moves, 1)
+	}
+
+	return moves
+}
+
+func (st *State) LegalMoves(stopAtFirst bool) []Move {
 	lms := []Move{}
 
+	if end, _, _ := st.VariantEnd(); end{
+		return lms
+	}
+
+	li := st.LegalityInfo()
+
+	undo := Undo{}
+
 	for _, move := range st.GenerateMoves() {
-		newSt := st
-		newSt.MakeMove(move)
-		if !newSt.IsCheckedThem() {
+		if st.IsLegal(&li, move, &undo) {
 			lms = append(lms, move)
 		}
 		if stopAtFirst {
@@ -581,6 +769,15 @@ func (st State) LegalMoves(stopAtFirst bool) []Move {
 	return lms
 }
 
-func (st State) HasLegalMove() bool {
+func (st *State) HasLegalMove() bool {
+	if st.Rules().Duck && st.Rules().Win == WinKingCapture{
+		// every move is legal and leaves empty squares for the duck, no need to expand the duck placements
+		if end, _, _ := st.VariantEnd(); end{
+			return false
+		}
+
+		return len(st.GenerateBaseMoves()) > 0
+	}
+
 	return len(st.LegalMoves(true)) > 0
 }
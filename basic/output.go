@@ -0,0 +1,106 @@
+package basic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Output receives what the engine writes, a line or a block of lines at a time, without the final newline
+// protocol front-ends, tests and the wasm build plug in their own to capture or route the output
+type Output interface{
+	Println(text string)
+}
+
+// WriterOutput writes the output to a writer, one line at a time
+type WriterOutput struct{
+	Writer io.Writer
+}
+
+func (wo WriterOutput) Println(text string){
+	io.WriteString(wo.Writer, text + "\n")
+}
+
+// OutputFunc lets a function receive the output
+type OutputFunc func(text string)
+
+func (of OutputFunc) Println(text string){
+	of(text)
+}
+
+// DiscardOutput drops the output
+var DiscardOutput = OutputFunc(func(text string){})
+
+// DefaultOutput receives the output of positions without an output of their own and of code that has no position at hand
+var DefaultOutput Output = WriterOutput{os.Stdout}
+
+// Output returns where the position writes to
+func (pos *Position) Output() Output{
+	if pos.Out == nil{
+		return DefaultOutput
+	}
+
+	return pos.Out
+}
+
+// Println writes the operands separated by spaces as a line
+func (pos *Position) Println(a ...interface{}){
+	pos.Output().Println(strings.TrimSuffix(fmt.Sprintln(a...), "\n"))
+}
+
+// Printf writes the formatted text, the final newline of the format is implied
+func (pos *Position) Printf(format string, a ...interface{}){
+	pos.Output().Println(strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"))
+}
+
+// LogFile appends lines to a file kept open, the lines are buffered until Flush
+type LogFile struct{
+	Path   string
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// OpenLogFile opens the file for appending, it is created if it does not exist
+func OpenLogFile(path string) (*LogFile, error){
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil{
+		return nil, err
+	}
+
+	return &LogFile{
+		Path: path,
+		file: file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+func (lf *LogFile) Println(text string){
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+
+	lf.writer.WriteString(text + "\n")
+}
+
+// Flush writes the buffered lines to the file
+func (lf *LogFile) Flush() error{
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+
+	return lf.writer.Flush()
+}
+
+// Close flushes and closes the file
+func (lf *LogFile) Close() error{
+	if err := lf.Flush(); err != nil{
+		lf.file.Close()
+
+		return err
+	}
+
+	return lf.file.Close()
+}
@@ -0,0 +1,53 @@
+package basic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobbit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "search.log")
+
+	logFile, err := OpenLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := strings.Builder{}
+
+	pos := Position{}
+	pos.Out = WriterOutput{&out}
+	pos.LogFile = logFile
+
+	pos.Log("info depth 1")
+	pos.Log("info depth 2")
+
+	if out.String() != "info depth 1\ninfo depth 2\n" {
+		t.Errorf("wrong output %q", out.String())
+	}
+
+	if content, _ := ioutil.ReadFile(path); len(content) != 0 {
+		t.Errorf("log should be buffered, file has %q", content)
+	}
+
+	pos.PrintBestMove([]Move{MakeMoveFT(SquareE2, SquareE4)})
+
+	content, _ := ioutil.ReadFile(path)
+
+	if string(content) != "info depth 1\ninfo depth 2\nbestmove e2e4\n" {
+		t.Errorf("log should be written when the search ends, file has %q", content)
+	}
+
+	if err := logFile.Close(); err != nil {
+		t.Errorf("close failed %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package basic
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const PERFT_HASH_KEY_SIZE_IN_BITS = 20
+const PERFT_HASH_SIZE = 1 << PERFT_HASH_KEY_SIZE_IN_BITS
+
+// default depth of the perft suite
+const PERFT_SUITE_MAX_DEPTH = 4
+
+// the perft key has to tell apart the remaining depth, the Zobrist key does not hash it
+var zobristPerftDepth [MAX_STATES]uint64
+
+func init(){
+	r := rand.New(rand.NewSource(8))
+	f := func() uint64 { return uint64(r.Int63())<<32 ^ uint64(r.Int63()) }
+	for i := range zobristPerftDepth{
+		zobristPerftDepth[i] = f()
+	}
+}
+
+type PerftEntry struct{
+	Key   uint64
+	Count int
+}
+
+type PerftHashTable struct{
+	Entries []PerftEntry
+	Mask    uint64
+}
+
+func (ph *PerftHashTable) Alloc(size int){
+	ph.Entries = make([]PerftEntry, size)
+	ph.Mask = uint64(size - 1)
+}
+
+func (ph *PerftHashTable) Get(key uint64) (int, bool){
+	entry := ph.Entries[key&ph.Mask]
+
+	if entry.Key == key{
+		return entry.Count, true
+	}
+
+	return 0, false
+}
+
+func (ph *PerftHashTable) Set(key uint64, count int){
+	ph.Entries[key&ph.Mask] = PerftEntry{
+		Key:   key,
+		Count: count,
+	}
+}
+
+var PerftTable PerftHashTable
+
+// PerftKey returns the perft hash key of the state searched to depth
+func (st *State) PerftKey(depth int) uint64{
+	return st.Zobrist ^ zobristPerftDepth[depth]
+}
+
+// PerftRec counts the leaves of the legal move tree to depth
+func (pos *Position) PerftRec(depth int, hash *PerftHashTable) int{
+	st := pos.Current()
+
+	if depth == 0{
+		return 1
+	}
+
+	moves := st.LegalMoves(false)
+
+	if depth == 1{
+		// bulk counting
+		return len(moves)
+	}
+
+	key := uint64(0)
+
+	if hash != nil{
+		key = st.PerftKey(depth)
+
+		count, ok := hash.Get(key)
+
+		if ok{
+			return count
+		}
+	}
+
+	count := 0
+
+	for _, move := range moves{
+		pos.Push(move)
+		count += pos.PerftRec(depth-1, hash)
+		pos.Pop()
+	}
+
+	if hash != nil{
+		hash.Set(key, count)
+	}
+
+	return count
+}
+
+func (pos *Position) perftHash() *PerftHashTable{
+	if !pos.PerftHash{
+		return nil
+	}
+
+	if len(PerftTable.Entries) == 0{
+		PerftTable.Alloc(PERFT_HASH_SIZE)
+	}
+
+	return &PerftTable
+}
+
+// Perft counts the leaves of the legal move tree of the current position to depth
+func (pos *Position) Perft(depth int) int{
+	return pos.PerftRec(depth, pos.perftHash())
+}
+
+type DivideEntry struct{
+	Move  Move
+	Uci   string
+	Count int
+}
+
+type DivideEntries []DivideEntry
+
+func (de DivideEntries) Len() int{
+	return len(de)
+}
+
+func (de DivideEntries) Swap(i, j int){
+	de[i], de[j] = de[j], de[i]
+}
+
+func (de DivideEntries) Less(i, j int) bool{
+	return de[i].Uci < de[j].Uci
+}
+
+// Divide counts the perft leaves separately for each legal move of the current position
+func (pos *Position) Divide(depth int) DivideEntries{
+	hash := pos.perftHash()
+
+	entries := DivideEntries{}
+
+	if depth < 1{
+		return entries
+	}
+
+	for _, move := range pos.Current().LegalMoves(false){
+		pos.Push(move)
+		entries = append(entries, DivideEntry{
+			Move:  move,
+			Uci:   move.UCI(),
+			Count: pos.PerftRec(depth-1, hash),
+		})
+		pos.Pop()
+	}
+
+	sort.Sort(entries)
+
+	return entries
+}
+
+func perftStats(nodes int, elapsed time.Duration) string{
+	return fmt.Sprintf("nodes %d time %d nps %.0f", nodes, elapsed.Milliseconds(), float64(nodes)/elapsed.Seconds())
+}
+
+// CheckPerftDepth tells whether a move tree of depth fits the states left after the current state
+func (pos *Position) CheckPerftDepth(depth int) error{
+	maxDepth := MAX_STATES - pos.StatePtr - 1
+
+	if depth < 1 || depth > maxDepth{
+		return fmt.Errorf("depth should be 1 - %d", maxDepth)
+	}
+
+	return nil
+}
+
+// ExecPerftCommand prints the perft count of the current position
+func (pos *Position) ExecPerftCommand(depth int){
+	start := time.Now()
+
+	nodes := pos.Perft(depth)
+
+	pos.Printf("perft %d %s\n", depth, perftStats(nodes, time.Since(start)))
+}
+
+// ExecDivideCommand prints the perft counts for each legal move of the current position
+func (pos *Position) ExecDivideCommand(depth int){
+	start := time.Now()
+
+	total := 0
+
+	for _, entry := range pos.Divide(depth){
+		pos.Printf("%s %d\n", entry.Uci, entry.Count)
+		total += entry.Count
+	}
+
+	pos.Printf("divide %d moves %d %s\n", depth, len(pos.Current().LegalMoves(false)), perftStats(total, time.Since(start)))
+}
+
+// ExecVerifyHashCommand runs perft without perft hash, verifying the zobrist key after every move
+func (pos *Position) ExecVerifyHashCommand(depth int){
+	verifyHash, perftHash := VerifyHash, pos.PerftHash
+
+	VerifyHash, pos.PerftHash = true, false
+
+	atomic.StoreInt64(&HashMismatches, 0)
+
+	start := time.Now()
+
+	nodes := pos.Perft(depth)
+
+	pos.Printf("verifyhash %d mismatches %d %s\n", depth, atomic.LoadInt64(&HashMismatches), perftStats(nodes, time.Since(start)))
+
+	VerifyHash, pos.PerftHash = verifyHash, perftHash
+}
+
+type PerftSuitePosition struct{
+	Id      string
+	Variant Variant
+	Fen     string
+	// expected counts by depth, 0 if not given
+	Counts  []int
+}
+
+// ParsePerftSuiteLine parses an epd line like
+// rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 ;variant Standard ;id start ;D1 20 ;D2 400
+func ParsePerftSuiteLine(line string) (PerftSuitePosition, bool){
+	line = strings.TrimSpace(line)
+
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//"){
+		return PerftSuitePosition{}, false
+	}
+
+	fields := strings.Split(line, ";")
+
+	psp := PerftSuitePosition{
+		Variant: VariantStandard,
+		Fen:     strings.TrimSpace(fields[0]),
+	}
+
+	for _, field := range fields[1:]{
+		t := Tokenizer{Content: strings.TrimSpace(field)}
+
+		opcode, _ := t.GetToken()
+
+		operand := strings.TrimSpace(t.Content)
+
+		if opcode == "variant"{
+			psp.Variant = VariantNameToVariant(operand)
+		} else if opcode == "id"{
+			psp.Id = operand
+		} else if len(opcode) > 1 && opcode[0] == 'D'{
+			depthT := Tokenizer{Content: opcode[1:]}
+			depth := depthT.GetInt()
+			countT := Tokenizer{Content: operand}
+			count := countT.GetInt()
+			if depth < 1 || depth >= MAX_STATES{
+				continue
+			}
+			for len(psp.Counts) <= depth{
+				psp.Counts = append(psp.Counts, 0)
+			}
+			psp.Counts[depth] = count
+		}
+	}
+
+	if psp.Id == ""{
+		psp.Id = psp.Fen
+	}
+
+	return psp, true
+}
+
+type PerftSuiteStats struct{
+	Positions  int
+	Tests      int
+	Mismatches int
+}
+
+// RunPerftSuite runs the perft tests of the suite positions up to max depth, returns stats by variant
+func (pos *Position) RunPerftSuite(psps []PerftSuitePosition, maxDepth int) map[Variant]*PerftSuiteStats{
+	stats := map[Variant]*PerftSuiteStats{}
+
+	for _, psp := range psps{
+		if stats[psp.Variant] == nil{
+			stats[psp.Variant] = &PerftSuiteStats{}
+		}
+
+		vstats := stats[psp.Variant]
+
+		vstats.Positions++
+
+		pos.Init(psp.Variant)
+		pos.ParseFen(psp.Fen)
+
+		for depth := 1; depth < len(psp.Counts) && depth <= maxDepth; depth++{
+			expected := psp.Counts[depth]
+
+			if expected == 0{
+				continue
+			}
+
+			vstats.Tests++
+
+			start := time.Now()
+
+			count := pos.Perft(depth)
+
+			status := "ok"
+
+			if count != expected{
+				vstats.Mismatches++
+				status = fmt.Sprintf("MISMATCH expected %d", expected)
+			}
+
+			pos.Printf("%s %s D%d %d %s ( %d ms )\n", VariantInfos[psp.Variant].DisplayName, psp.Id, depth, count, status, time.Since(start).Milliseconds())
+		}
+	}
+
+	return stats
+}
+
+// ExecPerftSuiteCommand runs the perft suite in an epd file and prints a summary by variant
+func (pos *Position) ExecPerftSuiteCommand(path string, maxDepth int){
+	content, err := os.ReadFile(path)
+
+	if err != nil{
+		pos.Println(err)
+		return
+	}
+
+	psps := []PerftSuitePosition{}
+
+	for _, line := range strings.Split(string(content), "\n"){
+		psp, ok := ParsePerftSuiteLine(line)
+		if ok{
+			psps = append(psps, psp)
+		}
+	}
+
+	stats := pos.RunPerftSuite(psps, maxDepth)
+
+	mismatches := 0
+
+	for variant := range VariantInfos{
+		vstats := stats[Variant(variant)]
+		if vstats == nil{
+			continue
+		}
+		pos.Printf("%s positions %d tests %d mismatches %d\n", VariantInfos[variant].DisplayName, vstats.Positions, vstats.Tests, vstats.Mismatches)
+		mismatches += vstats.Mismatches
+	}
+
+	if mismatches == 0{
+		pos.Println("perft suite passed")
+	} else{
+		pos.Printf("perft suite failed with %d mismatches\n", mismatches)
+	}
+}
@@ -0,0 +1,116 @@
+package basic
+
+import (
+	"testing"
+)
+
+func TestPerftHash(t *testing.T) {
+	pos := Position{}
+
+	pos.Init(VariantStandard)
+	pos.ParseFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	pos.PerftHash = false
+	plain := pos.Perft(3)
+
+	pos.PerftHash = true
+	hashed := pos.Perft(3)
+
+	if plain != 97862 || hashed != plain {
+		t.Errorf("expected 97862 leaves with and without hash, got %d and %d", plain, hashed)
+	}
+}
+
+func TestDivide(t *testing.T) {
+	pos := Position{}
+
+	pos.Init(VariantEightPiece)
+
+	total := 0
+
+	for _, entry := range pos.Divide(3) {
+		total += entry.Count
+	}
+
+	if total != pos.Perft(3) {
+		t.Errorf("divide total %d differs from perft %d", total, pos.Perft(3))
+	}
+}
+
+func TestPerftSuite(t *testing.T) {
+	psp, ok := ParsePerftSuiteLine("# comment")
+
+	if ok {
+		t.Errorf("comment line should be skipped")
+	}
+
+	psp, ok = ParsePerftSuiteLine("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 ;variant Atomic ;id start ;D1 20 ;D3 8902")
+
+	if !ok || psp.Variant != VariantAtomic || psp.Id != "start" || len(psp.Counts) != 4 || psp.Counts[3] != 8902 || psp.Counts[2] != 0 {
+		t.Errorf("wrong parsed suite position %+v", psp)
+	}
+
+	psps := []PerftSuitePosition{psp}
+
+	for _, line := range []string{
+		"jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1 - ;variant Eightpiece ;D1 58 ;D2 3322 ;D3 141997",
+		"k7/8/8/7s/8/5N2/8/4K1R1 w - - 0 1 - ;variant Eightpiece ;id sentry check ;D1 11 ;D2 138 ;D3 2502",
+		"k7/8/8/lse7/5B2/2p5/8/4K3 w - - 0 1 - ;variant Eightpiece ;id lancer check ;D1 5 ;D2 129 ;D3 1803",
+		"rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1 ;variant Atomic ;D1 40 ;D2 1238",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1 ;D1 14 ;D2 191 ;D3 2812",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1 ;D1 15",
+	}{
+		psp, _ := ParsePerftSuiteLine(line)
+		psps = append(psps, psp)
+	}
+
+	pos := Position{}
+
+	stats := pos.RunPerftSuite(psps, 3)
+
+	if stats[VariantAtomic].Positions != 2 || stats[VariantAtomic].Tests != 4 || stats[VariantAtomic].Mismatches != 0 {
+		t.Errorf("wrong atomic stats %+v", stats[VariantAtomic])
+	}
+
+	if stats[VariantEightPiece].Tests != 9 || stats[VariantEightPiece].Mismatches != 0 {
+		t.Errorf("wrong eightpiece stats %+v", stats[VariantEightPiece])
+	}
+
+	if stats[VariantStandard].Tests != 4 || stats[VariantStandard].Mismatches != 1 {
+		t.Errorf("expected one standard mismatch, got %+v", stats[VariantStandard])
+	}
+}
+
+func BenchmarkPerf(b *testing.B) {
+	pos := Position{}
+
+	for i := 0; i < b.N; i++ {
+		pos.Init(VariantStandard)
+		pos.ParseFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		pos.Nodes = 0
+		pos.PerfRec(3)
+	}
+
+	b.ReportMetric(float64(pos.Nodes) * float64(b.N) / b.Elapsed().Seconds(), "nodes/s")
+}
+
+func TestCheckPerftDepth(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	for _, depth := range []int{0, -1, MAX_STATES, 120} {
+		if pos.CheckPerftDepth(depth) == nil {
+			t.Errorf("depth %d should be rejected", depth)
+		}
+	}
+
+	pos.PushUci("e2e4")
+
+	if pos.CheckPerftDepth(MAX_STATES-1) == nil || pos.CheckPerftDepth(MAX_STATES-2) != nil {
+		t.Errorf("the depth should be limited by the states left")
+	}
+
+	if psp, _ := ParsePerftSuiteLine("8/8/8/8/8/8/8/K6k w - - 0 1 ;D-1 5 ;D1000 3 ;D1 3"); len(psp.Counts) != 2 {
+		t.Errorf("out of range depths of the suite should be ignored, got %v", psp.Counts)
+	}
+}
@@ -0,0 +1,781 @@
+package basic
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// line length of exported movetext
+const PGN_LINE_LENGTH = 80
+
+// suffix annotations and their NAG equivalents
+var PGN_SUFFIX_NAGS = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+var PGN_RESULTS = []string{"1-0", "0-1", "1/2-1/2", "*"}
+
+// variant tag values other than display names
+var PGN_VARIANT_ALIASES = map[string]Variant{
+	"standard":         VariantStandard,
+	"from position":    VariantStandard,
+	"normal":           VariantStandard,
+	"8-piece":          VariantEightPiece,
+	"eightpiece":       VariantEightPiece,
+	"atomic":           VariantAtomic,
+	"chess960":         VariantChess960,
+	"chess 960":        VariantChess960,
+	"fischerandom":     VariantChess960,
+	"fischer random":   VariantChess960,
+	"crazyhouse":       VariantCrazyhouse,
+	"three-check":      VariantThreeCheck,
+	"three check":      VariantThreeCheck,
+	"threecheck":       VariantThreeCheck,
+	"3check":           VariantThreeCheck,
+	"king of the hill": VariantKingOfTheHill,
+	"kingofthehill":    VariantKingOfTheHill,
+	"koth":             VariantKingOfTheHill,
+	"antichess":        VariantAntichess,
+	"losing chess":     VariantAntichess,
+	"giveaway":         VariantAntichess,
+	"horde":            VariantHorde,
+	"racing kings":     VariantRacingKings,
+	"racingkings":      VariantRacingKings,
+	"capablanca chess": VariantCapablanca,
+	"gothic chess":     VariantGothic,
+	"embassy chess":    VariantEmbassy,
+	"duck chess":       VariantDuck,
+}
+
+type PgnTag struct{
+	Name  string
+	Value string
+}
+
+type PgnMove struct{
+	San         string
+	Move        Move
+	Nags        []int
+	PreComment  string
+	Comment     string
+	// alternatives to this move
+	Variations  []PgnLine
+}
+
+type PgnLine []PgnMove
+
+type PgnGame struct{
+	Tags    []PgnTag
+	Moves   PgnLine
+	Result  string
+}
+
+// Tag returns the value of a tag
+func (g PgnGame) Tag(name string) (string, bool){
+	for _, tag := range g.Tags{
+		if tag.Name == name{
+			return tag.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// SetTag sets the value of a tag, adding it if needed
+func (g *PgnGame) SetTag(name, value string){
+	for i, tag := range g.Tags{
+		if tag.Name == name{
+			g.Tags[i].Value = value
+			return
+		}
+	}
+
+	g.Tags = append(g.Tags, PgnTag{Name: name, Value: value})
+}
+
+// Variant determines the variant of the game from the Variant tag, defaults to standard
+func (g PgnGame) Variant() Variant{
+	name, ok := g.Tag("Variant")
+
+	if !ok{
+		return VariantStandard
+	}
+
+	if variant, ok := LookupVariant(name); ok{
+		return variant
+	}
+
+	return VariantStandard
+}
+
+// StartState sets up the start state of the game from the Variant and FEN tags
+func (g PgnGame) StartState() State{
+	st := State{}
+
+	st.Init(g.Variant())
+
+	fen, ok := g.Tag("FEN")
+
+	if ok{
+		st.ParseFen(fen)
+	}
+
+	return st
+}
+
+// Resolve finds the moves of the SANs in the mainline and in all variations
+func (g *PgnGame) Resolve() error{
+	return g.Moves.Resolve(g.StartState())
+}
+
+// Resolve finds the moves of the SANs in the line starting from state st
+func (line PgnLine) Resolve(st State) error{
+	for i, pm := range line{
+		for _, variation := range pm.Variations{
+			err := variation.Resolve(st)
+			if err != nil{
+				return err
+			}
+		}
+
+		move, ok := st.SanToMove(pm.San)
+
+		if !ok{
+			return fmt.Errorf("illegal move %s in %s", pm.San, st.ReportFen())
+		}
+
+		line[i].Move = move
+
+		st.MakeMove(move, &Undo{})
+	}
+
+	return nil
+}
+
+// NormalizeSan strips check, mate and annotation suffixes and converts zero castling to letter O
+func NormalizeSan(san string) string{
+	san = strings.TrimRight(san, "+#!?")
+
+	san = strings.TrimSuffix(san, "e.p.")
+
+	if san == "0-0" || san == "0-0-0"{
+		san = strings.ReplaceAll(san, "0", "O")
+	}
+
+	if strings.HasPrefix(san, "@"){
+		// pawn drop without letter
+		san = "P" + san
+	}
+
+	return san
+}
+
+// normalizeGeneratedSan strips the check, mate and stalemate suffixes of a SAN generated by MoveToSanBatch
+func normalizeGeneratedSan(san string) string{
+	return strings.TrimRight(san, "+#=")
+}
+
+// SanToMove converts a SAN to a legal move
+// over specified, under specified captures and promotions without = are also accepted
+func (st *State) SanToMove(san string) (Move, bool){
+	st.GenMoveBuff()
+
+	san = NormalizeSan(san)
+
+	for _, mbi := range st.MoveBuff{
+		if normalizeGeneratedSan(mbi.San) == san{
+			return mbi.Move, true
+		}
+	}
+
+	loose := func(s string) string{
+		return strings.NewReplacer("x", "", "=", "", "-", "").Replace(s)
+	}
+
+	looseSan := loose(san)
+
+	found := []Move{}
+
+	for _, mbi := range st.MoveBuff{
+		if mbi.Move.MoveType() == Castling || mbi.Move.MoveType() == Drop{
+			continue
+		}
+
+		p := st.PieceAtSquare(mbi.Move.FromSq())
+
+		letter := ""
+
+		if FigureOf[p] != Pawn{
+			letter = p.SanLetter()
+		}
+
+		if !strings.HasPrefix(looseSan, letter){
+			continue
+		}
+
+		target := mbi.Move.ToSq().UCI()
+
+		if mbi.Move.MoveType() == Promotion{
+			target += mbi.Move.PromotionPiece().SanSymbol()
+		}
+
+		rest := looseSan[len(letter):]
+
+		if !strings.HasSuffix(rest, target){
+			continue
+		}
+
+		disamb := rest[:len(rest) - len(target)]
+
+		orig := mbi.Move.FromSq().UCI()
+
+		if disamb == "" || disamb == orig || disamb == orig[0:1] || disamb == orig[1:2]{
+			found = append(found, mbi.Move)
+		}
+	}
+
+	if len(found) == 1{
+		return found[0], true
+	}
+
+	return Move(0), false
+}
+
+type pgnScanner struct{
+	content string
+	ptr     int
+}
+
+func (ps *pgnScanner) eof() bool{
+	return ps.ptr >= len(ps.content)
+}
+
+func (ps *pgnScanner) peek() byte{
+	return ps.content[ps.ptr]
+}
+
+func (ps *pgnScanner) skipSpace(){
+	for !ps.eof() && strings.IndexByte(" \t\r\n", ps.peek()) >= 0{
+		// escape line
+		if ps.peek() == '\n' && ps.ptr + 1 < len(ps.content) && ps.content[ps.ptr + 1] == '%'{
+			// skip up to the newline ending the escaped line, it is checked again for a following escape
+			ps.ptr++
+			for !ps.eof() && ps.peek() != '\n'{
+				ps.ptr++
+			}
+			continue
+		}
+		ps.ptr++
+	}
+}
+
+// readUntil reads up to the end byte, consumes the end byte but does not return it
+func (ps *pgnScanner) readUntil(end byte) string{
+	start := ps.ptr
+
+	for !ps.eof() && ps.peek() != end{
+		ps.ptr++
+	}
+
+	buff := ps.content[start:ps.ptr]
+
+	if !ps.eof(){
+		ps.ptr++
+	}
+
+	return buff
+}
+
+// readSymbol reads a movetext symbol up to white space or a special character
+func (ps *pgnScanner) readSymbol() string{
+	start := ps.ptr
+
+	for !ps.eof() && strings.IndexByte(" \t\r\n{}();[]$", ps.peek()) < 0{
+		ps.ptr++
+	}
+
+	return ps.content[start:ps.ptr]
+}
+
+func (ps *pgnScanner) readTag() (PgnTag, error){
+	// skip [
+	ps.ptr++
+
+	ps.skipSpace()
+
+	name := ps.readSymbol()
+
+	ps.skipSpace()
+
+	if ps.eof() || ps.peek() != '"'{
+		return PgnTag{}, fmt.Errorf("tag %s has no value", name)
+	}
+
+	ps.ptr++
+
+	value := ""
+
+	for !ps.eof() && ps.peek() != '"'{
+		if ps.peek() == '\\' && ps.ptr + 1 < len(ps.content){
+			ps.ptr++
+		}
+		value += ps.content[ps.ptr:ps.ptr + 1]
+		ps.ptr++
+	}
+
+	ps.readUntil(']')
+
+	return PgnTag{Name: name, Value: value}, nil
+}
+
+func isPgnResult(symbol string) bool{
+	for _, result := range PGN_RESULTS{
+		if symbol == result{
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendComment(buff, comment string) string{
+	comment = strings.TrimSpace(comment)
+
+	if buff == ""{
+		return comment
+	}
+
+	return buff + " " + comment
+}
+
+// readLine reads movetext up to the end of a variation, a result or the next game
+func (ps *pgnScanner) readLine(g *PgnGame, depth int) (PgnLine, error){
+	line := PgnLine{}
+
+	preComment := ""
+
+	for {
+		ps.skipSpace()
+
+		if ps.eof(){
+			if depth > 0{
+				return line, fmt.Errorf("unterminated variation")
+			}
+			return line, nil
+		}
+
+		c := ps.peek()
+
+		switch{
+		case c == '{' || c == ';':
+			ps.ptr++
+			end := byte('}')
+			if c == ';'{
+				end = '\n'
+			}
+			comment := ps.readUntil(end)
+			if len(line) == 0{
+				preComment = appendComment(preComment, comment)
+			}else{
+				line[len(line) - 1].Comment = appendComment(line[len(line) - 1].Comment, comment)
+			}
+		case c == '(':
+			ps.ptr++
+			if len(line) == 0{
+				return line, fmt.Errorf("variation without move")
+			}
+			variation, err := ps.readLine(g, depth + 1)
+			if err != nil{
+				return line, err
+			}
+			line[len(line) - 1].Variations = append(line[len(line) - 1].Variations, variation)
+		case c == ')':
+			ps.ptr++
+			if depth == 0{
+				return line, fmt.Errorf("unexpected )")
+			}
+			return line, nil
+		case c == '[':
+			if depth > 0{
+				return line, fmt.Errorf("unterminated variation")
+			}
+			// next game
+			return line, nil
+		case c == '$':
+			ps.ptr++
+			t := Tokenizer{Content: ps.readSymbol()}
+			if len(line) > 0{
+				line[len(line) - 1].Nags = append(line[len(line) - 1].Nags, t.GetInt())
+			}
+		default:
+			symbol := ps.readSymbol()
+
+			if symbol == ""{
+				return line, fmt.Errorf("unexpected character %c", c)
+			}
+
+			if isPgnResult(symbol){
+				if depth == 0{
+					g.Result = symbol
+					return line, nil
+				}
+				continue
+			}
+
+			// strip move number, but keep zero castling
+			number := strings.TrimLeft(symbol, "0123456789")
+			if strings.HasPrefix(number, "."){
+				symbol = strings.TrimLeft(number, ".")
+			}
+
+			if symbol == ""{
+				continue
+			}
+
+			if nag, ok := PGN_SUFFIX_NAGS[symbol]; ok{
+				// annotation separated from the move
+				if len(line) > 0{
+					line[len(line) - 1].Nags = append(line[len(line) - 1].Nags, nag)
+				}
+				continue
+			}
+
+			pm := PgnMove{
+				San: strings.TrimRight(symbol, "!?"),
+				PreComment: preComment,
+			}
+
+			preComment = ""
+
+			if nag, ok := PGN_SUFFIX_NAGS[symbol[len(pm.San):]]; ok{
+				pm.Nags = append(pm.Nags, nag)
+			}
+
+			line = append(line, pm)
+		}
+	}
+}
+
+// ParsePgn parses all games of a pgn, moves are not resolved
+func ParsePgn(content string) ([]PgnGame, error){
+	ps := pgnScanner{content: "\n" + content}
+
+	games := []PgnGame{}
+
+	for {
+		ps.skipSpace()
+
+		if ps.eof(){
+			return games, nil
+		}
+
+		g := PgnGame{}
+
+		for {
+			ps.skipSpace()
+
+			if ps.eof() || ps.peek() != '['{
+				break
+			}
+
+			tag, err := ps.readTag()
+
+			if err != nil{
+				return games, err
+			}
+
+			g.Tags = append(g.Tags, tag)
+		}
+
+		moves, err := ps.readLine(&g, 0)
+
+		if err != nil{
+			return games, fmt.Errorf("game %d : %v", len(games) + 1, err)
+		}
+
+		g.Moves = moves
+
+		if g.Result == ""{
+			g.Result, _ = g.Tag("Result")
+		}
+
+		games = append(games, g)
+	}
+}
+
+// LoadPgnFile loads a game from a pgn file, index is 1 based
+func LoadPgnFile(path string, index int) (PgnGame, error){
+	content, err := os.ReadFile(path)
+
+	if err != nil{
+		return PgnGame{}, err
+	}
+
+	games, err := ParsePgn(string(content))
+
+	if err != nil{
+		return PgnGame{}, err
+	}
+
+	if index < 1 || index > len(games){
+		return PgnGame{}, fmt.Errorf("game %d not found, file has %d game(s)", index, len(games))
+	}
+
+	g := games[index - 1]
+
+	err = g.Resolve()
+
+	return g, err
+}
+
+// LoadPgnGame sets up the position from the mainline of a resolved game
+// if the game is too long, the oldest states are dropped to the history, the whole game is kept for the export with its tags
+func (pos *Position) LoadPgnGame(g PgnGame){
+	pos.Init(g.Variant())
+	pos.Board = g.StartState()
+	pos.Board.Ply = 0
+	pos.Evals = nil
+
+	loaded := PgnGame{Tags: append([]PgnTag{}, g.Tags...)}
+
+	if g.Result != ""{
+		// the result of the movetext wins over the tag
+		loaded.SetTag("Result", g.Result)
+	}
+
+	pos.PgnTags = loaded.Tags
+
+	for _, pm := range g.Moves{
+		pos.Push(pm.Move)
+		pos.Rebase(MAX_GAME_PLIES)
+	}
+}
+
+type PgnEval struct{
+	Score Score
+	Depth int
+}
+
+// String reports the eval in cutechess format, for example +0.35/12 or -M3/20
+func (pe PgnEval) String() string{
+	if pe.Score.IsMateInN(){
+		if pe.Score > 0{
+			return fmt.Sprintf("+M%d/%d", (MATE_SCORE - pe.Score + 1) / 2, pe.Depth)
+		}
+		return fmt.Sprintf("-M%d/%d", (MATE_SCORE + pe.Score + 1) / 2, pe.Depth)
+	}
+
+	return fmt.Sprintf("%+.2f/%d", float32(pe.Score) / 100, pe.Depth)
+}
+
+// EvalTable stores engine evaluations of positions by Zobrist key, score is from the point of view of side to move
+type EvalTable struct{
+	mu      sync.Mutex
+	Entries map[uint64]PgnEval
+}
+
+func (et *EvalTable) Set(zobrist uint64, pe PgnEval){
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	if et.Entries == nil{
+		et.Entries = make(map[uint64]PgnEval)
+	}
+
+	et.Entries[zobrist] = pe
+}
+
+func (et *EvalTable) Get(zobrist uint64) (PgnEval, bool){
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	pe, ok := et.Entries[zobrist]
+
+	return pe, ok
+}
+
+// RecordEval stores the evaluation of the last completed iteration for the current position in the evaluations of the game
+func (pos *Position) RecordEval(){
+	if pos.HelperIndex > 0 || pos.OldMultiPvInfos[0].Depth < 1{
+		return
+	}
+
+	if pos.Evals == nil{
+		pos.Evals = &EvalTable{}
+	}
+
+	pos.Evals.Set(pos.Current().Zobrist, PgnEval{
+		Score: pos.OldMultiPvInfos[0].Score,
+		Depth: pos.OldMultiPvInfos[0].Depth,
+	})
+}
+
+// ResultString determines the result of the game in the current position
+func (pos *Position) ResultString() string{
+	result, _ := pos.GameResult()
+
+	return result
+}
+
+// PgnGame returns the game of the position up to the current state, with the tags of the loaded game if any
+// evaluations, if any, are added as comments after the moves, from the point of view of the side that moved
+func (pos *Position) PgnGame() PgnGame{
+	g := PgnGame{
+		Tags: []PgnTag{
+			{"Event", "?"},
+			{"Site", "?"},
+			{"Date", "????.??.??"},
+			{"Round", "?"},
+			{"White", "?"},
+			{"Black", "?"},
+		},
+		Result: pos.ResultString(),
+	}
+
+	for _, tag := range pos.PgnTags{
+		g.SetTag(tag.Name, tag.Value)
+	}
+
+	if g.Result == "*"{
+		// a loaded game may have ended by resignation, time or agreement, its result is kept unless the position decides
+		if result, ok := g.Tag("Result"); ok && isPgnResult(result){
+			g.Result = result
+		}
+	}
+
+	g.SetTag("Result", g.Result)
+
+	startSt, moves := pos.GameLine()
+
+	if startSt.Variant != VariantStandard{
+		g.SetTag("Variant", VariantInfos[startSt.Variant].DisplayName)
+	}
+
+	initSt := State{}
+	initSt.Init(startSt.Variant)
+
+	if startSt.ReportFen() != initSt.ReportFen(){
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", startSt.ReportFen())
+	}
+
+	st := startSt
+
+	undo := Undo{}
+
+	for _, move := range moves{
+		pm := PgnMove{
+			San: st.MoveToSan(move),
+			Move: move,
+		}
+
+		st.MakeMove(move, &undo)
+
+		if pos.Evals != nil{
+			if pe, ok := pos.Evals.Get(st.Zobrist); ok{
+				pe.Score = -pe.Score
+				pm.Comment = pe.String()
+			}
+		}
+
+		g.Moves = append(g.Moves, pm)
+	}
+
+	return g
+}
+
+func (line PgnLine) appendMovetext(tokens []string, fullmoveNumber int, turn Color) []string{
+	forceNumber := true
+
+	for _, pm := range line{
+		if pm.PreComment != ""{
+			tokens = append(tokens, "{" + pm.PreComment + "}")
+			forceNumber = true
+		}
+
+		if turn == White{
+			tokens = append(tokens, fmt.Sprintf("%d.", fullmoveNumber))
+		}else if forceNumber{
+			tokens = append(tokens, fmt.Sprintf("%d...", fullmoveNumber))
+		}
+
+		forceNumber = false
+
+		tokens = append(tokens, pm.San)
+
+		for _, nag := range pm.Nags{
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+
+		if pm.Comment != ""{
+			tokens = append(tokens, "{" + pm.Comment + "}")
+			forceNumber = true
+		}
+
+		for _, variation := range pm.Variations{
+			tokens = append(tokens, "(")
+			tokens = variation.appendMovetext(tokens, fullmoveNumber, turn)
+			tokens = append(tokens, ")")
+			forceNumber = true
+		}
+
+		if turn == Black{
+			fullmoveNumber++
+		}
+
+		turn = turn.Inverse()
+	}
+
+	return tokens
+}
+
+// String exports the game in pgn format
+func (g PgnGame) String() string{
+	buff := ""
+
+	for _, tag := range g.Tags{
+		value := strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(tag.Value)
+		buff += fmt.Sprintf("[%s \"%s\"]\n", tag.Name, value)
+	}
+
+	buff += "\n"
+
+	startSt := g.StartState()
+
+	result := g.Result
+
+	if result == ""{
+		result = "*"
+	}
+
+	tokens := append(g.Moves.appendMovetext([]string{}, startSt.FullmoveNumber, startSt.Turn), result)
+
+	line := ""
+
+	for _, token := range tokens{
+		if line != "" && len(line) + 1 + len(token) > PGN_LINE_LENGTH{
+			buff += line + "\n"
+			line = ""
+		}
+
+		if line != "" && token != ")" && !strings.HasSuffix(line, "("){
+			line += " "
+		}
+
+		line += token
+	}
+
+	return buff + line + "\n"
+}
+
+// SavePgnFile saves the line of the position as a game to a pgn file
+func (pos *Position) SavePgnFile(path string) error{
+	return os.WriteFile(path, []byte(pos.PgnGame().String() + "\n"), 0644)
+}
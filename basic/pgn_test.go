@@ -0,0 +1,211 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testPgn = `[Event "Test \"quoted\""]
+[Result "1-0"]
+
+% escaped line
+{Opening comment} 1. e4 e5 $1 2. Nf3!? Nc6 {main} (2... d6 3. d4 (3. Bc4) exd4) 3. Bb5 a6 4. Ba4 Nf6 5. 0-0 1-0
+
+[Variant "Atomic"]
+[FEN "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"]
+
+1. Nf3 f6 2. e3 e5 3. Nxe5 *
+`
+
+func TestParsePgn(t *testing.T) {
+	games, err := ParsePgn(testPgn)
+
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games, got %d", len(games))
+	}
+
+	g := games[0]
+
+	if event, _ := g.Tag("Event"); event != `Test "quoted"` {
+		t.Errorf("wrong event tag %s", event)
+	}
+
+	if g.Result != "1-0" || len(g.Moves) != 9 {
+		t.Errorf("expected 9 plies and result 1-0, got %d plies and result %s", len(g.Moves), g.Result)
+	}
+
+	if g.Moves[0].PreComment != "Opening comment" || g.Moves[3].Comment != "main" {
+		t.Errorf("comments not parsed")
+	}
+
+	if len(g.Moves[1].Nags) != 1 || g.Moves[1].Nags[0] != 1 || g.Moves[2].Nags[0] != 5 {
+		t.Errorf("nags not parsed")
+	}
+
+	if len(g.Moves[3].Variations) != 1 || len(g.Moves[3].Variations[0]) != 3 || len(g.Moves[3].Variations[0][1].Variations) != 1 {
+		t.Errorf("variations not parsed")
+	}
+
+	if err := g.Resolve(); err != nil {
+		t.Errorf("unexpected resolve error %v", err)
+	}
+
+	if g.Moves[8].Move.MoveType() != Castling {
+		t.Errorf("expected castling, got %s", g.Moves[8].Move.UCI())
+	}
+
+	if games[1].Variant() != VariantAtomic {
+		t.Errorf("expected atomic, got %s", games[1].Variant())
+	}
+}
+
+func TestSanToMove(t *testing.T) {
+	st := State{}
+	st.Init(VariantStandard)
+	st.ParseFen("r3k3/1P6/8/8/8/2N3N1/8/4K3 w q - 0 1")
+
+	for san, uci := range map[string]string{
+		"b8=Q":   "b7b8q",
+		"b8Q":    "b7b8q",
+		"bxa8=N": "b7a8n",
+		"Nge4":   "g3e4",
+		"Ng3e4":  "g3e4",
+		"Nc3-e4": "c3e4",
+	} {
+		move, ok := st.SanToMove(san)
+		if !ok || move.UCI() != uci {
+			t.Errorf("%s expected %s, got %s %v", san, uci, move.UCI(), ok)
+		}
+	}
+
+	if _, ok := st.SanToMove("Ne4"); ok {
+		t.Errorf("ambiguous Ne4 should not be resolved")
+	}
+}
+
+func TestPgnExport(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		pos.PushUci(uci)
+	}
+
+	pgn := pos.PgnGame().String()
+
+	if !strings.Contains(pgn, "\n1. e4 e5 2. Nf3 *\n") {
+		t.Errorf("wrong movetext in\n%s", pgn)
+	}
+
+	games, err := ParsePgn(pgn)
+
+	if err != nil || len(games) != 1 || len(games[0].Moves) != 3 {
+		t.Errorf("exported pgn does not parse back %v", err)
+	}
+}
+
+func TestPgnExportLongGame(t *testing.T) {
+	movetext := ""
+
+	for i := 0; i < 40; i++ {
+		movetext += fmt.Sprintf("%d. Nf3 Nf6 %d. Ng1 Ng8 ", 2*i+1, 2*i+2)
+	}
+
+	games, err := ParsePgn("[Event \"t\"]\n[Annotator \"a\"]\n\n" + movetext + "*\n")
+
+	if err != nil || len(games) != 1 {
+		t.Fatalf("parse failed %v", err)
+	}
+
+	games[0].Resolve()
+
+	pos := Position{}
+	pos.LoadPgnGame(games[0])
+
+	if pos.StatePtr >= 160 {
+		t.Fatalf("the game should be longer than the rebase window")
+	}
+
+	g := pos.PgnGame()
+
+	if len(g.Moves) != 160 || g.Moves[0].San != "Nf3" {
+		t.Errorf("expected the whole game of 160 plies from Nf3, got %d plies", len(g.Moves))
+	}
+
+	if _, ok := g.Tag("FEN"); ok {
+		t.Errorf("the game starts from the start position")
+	}
+
+	if event, _ := g.Tag("Event"); event != "t" {
+		t.Errorf("expected the loaded Event tag, got %s", event)
+	}
+
+	if annotator, _ := g.Tag("Annotator"); annotator != "a" {
+		t.Errorf("expected the loaded Annotator tag, got %s", annotator)
+	}
+}
+
+func TestPgnResultRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		pgn    string
+		result string
+	}{
+		// black resigned, the position does not decide
+		{"[Event \"resigned\"]\n[Result \"1-0\"]\n\n1. e4 e5 2. Qh5 Nc6 1-0\n", "1-0"},
+		{"[Result \"1/2-1/2\"]\n\n1. d4 d5 1/2-1/2\n", "1/2-1/2"},
+		// the result of the movetext without a tag
+		{"1. e4 e5 0-1\n", "0-1"},
+		// the mate decides an unfinished game
+		{"[Result \"*\"]\n\n1. f3 e5 2. g4 Qh4 *\n", "0-1"},
+		{"1. e4 *\n", "*"},
+	} {
+		games, err := ParsePgn(test.pgn)
+
+		if err != nil || len(games) != 1 {
+			t.Fatalf("parse failed %v", err)
+		}
+
+		games[0].Resolve()
+
+		pos := Position{}
+		pos.LoadPgnGame(games[0])
+
+		saved := pos.PgnGame().String()
+
+		games, err = ParsePgn(saved)
+
+		if err != nil || len(games) != 1 {
+			t.Fatalf("saved game does not parse back %v\n%s", err, saved)
+		}
+
+		if result, _ := games[0].Tag("Result"); result != test.result || games[0].Result != test.result {
+			t.Errorf("expected result %s got tag %s movetext %s in\n%s", test.result, result, games[0].Result, saved)
+		}
+	}
+}
+
+func TestPgnEvalsPerGame(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+	pos.PushUci("e2e4")
+
+	pos.Evals = &EvalTable{}
+	pos.Evals.Set(pos.Current().Zobrist, PgnEval{Score: -30, Depth: 8})
+
+	if comment := pos.PgnGame().Moves[0].Comment; comment != "+0.30/8" {
+		t.Errorf("expected the evaluation as comment, got %s", comment)
+	}
+
+	other := Position{}
+	other.Init(VariantStandard)
+	other.PushUci("e2e4")
+
+	if comment := other.PgnGame().Moves[0].Comment; comment != "" {
+		t.Errorf("evaluations of another game should not be exported, got %s", comment)
+	}
+}
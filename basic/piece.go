@@ -23,11 +23,13 @@ const (
    LancerNW         = Figure(15)
    Sentry           = Figure(16)
    Jailer           = Figure(17)
+   Archbishop       = Figure(18)
+   Chancellor       = Figure(19)
    FigureArraySize  = int(iota)
 )
 
 const FigureMinValue = Pawn
-const FigureMaxValue = Jailer
+const FigureMaxValue = Chancellor
 
 const LancerMinValue = LancerN
 const LancerMaxValue = LancerNW
@@ -35,7 +37,7 @@ const LancerMaxValue = LancerNW
 const LANCER_DIRECTION_MASK = 0b111
 
 // SymbolOf tells the symbol of a Figure
-var SymbolOf = [18]string{
+var SymbolOf = [20]string{
    "."    , // 0   NoFigure
    "p"    , // 1   Pawn
    "n"    , // 2   Knight
@@ -54,6 +56,8 @@ var SymbolOf = [18]string{
    "lnw"  , // 15  LancerNW
    "s"    , // 16  Sentry
    "j"    , // 17  Jailer
+   "a"    , // 18  Archbishop
+   "c"    , // 19  Chancellor
 }
 
 type Piece int
@@ -95,13 +99,17 @@ const (
    WhiteSentry           = Piece(33)
    BlackJailer           = Piece(34)
    WhiteJailer           = Piece(35)
+   BlackArchbishop       = Piece(36)
+   WhiteArchbishop       = Piece(37)
+   BlackChancellor       = Piece(38)
+   WhiteChancellor       = Piece(39)
 )
 
 const PieceMinValue = BlackPawn
-const PieceMaxValue = WhiteJailer
+const PieceMaxValue = WhiteChancellor
 const PieceArraySize = PieceMaxValue - PieceMinValue + 1
 
-var FigureOf [36]Figure
+var FigureOf [40]Figure
 
 type Color int
 
@@ -130,10 +138,10 @@ func (color Color) Inverse() Color{
 }
 
 // ColorOf tells the color of a Piece
-var ColorOf [36]Color
+var ColorOf [40]Color
 
 // ColorFigure constructs a Piece from Color and Figure
-var ColorFigure[2][18]Piece
+var ColorFigure[2][20]Piece
 
 // SymbolToPiece tells Piece for a FEN symbol
 var SymbolToPiece = map[string]Piece{
@@ -171,5 +179,9 @@ var SymbolToPiece = map[string]Piece{
    "S"    : WhiteSentry,      // 33
    "j"    : BlackJailer,      // 34
    "J"    : WhiteJailer,      // 35
+   "a"    : BlackArchbishop,  // 36
+   "A"    : WhiteArchbishop,  // 37
+   "c"    : BlackChancellor,  // 38
+   "C"    : WhiteChancellor,  // 39
 }
 
@@ -51,6 +51,9 @@ func (p Piece) SanLetter() string {
 
 // FenSymbol tells the FEN symbol of a Piece
 func (p Piece) FenSymbol() string {
+	if p == DummyPiece {
+		return DUCK_SYMBOL
+	}
 	if ColorOf[p] == White {
 		return p.SanSymbol()
 	}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 	"time"
-	"os"
 )
 
 const SEARCH_MAX_DEPTH = 100
@@ -20,6 +19,7 @@ type MultiPvInfo struct{
 	Depth                    int
 	Time                     int
 	Nodes                    int	
+	QNodes                   int
 	Nps                      float32
 	Score                    Score
 	Pv                       []Move
@@ -27,25 +27,36 @@ type MultiPvInfo struct{
 }
 
 func (mpi MultiPvInfo) String() string{
-	return fmt.Sprintf("info multipv %d depth %d time %d nodes %d nps %.0f score cp %d pv %v", mpi.Index, mpi.Depth, mpi.Time, mpi.Nodes, mpi.Nps, mpi.Score, mpi.PvUCI)
+	return fmt.Sprintf("info multipv %d depth %d time %d nodes %d qnodes %d nps %.0f score cp %d pv %v", mpi.Index, mpi.Depth, mpi.Time, mpi.Nodes, mpi.QNodes, mpi.Nps, mpi.Score, mpi.PvUCI)
 }
 
 type MultiPvInfos [MAX_MULTIPV]MultiPvInfo
 
 type Position struct {
-	States                   [MAX_STATES]State
+	// the board is updated in place by Push and restored by Pop from the undo records
+	Board                    State
+	// Undos[ptr] restores the state at ptr, after the move made from it is taken back
+	Undos                    [MAX_STATES]Undo
+	// search stack of the node at ptr
+	Stack                    [MAX_STATES]SearchStack
 	StatePtr                 int
 	SearchRootPtr            int
 	MaxStatePtr              int
 	Nodes                    int
+	QNodes                   int
+	// set by the searching goroutine only, other goroutines stop the search by closing StopSignal
 	SearchStopped            bool
+	// closed to stop the search, a nil channel never stops it
+	StopSignal               <-chan struct{}
 	NullMovePruning          bool
 	NullMovePruningMinDepth  int
 	NullMoveDepthReduction   int
 	StackReduction           bool
 	AspirationWindow         bool
+	Quiescence               bool
 	PvTable                  *PvHash
 	PosMoveTable             *PosMoveHash
+	TransTable               *TranspositionTable
 	LastRootPvScore          Score
 	LastGoodPv               []Move
 	Start                    time.Time
@@ -55,11 +66,43 @@ type Position struct {
 	IgnoreRootMoves          []Move
 	PruningAgressivity       int
 	PruningReduction         int
+	// number of duck placements searched for each move in duck chess, DEFAULT_DUCK_SEARCH_SQUARES if 0
+	DuckSquares              int
 	MultiPV                  int
 	MultiPvInfos             MultiPvInfos
 	OldMultiPvInfos          MultiPvInfos
 	MultiPvIndex             int
-	LogFilePath              string
+	// the search log is appended to this file, if set
+	LogFile                  *LogFile
+	// where the position writes to, DefaultOutput if nil
+	Out                      Output
+	Limits                   SearchLimits
+	MoveOverhead             int
+	SoftTimeMs               int
+	HardTimeMs               int
+	Threads                  int
+	Smp                      *SmpShared
+	HelperIndex              int
+	ReportedNodes            int
+	ReportedQNodes           int
+	PerftHash                bool
+	// zobrist keys of the game states dropped by Rebase, oldest first
+	History                  []uint64
+	// the start state of the game and the moves dropped by Rebase, the whole game can still be exported
+	GameStart                State
+	GameMoves                []Move
+	// tags of the loaded pgn game, they are kept for the export
+	PgnTags                  []PgnTag
+	// evaluations of the positions of the game, they are added to the exported moves, nil until the first search
+	Evals                    *EvalTable
+	OwnBook                  bool
+	BookFile                 string
+	BookDepth                int
+	BestBookMove             bool
+	// protocol front-ends can take over reporting of search info and best move
+	InfoHook                 func(mpi MultiPvInfo)
+	BestMoveHook             func(pv []Move)
+	PrintHook                func(pos *Position)
 }
 
 func (pos Position) Log(content string){
@@ -67,18 +110,10 @@ func (pos Position) Log(content string){
 		return
 	}
 
-	fmt.Println(content)
+	pos.Output().Println(content)
 
-	if pos.LogFilePath != ""{
-		f, err := os.OpenFile(pos.LogFilePath,
-			os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			fmt.Println(err)
-		}
-		defer f.Close()
-		if _, err := f.WriteString(content + "\n"); err != nil {
-			fmt.Println(err)
-		}
+	if pos.LogFile != nil{
+		pos.LogFile.Println(content)
 	}
 }
 
@@ -94,10 +129,6 @@ func (pos *Position) ClearPosMoveTable(){
 	}
 }
 
-func (pos Position) SearchRoot() *State{
-	return &pos.States[pos.SearchRootPtr]
-}
-
 func (sc Score) IsMateInN() bool{
 	return sc < -MAX_SCORE || sc > MAX_SCORE
 }
@@ -118,28 +149,60 @@ func (pos Position) PvUCI() string{
 	return pv
 }
 
-func (pos Position) Time() float32{
+func (pos *Position) Time() float32{
 	return float32(time.Now().Sub(pos.Start)) / 1e9
 }
 
-func (pos Position) Nps() float32{
-	return float32(pos.Nodes) / pos.Time()
+func (pos *Position) Nps() float32{
+	return float32(pos.TotalNodes() + pos.TotalQNodes()) / pos.Time()
 }
 
-func (pos Position) TimeMs() int{
+func (pos *Position) TimeMs() int{
 	return int(float32(time.Now().Sub(pos.Start)) / 1e6)
 }
 
-func (pos Position) CheckTime() float32{
+func (pos *Position) CheckTime() float32{
 	return float32(time.Now().Sub(pos.CheckPoint)) / 1e9
 }
 
 func (pos *Position) Current() *State {
-	return &pos.States[pos.StatePtr]
+	return &pos.Board
+}
+
+// StartState returns a copy of the state at ptr 0, by taking back all moves on a copy of the board
+func (pos *Position) StartState() State{
+	st := pos.Board
+
+	for ptr := pos.StatePtr - 1; ptr >= 0; ptr--{
+		st.UnmakeMove(&pos.Undos[ptr])
+	}
+
+	return st
+}
+
+// GameLine returns the start state of the game and all moves up to the current state, including the moves dropped by Rebase
+func (pos *Position) GameLine() (State, []Move){
+	start := pos.StartState()
+
+	if len(pos.GameMoves) > 0{
+		start = pos.GameStart
+	}
+
+	moves := append([]Move{}, pos.GameMoves...)
+
+	for ptr := 0; ptr < pos.StatePtr; ptr++{
+		moves = append(moves, pos.Undos[ptr].Move)
+	}
+
+	return start, moves
 }
 
 func (pos *Position) Init(variant Variant) {
 	pos.StatePtr = 0
+	pos.MaxStatePtr = 0
+	pos.History = []uint64{}
+	pos.GameMoves = nil
+	pos.PgnTags = nil
 	pos.Current().Init(variant)
 	pos.Current().Ply = 0
 }
@@ -148,18 +211,22 @@ func (pos *Position) Reset(){
 	pos.Init(pos.Current().Variant)
 }
 
-func (pos *Position) ParseFen(fen string){
+func (pos *Position) ParseFen(fen string) error{
 	pos.Reset()
-	pos.Current().ParseFen(fen)
+	return pos.Current().ParseFen(fen)
 }
 
 func (pos Position) Line() string {
 	sans := []string{}
 
-	for ptr := pos.StatePtr; ptr > 0; ptr-- {
-		prevSt := pos.States[ptr-1]
-		san := prevSt.MoveToSan(pos.States[ptr].Move)		
-		sans = append(sans, san)
+	st := pos.StartState()
+
+	undo := Undo{}
+
+	for ptr := 0; ptr < pos.StatePtr; ptr++ {
+		move := pos.Undos[ptr].Move
+		sans = append(sans, st.MoveToSan(move))
+		st.MakeMove(move, &undo)
 	}
 
 	return strings.Join(sans, " ")
@@ -182,21 +249,30 @@ func (st *State) Put(p Piece, sq Square) {
 		return
 	}
 
+	if p == DummyPiece{
+		// the duck only occupies its square
+		st.Pieces[RankOf[sq]][FileOf[sq]] = p
+		st.Duck = st.Duck.Or(sq.Bitboard())
+		st.Zobrist ^= zobristPiece[p][sq]
+
+		return
+	}
+
 	st.Pieces[RankOf[sq]][FileOf[sq]] = p
 
 	sqbb := sq.Bitboard()
 
 	color := ColorOf[p]
 
-	st.ByColor[color] |= sqbb
+	st.ByColor[color] = st.ByColor[color].Or(sqbb)
 
-	st.ByFigure[FigureOf[p]] |= sqbb
+	st.ByFigure[FigureOf[p]] = st.ByFigure[FigureOf[p]].Or(sqbb)
 
 	if p.IsLancer(){
-		st.ByLancer |= sqbb
+		st.ByLancer = st.ByLancer.Or(sqbb)
 	}
 
-	mat := PieceMaterialTables[p][sq]
+	mat := st.PieceMaterial(p, sq)
 
 	st.Material[color].Merge(mat)
 
@@ -221,19 +297,26 @@ func (st *State) Remove(sq Square) {
 
 	st.Pieces[RankOf[sq]][FileOf[sq]] = NoPiece
 
+	if p == DummyPiece{
+		st.Duck = st.Duck.AndNot(sq.Bitboard())
+		st.Zobrist ^= zobristPiece[p][sq]
+
+		return
+	}
+
 	color := ColorOf[p]
 
 	sqbb := sq.Bitboard()
 
-	st.ByColor[color] &^= sqbb
+	st.ByColor[color] = st.ByColor[color].AndNot(sqbb)
 
-	st.ByFigure[FigureOf[p]] &^= sqbb
+	st.ByFigure[FigureOf[p]] = st.ByFigure[FigureOf[p]].AndNot(sqbb)
 
 	if p.IsLancer(){
-		st.ByLancer &^= sqbb
+		st.ByLancer = st.ByLancer.AndNot(sqbb)
 	}
 
-	mat := PieceMaterialTables[p][sq]
+	mat := st.PieceMaterial(p, sq)
 
 	st.Material[color].UnMerge(mat)
 
@@ -252,33 +335,182 @@ func (st *State) Remove(sq Square) {
 const INFINITE_SCORE = Score(20000)
 const MATE_SCORE = Score(10000)
 
-func (pos Position) GameEnd(ply int) (bool, Score) {
+func (pos *Position) GameEnd(ply int) (bool, Score) {
 	st := pos.Current()
 
-	if st.KingInfos[st.Turn].IsCaptured {
+	if end, result, _ := st.VariantEnd(); end {
+		return true, Score(result) * (MATE_SCORE - Score(ply))
+	}
+
+	if st.RoyalKingCaptured() {
 		return true, -MATE_SCORE + Score(ply)
 	}
 
-	currentZobrist := pos.Zobrist()
+	if ply == 0{
+		// the root is searched even if drawn, so that there is a move to play
+		return false, 0
+	}
 
-	for testPtr := 0; testPtr < ( pos.StatePtr - 1 ); testPtr++{
-		if pos.States[testPtr].Zobrist == currentZobrist{
-			// repetition
-			return true, 0
-		}
+	if st.HalfmoveClock >= FIFTY_MOVE_RULE_PLIES && ( !st.IsCheckedUs() || st.HasLegalMove() ){
+		return true, 0
+	}
+
+	// a repetition inside the search tree is scored as a draw, before the root it has to be threefold
+	repetitions, inTree := pos.Repetitions()
+
+	if inTree || repetitions >= 2{
+		return true, 0
+	}
+
+	if st.InsufficientMaterial(){
+		return true, 0
 	}
 
 	return false, 0
 }
 
-func (st *State) MakeMove(move Move) {
+// max number of squares a move can change, an atomic capture changes the from and to squares and the 8 adjacent squares
+const MAX_SQUARE_CHANGES = 16
+
+// SquareChange records the piece that was on a square before a move
+type SquareChange struct{
+	Square Square
+	Piece  Piece
+}
+
+// Undo records what MakeMove changed, so that UnmakeMove can restore the state without copying it
+type Undo struct{
+	// the move made
+	Move                 Move
+	// Move of the state before the move
+	LastMove             Move
+	Turn                 Color
+	CanCastle            [ColorArraySize][2]bool
+	EpSquare             Square
+	HalfmoveClock        int
+	FullmoveNumber       int
+	HasDisabledMove      bool
+	DisableFromSquare    Square
+	DisableToSquare      Square
+	Ply                  int
+	Zobrist              uint64
+	KingInfos            [ColorArraySize]KingInfo
+	LostCastlingForColor [ColorArraySize]bool
+	Changes              [MAX_SQUARE_CHANGES]SquareChange
+	NumChanges           int
+	// crazyhouse piece taken from the pocket and piece put into the pocket, NoPiece if none
+	Dropped              Piece
+	Pocketed             Piece
+	Promoted             Bitboard
+	Checks               [ColorArraySize]int
+}
+
+// Save records the piece on the square, unless the square was already saved
+// has to be called before the square is changed
+func (undo *Undo) Save(st *State, sq Square){
+	for i := 0; i < undo.NumChanges; i++{
+		if undo.Changes[i].Square == sq{
+			return
+		}
+	}
+
+	undo.Changes[undo.NumChanges] = SquareChange{
+		Square: sq,
+		Piece: st.PieceAtSquare(sq),
+	}
+
+	undo.NumChanges++
+}
+
+// UnmakeMove takes back the move recorded in undo
+func (st *State) UnmakeMove(undo *Undo){
+	for i := undo.NumChanges - 1; i >= 0; i--{
+		st.Remove(undo.Changes[i].Square)
+	}
+
+	for i := 0; i < undo.NumChanges; i++{
+		st.Put(undo.Changes[i].Piece, undo.Changes[i].Square)
+	}
+
+	if undo.Dropped != NoPiece{
+		st.AddToPocket(undo.Dropped)
+	}
+
+	if undo.Pocketed != NoPiece{
+		st.RemoveFromPocket(undo.Pocketed)
+	}
+
+	st.Promoted = undo.Promoted
+	st.Checks = undo.Checks
+
+	for color := Black; color <= White; color++{
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++{
+			st.CastlingRights[color][side].CanCastle = undo.CanCastle[color][side]
+		}
+	}
+
+	st.Move = undo.LastMove
+	st.Turn = undo.Turn
+	st.EpSquare = undo.EpSquare
+	st.HalfmoveClock = undo.HalfmoveClock
+	st.FullmoveNumber = undo.FullmoveNumber
+	st.HasDisabledMove = undo.HasDisabledMove
+	st.DisableFromSquare = undo.DisableFromSquare
+	st.DisableToSquare = undo.DisableToSquare
+	st.Ply = undo.Ply
+	// the pieces are restored with their hash, the rest of the key is restored at once
+	st.Zobrist = undo.Zobrist
+	st.KingInfos = undo.KingInfos
+	st.LostCastlingForColor = undo.LostCastlingForColor
+}
+
+// MakeMove makes the move, recording in undo what is needed to take it back
+func (st *State) MakeMove(move Move, undo *Undo) {
+	undo.Move = move
+	undo.LastMove = st.Move
+	undo.Turn = st.Turn
+	for color := Black; color <= White; color++{
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++{
+			undo.CanCastle[color][side] = st.CastlingRights[color][side].CanCastle
+		}
+	}
+	undo.EpSquare = st.EpSquare
+	undo.HalfmoveClock = st.HalfmoveClock
+	undo.FullmoveNumber = st.FullmoveNumber
+	undo.HasDisabledMove = st.HasDisabledMove
+	undo.DisableFromSquare = st.DisableFromSquare
+	undo.DisableToSquare = st.DisableToSquare
+	undo.Ply = st.Ply
+	undo.Zobrist = st.Zobrist
+	undo.KingInfos = st.KingInfos
+	undo.LostCastlingForColor = st.LostCastlingForColor
+	undo.NumChanges = 0
+	undo.Dropped = NoPiece
+	undo.Pocketed = NoPiece
+	undo.Promoted = st.Promoted
+	undo.Checks = st.Checks
+
 	if move == NullMove{		
 		st.SetSideToMove(st.Turn.Inverse())
 
+		// the passing side gives up en passant captures and the disabled move
+		st.SetEpSquare(SquareA1)
+		st.ClearDisabledMove()
+
 		if st.Turn == White {
 			st.FullmoveNumber++
 		}
 
+		if VerifyHash{
+			st.VerifyZobrist(move)
+		}
+
+		return
+	}
+
+	if move.MoveType() == Drop{
+		st.MakeDrop(move, undo)
+
 		return
 	}
 
@@ -288,37 +520,67 @@ func (st *State) MakeMove(move Move) {
 
 	top := st.PieceAtSquare(move.ToSq())
 
+	undo.Save(st, move.FromSq())
+	undo.Save(st, move.ToSq())
+
 	st.Remove(move.FromSq())
 
 	st.Remove(move.ToSq())
 
-	st.HasDisabledMove = false
+	st.ClearDisabledMove()
 
 	if move.MoveType() == Promotion {
 		st.Put(move.PromotionPiece(), move.ToSq())
 	} else if move.MoveType() == SentryPush{
 		st.Put(p, move.ToSq())
 
+		undo.Save(st, move.PromotionSquare())
 		st.Remove(move.PromotionSquare())
 		st.Put(move.PromotionPiece(), move.PromotionSquare())
 
-		st.DisableFromSquare = move.PromotionSquare()
-		st.DisableToSquare = move.ToSq()
-		st.HasDisabledMove = true
+		st.SetDisabledMove(move.PromotionSquare(), move.ToSq())
 	} else if move.MoveType() == Castling{		
 		side := CastlingSideKing		
 		if FileOf[move.ToSq()] < FileOf[move.FromSq()]{
 			side = CastlingSideQueen
 		}
 		cts := st.CastlingTargetSquares(pCol, side)
+		undo.Save(st, cts[0])
+		undo.Save(st, cts[1])
 		st.Put(p, cts[0])
 		st.Put(st.CastlingRights[pCol][side].RookOrigPiece, cts[1])
 	} else {
 		st.Put(p, move.ToSq())
 	}
 
-	if st.Variant == VariantAtomic{
-		if ( top != NoPiece ) || ( ( st.EpSquare != SquareA1 ) && ( move.ToSq() == st.EpSquare ) ){
+	if st.Rules().Drops{
+		if top != NoPiece && move.MoveType() != Castling{
+			// the captured piece goes to the pocket of the capturer, a promoted piece as a pawn
+			fig := FigureOf[top]
+
+			if st.Promoted.Has(move.ToSq()){
+				fig = Pawn
+
+				st.TogglePromoted(move.ToSq())
+			}
+
+			undo.Pocketed = ColorFigure[pCol][fig]
+
+			st.AddToPocket(undo.Pocketed)
+		}
+
+		if st.Promoted.Has(move.FromSq()){
+
+			st.TogglePromoted(move.FromSq())
+			st.TogglePromoted(move.ToSq())
+		} else if move.MoveType() == Promotion{
+			st.TogglePromoted(move.ToSq())
+		}
+	}
+
+	if st.Rules().Explosions{
+		// castling is king takes own rook, but no capture, ep square explodes only for pawn captures
+		if ( top != NoPiece && move.MoveType() != Castling ) || ( FigureOf[p] == Pawn && ( st.EpSquare != SquareA1 ) && ( move.ToSq() == st.EpSquare ) ){
 			// atomic capture
 			st.Remove(move.ToSq())
 
@@ -326,6 +588,7 @@ func (st *State) MakeMove(move Move) {
 
 			for _, expSq := range adj.PopAll(){
 				if FigureOf[st.PieceAtSquare(expSq)] != Pawn{
+					undo.Save(st, expSq)
 					st.Remove(expSq)
 				}
 			}
@@ -357,17 +620,21 @@ func (st *State) MakeMove(move Move) {
 		newCastlingRights[pCol][CastlingSideQueen].CanCastle = false
 
 		st.SetCastlingAbility(newCastlingRights)
-	}else{
-		// check if castling partner was moved or captured, if so, delete castling right on that side
-		for color := Black; color <= White; color++{
-			for side := CastlingSideKing; side <= CastlingSideQueen; side++{
-				testSq := newCastlingRights[color][side].RookOrigSq
+	}
 
-				if move.FromSq() == testSq || move.ToSq() == testSq || st.PieceAtSquare(newCastlingRights[color][side].RookOrigSq) != newCastlingRights[color][side].RookOrigPiece {
-					newCastlingRights[pCol][side].CanCastle = false
+	// check if castling partner was moved or captured ( also by the king ), if so, delete castling right on that side
+	for color := Black; color <= White; color++{
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++{
+			testSq := newCastlingRights[color][side].RookOrigSq
 
-					st.SetCastlingAbility(newCastlingRights)
-				}
+			if !newCastlingRights[color][side].CanCastle{
+				continue
+			}
+
+			if move.FromSq() == testSq || move.ToSq() == testSq || st.PieceAtSquare(newCastlingRights[color][side].RookOrigSq) != newCastlingRights[color][side].RookOrigPiece {
+				newCastlingRights[color][side].CanCastle = false
+
+				st.SetCastlingAbility(newCastlingRights)
 			}
 		}
 	}
@@ -389,13 +656,21 @@ func (st *State) MakeMove(move Move) {
 			}
 		}
 
-		if move.ToSq() == oldEpSq{
+		// a1 means no ep square, a pawn promoting on a1 is not an ep capture
+		if oldEpSq != SquareA1 && move.ToSq() == oldEpSq{
 			var dir Rank = 1
 			if ColorOf[p] == White{
 				dir = -1
 			}
 			epClSq := RankFile[RankOf[move.ToSq()]+dir][FileOf[move.ToSq()]]			
+			undo.Save(st, epClSq)
 			st.Remove(epClSq)
+
+			if st.Rules().Drops{
+				undo.Pocketed = ColorFigure[pCol][Pawn]
+
+				st.AddToPocket(undo.Pocketed)
+			}
 		}
 	}
 
@@ -406,31 +681,116 @@ func (st *State) MakeMove(move Move) {
 	if st.Turn == White {
 		st.FullmoveNumber++
 	}
+
+	st.PlaceDuck(move, undo)
+
+	if st.Rules().CheckLimit > 0 && st.IsCheckedUs(){
+		st.AddCheck(st.Turn.Inverse())
+	}
+
+	if VerifyHash{
+		st.VerifyZobrist(move)
+	}
 }
 
-func (pos *Position) Push(move Move) {
-	oldState := pos.States[pos.StatePtr]
+// MakeDrop makes a crazyhouse drop, taking the dropped piece from the pocket
+func (st *State) MakeDrop(move Move, undo *Undo) {
+	p := move.PromotionPiece()
 
-	pos.StatePtr++
+	undo.Save(st, move.ToSq())
+
+	st.Put(p, move.ToSq())
+
+	undo.Dropped = p
+
+	st.RemoveFromPocket(p)
+
+	st.ClearDisabledMove()
+
+	st.Move = move
+
+	st.Ply++
+
+	st.SetSideToMove(st.Turn.Inverse())
+
+	st.HalfmoveClock++
+
+	st.SetEpSquare(SquareA1)
+
+	if st.Turn == White {
+		st.FullmoveNumber++
+	}
+
+	st.PlaceDuck(move, undo)
+
+	if VerifyHash{
+		st.VerifyZobrist(move)
+	}
+}
 
-	if pos.StatePtr > pos.MaxStatePtr || pos.Current().Move != move {
-		pos.MaxStatePtr = pos.StatePtr
+func (pos *Position) Push(move Move) {
+	undo := &pos.Undos[pos.StatePtr]
+
+	if pos.StatePtr >= pos.MaxStatePtr || undo.Move != move {
+		// a different move drops the moves that could be stepped forward to
+		pos.MaxStatePtr = pos.StatePtr + 1
 	}
 
-	pos.States[pos.StatePtr] = oldState
+	pos.Board.MakeMove(move, undo)
 
-	pos.Current().MakeMove(move)
+	pos.StatePtr++
 }
 
+// max number of game plies kept in the position, the rest of the States is left for the search
+const MAX_GAME_PLIES = MAX_STATES / 2
+
 func (pos *Position) PushUci(uci string){
 	move, ok := pos.Current().UciToMove(uci)
 	if ok{
 		pos.Push(move)
+		pos.Rebase(MAX_GAME_PLIES)
 	}
 }
 
+// Rebase drops the oldest states so that at most keep plies lead to the current state
+func (pos *Position) Rebase(keep int){
+	drop := pos.StatePtr - keep
+
+	if drop <= 0{
+		return
+	}
+
+	if len(pos.GameMoves) == 0{
+		pos.GameStart = pos.StartState()
+	}
+
+	for i := 0; i < drop; i++{
+		pos.History = append(pos.History, pos.Undos[i].Zobrist)
+		pos.GameMoves = append(pos.GameMoves, pos.Undos[i].Move)
+	}
+
+	if len(pos.History) > FIFTY_MOVE_RULE_PLIES{
+		// older positions can not repeat any more
+		pos.History = pos.History[len(pos.History) - FIFTY_MOVE_RULE_PLIES:]
+	}
+
+	copy(pos.Undos[:], pos.Undos[drop:pos.MaxStatePtr])
+
+	pos.StatePtr -= drop
+	pos.MaxStatePtr -= drop
+}
+
 func (pos *Position) Pop() {
 	pos.StatePtr--
+
+	pos.Board.UnmakeMove(&pos.Undos[pos.StatePtr])
+}
+
+// PopAll takes back all moves
+func (pos *Position) PopAll() {
+	for pos.StatePtr > 0{
+		pos.Pop()
+	}
 }
 
 func (pos *Position) PerfRec(remDepth int) {
@@ -457,14 +817,20 @@ func (pos *Position) Perf(depth int) {
 
 	elapsed := time.Now().Sub(start)
 
-	fmt.Printf("elapsed %v , nodes %v , nps %.3f Mn/s\n", elapsed, pos.Nodes, float32(pos.Nodes)/(float32(elapsed)/1e9)/1e6)
+	pos.Printf("elapsed %v , nodes %v , nps %.3f Mn/s\n", elapsed, pos.Nodes, float32(pos.Nodes)/(float32(elapsed)/1e9)/1e6)
 }
 
 func (pos *Position) Print() {
-	fmt.Println(pos.PrettyPrintString())
+	if pos.PrintHook != nil {
+		pos.PrintHook(pos)
+		return
+	}
+
+	pos.Println(pos.PrettyPrintString())
 }
 
-func (pos *Position) ExecCommand(command string) {
+// ExecCommand executes a command of the interactive console, the error tells that the command could not be executed
+func (pos *Position) ExecCommand(command string) error {
 	t := Tokenizer{command}
 	i := t.GetInt()
 
@@ -478,21 +844,21 @@ func (pos *Position) ExecCommand(command string) {
 			pos.Push(mb[i].Move)
 			pos.Print()
 		} else {
-			fmt.Println("warning : move index out of range")
+			return fmt.Errorf("warning : move index out of range")
 		}
 	} else if command == "d" {
 		if pos.StatePtr > 0 {
 			pos.Pop()
 			pos.Print()
 		} else {
-			fmt.Println("warning : no move to delete")
+			return fmt.Errorf("warning : no move to delete")
 		}
 	} else if command == "f" {
 		if pos.StatePtr < pos.MaxStatePtr {
-			pos.StatePtr++
+			pos.Push(pos.Undos[pos.StatePtr].Move)
 			pos.Print()
 		} else {
-			fmt.Println("warning : no move forward")
+			return fmt.Errorf("warning : no move forward")
 		}
 	} else if command == "perf" {
 		pos.Perf(4)
@@ -507,7 +873,9 @@ func (pos *Position) ExecCommand(command string) {
 			}
 		}
 		if !found {
-			fmt.Println("warning : unknown command or illegal move")
+			return fmt.Errorf("warning : unknown command or illegal move")
 		}
 	}
+
+	return nil
 }
@@ -0,0 +1,113 @@
+package basic
+
+import (
+	"reflect"
+	"testing"
+)
+
+// unmakeRec checks that taking back each move restores the state exactly
+func unmakeRec(t *testing.T, pos *Position, depth int) {
+	if depth == 0 {
+		return
+	}
+
+	for _, move := range pos.Current().LegalMoves(false) {
+		before := *pos.Current()
+
+		pos.Push(move)
+		unmakeRec(t, pos, depth-1)
+		pos.Pop()
+
+		if !reflect.DeepEqual(before, *pos.Current()) {
+			t.Fatalf("%s not restored after %s, got %s", before.ReportFen(), move.UCI(), pos.Current().ReportFen())
+		}
+	}
+}
+
+func TestUnmakeMove(t *testing.T) {
+	for _, test := range []struct {
+		variant Variant
+		fen     string
+	}{
+		{VariantStandard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+		{VariantStandard, "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1"},
+		{VariantChess960, "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"},
+		{VariantAtomic, "rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1"},
+		{VariantEightPiece, "jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1 -"},
+		{VariantCrazyhouse, "4k3/1Q~6/8/8/4b3/8/Kpp5/8[Nq] b - - 0 1"},
+		{VariantThreeCheck, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1 +2+1"},
+	} {
+		pos := Position{}
+		pos.Init(test.variant)
+		pos.ParseFen(test.fen)
+
+		unmakeRec(t, &pos, 2)
+	}
+}
+
+func TestStepForward(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		pos.PushUci(uci)
+	}
+
+	fen := pos.Current().ReportFen()
+
+	pos.PopAll()
+
+	if pos.Current().ReportFen() != VariantInfos[VariantStandard].StartFen {
+		t.Errorf("expected start position, got %s", pos.Current().ReportFen())
+	}
+
+	if pos.Line() != "" || pos.MaxStatePtr != 3 {
+		t.Errorf("wrong line %q or max state ptr %d", pos.Line(), pos.MaxStatePtr)
+	}
+
+	for pos.StatePtr < pos.MaxStatePtr {
+		pos.Push(pos.Undos[pos.StatePtr].Move)
+	}
+
+	if pos.Current().ReportFen() != fen || pos.Line() != "e4 e5 Nf3" {
+		t.Errorf("expected %s after e4 e5 Nf3, got %s after %s", fen, pos.Current().ReportFen(), pos.Line())
+	}
+}
+
+func TestCrazyhousePockets(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantCrazyhouse)
+	pos.ParseFen("4k3/1Q~6/8/8/4b3/8/Kpp5/8/N b - - 0 1")
+
+	if fen := pos.Current().ReportFen(); fen != "4k3/1Q~6/8/8/4b3/8/Kpp5/8[N] b - - 0 1" {
+		t.Errorf("wrong fen %s", fen)
+	}
+
+	for _, test := range []struct {
+		uci string
+		san string
+		fen string
+	}{
+		// the promoted queen goes to the pocket as a pawn
+		{"e4b7", "Bxb7", "4k3/1b6/8/8/8/8/Kpp5/8[Np] w - - 0 2"},
+		{"N@c3", "N@c3", "4k3/1b6/8/8/8/2N5/Kpp5/8[p] b - - 1 2"},
+		{"b2b1q", "b1=Q+", "4k3/1b6/8/8/8/2N5/K1p5/1q~6[p] w - - 0 3"},
+		{"c3b1", "Nxb1", "4k3/1b6/8/8/8/8/K1p5/1N6[Pp] b - - 0 3"},
+		{"P@b3", "P@b3+", "4k3/1b6/8/8/8/1p6/K1p5/1N6[P] w - - 1 4"},
+	} {
+		move, ok := pos.Current().UciToMove(test.uci)
+		if !ok {
+			t.Fatalf("illegal move %s", test.uci)
+		}
+
+		if san := pos.Current().MoveToSan(move); san != test.san {
+			t.Errorf("expected san %s for %s, got %s", test.san, test.uci, san)
+		}
+
+		pos.Push(move)
+
+		if fen := pos.Current().ReportFen(); fen != test.fen {
+			t.Errorf("expected %s after %s, got %s", test.fen, test.uci, fen)
+		}
+	}
+}
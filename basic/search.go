@@ -14,13 +14,7 @@ type AlphaBetaInfo struct {
 	NullMoveDepth int
 }
 
-type TranspositionTableEntry struct{
-	Score    Score
-	RemDepth int
-	Zobrist  uint64
-}
-
-func (st State) Phase() float32{
+func (st *State) Phase() float32{
 	mat := st.Material[White]
 	mat.Merge(st.Material[Black])
 
@@ -31,7 +25,7 @@ const MAX_SCORE = 9000
 
 const LOST_CASTLING_DEDUCTION = 50
 
-func (st State) LostCastlingDeductionForColor(color Color, phase float32) Score{
+func (st *State) LostCastlingDeductionForColor(color Color, phase float32) Score{
 	if st.LostCastlingForColor[color]{
 		return Score(phase * float32(LOST_CASTLING_DEDUCTION))
 	}	
@@ -39,11 +33,11 @@ func (st State) LostCastlingDeductionForColor(color Color, phase float32) Score{
 	return 0
 }
 
-func (st State) LostCastlingDeductionBalance(phase float32) Score{	
+func (st *State) LostCastlingDeductionBalance(phase float32) Score{	
 	return st.LostCastlingDeductionForColor(White, phase) - st.LostCastlingDeductionForColor(Black, phase)
 }
 
-func (st State) LostCastlingDeductionPOV(phase float32) Score{	
+func (st *State) LostCastlingDeductionPOV(phase float32) Score{	
 	bal := st.LostCastlingDeductionBalance(phase)
 	
 	if st.Turn == White{
@@ -53,11 +47,84 @@ func (st State) LostCastlingDeductionPOV(phase float32) Score{
 	return -bal
 }
 
-func (st State) Score() Score {
+// flag region bonus by the king's distance to the nearest square of the region, in king moves
+var FLAG_DISTANCE_BONUS = [NUM_FILES]Score{0, 250, 100, 30}
+
+func (st *State) FlagBonusBalance() Score{
+	bal := Score(0)
+
+	for color := Black; color <= White; color++{
+		if st.KingInfos[color].IsCaptured{
+			continue
+		}
+
+		bonus := FLAG_DISTANCE_BONUS[st.Rules().FlagDistance[st.KingInfos[color].Square]]
+
+		if color == White{
+			bal += bonus
+		}else{
+			bal -= bonus
+		}
+	}
+
+	return bal
+}
+
+// racing kings bonus by the rank of the king
+var RACING_KINGS_RANK_BONUS = [NUM_RANKS]Score{0, 0, 20, 50, 90, 150, 250, 400}
+
+func (st *State) RacingBonusBalance() Score{
+	return RACING_KINGS_RANK_BONUS[RankOf[st.KingInfos[White].Square]] - RACING_KINGS_RANK_BONUS[RankOf[st.KingInfos[Black].Square]]
+}
+
+// horde bonus for each advanced white pawn by its rank, the horde breaks through by promoting
+var HORDE_PAWN_RANK_BONUS = [NUM_RANKS]Score{0, 0, 0, 0, 10, 30, 60, 0}
+
+func (st *State) HordeBonusBalance() Score{
+	pawns := st.ByFigure[Pawn].And(st.ByColor[White])
+
+	bal := Score(0)
+
+	for rank := 0; rank < NUM_RANKS; rank++{
+		bal += HORDE_PAWN_RANK_BONUS[rank] * Score(pawns.And(RankBb(rank)).Count())
+	}
+
+	return bal
+}
+
+// VariantBonusPOV is the evaluation term of the variant goals from the point of view of the side to move
+func (st *State) VariantBonusPOV() Score{
+	bal := Score(0)
+
+	if st.Rules().FlagRegion != BbEmpty{
+
+		bal += st.FlagBonusBalance()
+	}
+
+	switch st.Rules().Win{
+	case WinRace:
+		bal += st.RacingBonusBalance()
+	case WinHorde:
+		bal += st.HordeBonusBalance()
+	}
+
+	if st.Turn == White{
+		return bal
+	}
+
+	return -bal
+}
+
+func (st *State) Score() Score {
 	phase := st.Phase()
 
 	mat := st.MaterialPOV()
 
+	if st.Rules().Win == WinAntichess{
+		// the aim is to lose material
+		mat = mat.Mult(-1)
+	}
+
 	mf := float32(mat.M)
 	ef := float32(mat.E)
 
@@ -71,6 +138,8 @@ func (st State) Score() Score {
 
 	score -= st.LostCastlingDeductionPOV(phase)
 
+	score += st.VariantBonusPOV()
+
 	if score > MAX_SCORE{
 		score = MAX_SCORE
 	}
@@ -83,12 +152,12 @@ func (st State) Score() Score {
 }
 
 func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
-	if int(pos.CheckTime()) % 20 == 1{		
-		time.Sleep(time.Second)
-	}
+	pos.Throttle()
 
 	pos.Nodes++
 
+	pos.CheckLimits()
+
 	st := pos.Current()
 
 	end, score := pos.GameEnd(abi.CurrentDepth)
@@ -98,15 +167,60 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 		return score
 	}
 
-	if abi.CurrentDepth >= abi.MaxDepth || pos.SearchStopped {
-		// if reached max depth or search stopped, return material score
+	if pos.SearchStopped {
+		// if search stopped, return material score
+		return st.Score()
+	}
+
+	remDepth := abi.MaxDepth - abi.CurrentDepth
+
+	hashMove := NullMove
+
+	tte, hasTte := pos.TransTable.Get(st.Zobrist)
+
+	if hasTte{
+		hashMove = tte.Move
+
+		if abi.CurrentDepth > 0 && int(tte.RemDepth) >= remDepth{
+			// https://www.chessprogramming.org/Transposition_Table#Probing
+			ttScore := ScoreFromTT(tte.Score, abi.CurrentDepth)
+
+			if tte.Bound == BoundExact{
+				if ttScore <= abi.Alpha{
+					return abi.Alpha
+				}
+
+				if ttScore >= abi.Beta{
+					return abi.Beta
+				}
+
+				return ttScore
+			}
+
+			if tte.Bound == BoundLower && ttScore >= abi.Beta{
+				return abi.Beta
+			}
+
+			if tte.Bound == BoundUpper && ttScore <= abi.Alpha{
+				return abi.Alpha
+			}
+		}
+	}
+
+	if abi.CurrentDepth >= abi.MaxDepth {
+		// if reached max depth, resolve violent moves
+		if pos.Quiescence{
+			return pos.QuiescenceRec(abi.Alpha, abi.Beta, abi.CurrentDepth)
+		}
+
 		return st.Score()
 	}
 
 	hasMove := false
 
 	// https://www.chessprogramming.org/Null_Move_Pruning
-	allowNMP := pos.NullMovePruning && (!abi.NullMoveMade) && abi.CurrentDepth >= pos.NullMovePruningMinDepth
+	// not in antichess, where captures are compulsory and zugzwang is common
+	allowNMP := pos.NullMovePruning && (!abi.NullMoveMade) && abi.CurrentDepth >= pos.NullMovePruningMinDepth && st.Rules().Win != WinAntichess
 
 	ignoreMoves := []Move{}
 
@@ -114,22 +228,26 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 		ignoreMoves = pos.IgnoreRootMoves
 	}
 
-	st.InitStack(allowNMP, pos.PvTable, ignoreMoves)
+	ss := &pos.Stack[pos.StatePtr]
+
+	ss.InitStack(st, allowNMP, pos.PvTable, ignoreMoves, hashMove)
 
 	currPvMove := NullMove
 
-	for st.StackPhase != GenDone {
-		move := st.PopStack(pos)
+	origAlpha := abi.Alpha
+
+	bestMove := NullMove
+
+	li := st.LegalityInfo()
+
+	undo := Undo{}
+
+	for ss.StackPhase != GenDone {
+		move := ss.PopStack(pos)
 
-		if st.StackPhase != GenDone{
+		if ss.StackPhase != GenDone && st.IsLegal(&li, move, &undo){
 			pos.Push(move)
-		}
 
-		if st.StackPhase == GenDone || pos.Current().IsCheckedThem(){
-			if st.StackPhase != GenDone{
-				pos.Pop()
-			}			
-		}else{
 			if move != NullMove{
 				hasMove = true
 			}
@@ -155,7 +273,7 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 
 			nodesStart := pos.Nodes
 
-			stackReduceDepth := st.StackReduceDepth
+			stackReduceDepth := ss.StackReduceDepth
 
 			if !pos.StackReduction || abi.CurrentDepth < 3{
 				stackReduceDepth = 0
@@ -176,7 +294,7 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 
 			if stackReduceDepth > 0{
 				for i := 0; i < stackReduceDepth; i++{
-					subTree *= st.StackReduceFactor
+					subTree *= ss.StackReduceFactor
 				}				
 			}
 			
@@ -189,12 +307,14 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 				// alpha improvement
 				abi.Alpha = score
 
+				bestMove = move
+
 				if abi.CurrentDepth == 0 && score.IsMateInN(){
 					// stop at forced mate
 					if pos.Verbose{
-						pos.Log(fmt.Sprintf("info skip %d", len(st.StackBuff)))
+						pos.Log(fmt.Sprintf("info skip %d", len(ss.StackBuff)))
 					}					
-					st.StackPhase = GenDone
+					ss.StackPhase = GenDone
 				}
 
 				if abi.CurrentDepth == 0{					
@@ -234,30 +354,140 @@ func (pos *Position) AlphaBetaRec(abi AlphaBetaInfo) Score {
 				}
 
 				if pos.Verbose{
-					pos.Log(fmt.Sprintf("info currstack %d currdepth %d time %d currpvmove %s latestrootscore cp %d oldpv %v", len(pos.SearchRoot().StackBuff), pos.Depth, pos.TimeMs(), currPvUci, pos.LastRootPvScore, pos.PvUCI()))
+					pos.Log(fmt.Sprintf("info currstack %d currdepth %d time %d currpvmove %s latestrootscore cp %d oldpv %v", len(pos.Stack[pos.SearchRootPtr].StackBuff), pos.Depth, pos.TimeMs(), currPvUci, pos.LastRootPvScore, pos.PvUCI()))
 				}				
 
 				pos.CheckPoint = time.Now()
 			}
 
 			if score >= abi.Beta {
-				// beta cut				
+				// beta cut
+				pos.StoreTransTable(abi, BoundLower, abi.Beta, move)
+
 				return abi.Beta
 			}
 		}
 	}
 
 	if !hasMove{
+		score = 0
+
 		if pos.Current().IsCheckedUs(){
-			return -MATE_SCORE + Score(abi.CurrentDepth)
-		}else{
-			return 0
+			score = -MATE_SCORE + Score(abi.CurrentDepth)
 		}
+
+		if st.Rules().StalemateWins(){
+			// being stalemated wins antichess and duck chess
+			score = MATE_SCORE - Score(abi.CurrentDepth)
+		}
+
+		pos.StoreTransTable(abi, BoundExact, score, NullMove)
+
+		return score
+	}
+
+	if abi.Alpha > origAlpha{
+		pos.StoreTransTable(abi, BoundExact, abi.Alpha, bestMove)
+	}else{
+		pos.StoreTransTable(abi, BoundUpper, abi.Alpha, NullMove)
 	}
 
 	return abi.Alpha
 }
 
+// StoreTransTable stores the result of the current node in the transposition table
+func (pos *Position) StoreTransTable(abi AlphaBetaInfo, bound uint8, score Score, move Move){
+	if pos.SearchStopped || abi.CurrentDepth == 0{
+		// result of stopped search is unreliable, root may have ignored moves
+		return
+	}
+
+	pos.TransTable.Set(pos.Zobrist(), TranspositionTableEntry{
+		Move: move,
+		Score: ScoreToTT(score, abi.CurrentDepth),
+		RemDepth: int8(abi.MaxDepth - abi.CurrentDepth),
+		Bound: bound,
+	})
+}
+
+// QuiescenceRec searches violent moves past the horizon until the position is quiet
+// https://www.chessprogramming.org/Quiescence_Search
+func (pos *Position) QuiescenceRec(alpha, beta Score, ply int) Score {
+	pos.QNodes++
+
+	pos.CheckLimits()
+
+	end, score := pos.GameEnd(ply)
+
+	if end {
+		// if game ended, return final score
+		return score
+	}
+
+	st := pos.Current()
+
+	var moves []Move
+
+	forced := false
+
+	if st.Rules().Win == WinAntichess {
+		// captures are compulsory, the side to move can only stand pat without captures
+		moves = st.QuiescenceMoves()
+
+		forced = len(moves) > 0
+	}
+
+	if !forced {
+		// stand pat
+		standPat := st.Score()
+
+		if standPat >= beta {
+			return beta
+		}
+
+		if standPat > alpha {
+			alpha = standPat
+		}
+	}
+
+	if pos.SearchStopped || pos.StatePtr >= MAX_STATES - 1{
+		// no room for further moves
+		return alpha
+	}
+
+	li := st.LegalityInfo()
+
+	undo := Undo{}
+
+	if moves == nil {
+		moves = st.QuiescenceMoves()
+	}
+
+	for _, move := range moves{
+		if !st.IsLegal(&li, move, &undo){
+			continue
+		}
+
+		pos.Push(move)
+
+		score = -pos.QuiescenceRec(-beta, -alpha, ply + 1)
+
+		pos.Pop()
+
+		if score >= beta {
+			// beta cut
+			return beta
+		}
+
+		if score > alpha {
+			// alpha improvement
+			alpha = score
+		}
+	}
+
+	return alpha
+}
+
 func (pos *Position) AlphaBeta(maxDepth int) Score {	
 	pos.PvTable.Set(pos.Zobrist(), PvEntry{		
 		Depth: INFINITE_DEPTH,
@@ -333,7 +563,7 @@ func (pos *Position) AlphaBeta(maxDepth int) Score {
 	})
 }
 
-func (pos Position) Zobrist() uint64 {
+func (pos *Position) Zobrist() uint64 {
 	return pos.Current().Zobrist
 }
 
@@ -351,17 +581,58 @@ func (pos Position) GetPvRec(depthRemaining int, pvSoFar []Move) []Move {
 		return pos.GetPvRec(depthRemaining-1, append(pvSoFar, moves[0]))
 	}
 
+	if pos.TransTable != nil {
+		// pv table has no move, try hash move, which may have caused a cutoff
+		tte, hasTte := pos.TransTable.Get(pos.Zobrist())
+
+		if hasTte && tte.Move != NullMove {
+			for _, move := range pos.Current().LegalMoves(false) {
+				if move == tte.Move {
+					pos.Push(move)
+					return pos.GetPvRec(depthRemaining-1, append(pvSoFar, move))
+				}
+			}
+		}
+	}
+
 	return pvSoFar
 }
 
 func (pos Position) GetPv(maxDepth int) []Move {
-	oldStatePtr := pos.StatePtr
-	pv := pos.GetPvRec(maxDepth, []Move{})
-	pos.StatePtr = oldStatePtr
-	return pv
+	// pos is a copy, the moves of the pv need not be taken back
+	return pos.GetPvRec(maxDepth, []Move{})
+}
+
+// ReportInfo reports the info of a completed iteration
+func (pos *Position) ReportInfo(mpi MultiPvInfo) {
+	if pos.InfoHook != nil {
+		pos.InfoHook(mpi)
+		return
+	}
+
+	pos.Log(mpi.String())
 }
 
 func (pos *Position) PrintBestMove(pv []Move) {
+	if pos.LogFile != nil {
+		// the log of the search is written when it ends
+		defer pos.LogFile.Flush()
+	}
+
+	if len(pv) == 0 {
+		// search was stopped before the first iteration completed, fall back to any legal move
+		st := pos.Current()
+		st.GenMoveBuff()
+		if len(st.MoveBuff) > 0 {
+			pv = []Move{st.MoveBuff[0].Move}
+		}
+	}
+
+	if pos.BestMoveHook != nil {
+		pos.BestMoveHook(pv)
+		return
+	}
+
 	if len(pv) == 0 {
 		pos.Log("bestmove (none)")
 		return
@@ -377,22 +648,53 @@ func (pos *Position) PrintBestMove(pv []Move) {
 
 var PvTable PvHash
 var PosMoveTable PosMoveHash
+var TransTable TranspositionTable
 
+// Search runs iterative deepening up to maxDepth and reports the best move
+// positions without tables of their own search with the global ones, so only one of them can search at a time
 func (pos *Position) Search(maxDepth int) {
-	pos.PvTable = &PvTable
+	if pos.PvTable == nil{
+		pos.PvTable = &PvTable
+	}
 	pos.ClearPvTable()
-	pos.PosMoveTable = &PosMoveTable
+	if pos.PosMoveTable == nil{
+		pos.PosMoveTable = &PosMoveTable
+	}
 	pos.ClearPosMoveTable()
+	if pos.TransTable == nil{
+		pos.TransTable = &TransTable
+	}
+	pos.TransTable.NewSearch()
 
 	pos.LastGoodPv = []Move{}
 
 	pos.SearchStopped = false
 
+	if !pos.Limits.Infinite && len(pos.IgnoreRootMoves) == 0{
+		bookMove, ok := pos.BookMove()
+
+		if ok{
+			pos.Log("info string book move " + bookMove.UCI())
+			pos.PrintBestMove([]Move{bookMove})
+			return
+		}
+	}
+
 	pos.Nodes = 0
+	pos.QNodes = 0
 
 	pos.Start = time.Now()
 	pos.CheckPoint = pos.Start
 
+	pos.AllocateTime()
+
+	if pos.Limits.Mate > 0 && maxDepth > 2 * pos.Limits.Mate{
+		// mate in n moves needs at most 2n - 1 plies
+		maxDepth = 2 * pos.Limits.Mate
+	}
+
+	pos.StartHelpers(maxDepth)
+
 	ignoreMovesOrig := pos.IgnoreRootMoves
 
 	st := pos.Current()
@@ -427,7 +729,9 @@ func (pos *Position) Search(maxDepth int) {
 			if pos.SearchStopped {
 				pos.IgnoreRootMoves = ignoreMovesOrig
 
-				pos.PrintBestMove(pos.OldMultiPvInfos[0].Pv)
+				pos.RecordEval()
+
+				pos.PrintBestMove(pos.FinishHelpers())
 				return
 			}
 
@@ -451,7 +755,8 @@ func (pos *Position) Search(maxDepth int) {
 			pos.MultiPvInfos[pos.MultiPvIndex - 1] = MultiPvInfo{
 				Depth: pos.Depth,
 				Time: pos.TimeMs(),
-				Nodes: pos.Nodes,
+				Nodes: pos.TotalNodes(),
+				QNodes: pos.TotalQNodes(),
 				Nps: pos.Nps(),
 				Score: pos.LastRootPvScore,
 				Pv: pos.LastGoodPv,
@@ -474,14 +779,32 @@ func (pos *Position) Search(maxDepth int) {
 
 		for i := 1; i <= maxMultiPv; i++{
 			pos.MultiPvInfos[i - 1].Index = i
-			pos.Log(pos.MultiPvInfos[i - 1].String())
+			pos.ReportInfo(pos.MultiPvInfos[i - 1])
 		}
 
 		pos.OldMultiPvInfos = pos.MultiPvInfos
 
+		if !pos.IterationAllowed(){
+			break
+		}
 	}
 
 	pos.IgnoreRootMoves = ignoreMovesOrig
+
+	pos.RecordEval()
 				
-	pos.PrintBestMove(pos.OldMultiPvInfos[0].Pv)
+	pos.PrintBestMove(pos.FinishHelpers())
+}
+
+// FinishHelpers stops the helpers and returns the best pv found by all searchers
+func (pos *Position) FinishHelpers() []Move{
+	pos.StopHelpers()
+
+	helperPv, ok := pos.HelperBestPv(pos.OldMultiPvInfos[0].Depth)
+
+	if ok{
+		return helperPv
+	}
+
+	return pos.OldMultiPvInfos[0].Pv
 }
@@ -0,0 +1,32 @@
+package basic
+
+import "testing"
+
+func TestQuiescenceMoves(t *testing.T) {
+	st := State{}
+
+	st.Init(VariantStandard)
+
+	st.ParseFen("4k3/8/2p5/3q4/4P3/2Q5/8/4K3 w - - 0 1")
+
+	qms := st.QuiescenceMoves()
+
+	if len(qms) != 2 {
+		t.Errorf("expected 2 quiescence moves, got %d", len(qms))
+		return
+	}
+
+	if qms[0].UCI() != "e4d5" {
+		t.Errorf("expected pawn takes queen first, got %s", qms[0].UCI())
+	}
+
+	if qms[1].UCI() != "c3c6" {
+		t.Errorf("expected queen takes pawn second, got %s", qms[1].UCI())
+	}
+
+	st.ParseFen("4k3/8/8/8/8/8/8/R3K3 w Q - 0 1")
+
+	if len(st.QuiescenceMoves()) != 0 {
+		t.Errorf("expected no quiescence moves in quiet position, got %v", st.QuiescenceMoves())
+	}
+}
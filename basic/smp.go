@@ -0,0 +1,181 @@
+package basic
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// number of entries of the private pv and pos move hash of a helper
+const HELPER_HASH_SIZE = 1 << 16
+
+const MAX_THREADS = 256
+
+// SmpShared is the state shared between the main searcher and its helpers
+// helpers search the same root with their own Position copy and share the transposition table
+// https://www.chessprogramming.org/Lazy_SMP
+type SmpShared struct {
+	Stop      int32
+	Nodes     int64
+	QNodes    int64
+	WaitGroup sync.WaitGroup
+	Mutex     sync.Mutex
+	BestDepth int
+	BestScore Score
+	BestPv    []Move
+	// the positions of the helpers, their counters are final once the helpers are stopped
+	Helpers   []*Position
+}
+
+// StartHelpers starts Threads - 1 helper searchers on copies of the position
+func (pos *Position) StartHelpers(maxDepth int) {
+	pos.Smp = nil
+
+	if pos.Threads <= 1 {
+		return
+	}
+
+	smp := &SmpShared{}
+
+	pos.Smp = smp
+
+	for i := 1; i < pos.Threads; i++ {
+		helper := new(Position)
+
+		*helper = *pos
+
+		helper.HelperIndex = i
+		helper.Verbose = false
+		helper.PvTable = &PvHash{}
+		helper.PvTable.Alloc(HELPER_HASH_SIZE)
+		helper.PosMoveTable = &PosMoveHash{}
+		helper.PosMoveTable.Alloc(HELPER_HASH_SIZE)
+		helper.Nodes = 0
+		helper.QNodes = 0
+		helper.ReportedNodes = 0
+		helper.ReportedQNodes = 0
+
+		smp.Helpers = append(smp.Helpers, helper)
+
+		smp.WaitGroup.Add(1)
+
+		go helper.HelperSearch(maxDepth)
+	}
+}
+
+// HelperSearch is the iterative deepening loop of a helper
+// every second helper starts one ply deeper, so that helpers diverge from the main searcher
+func (pos *Position) HelperSearch(maxDepth int) {
+	defer pos.Smp.WaitGroup.Done()
+
+	for pos.Depth = 1 + pos.HelperIndex%2; pos.Depth <= maxDepth; pos.Depth++ {
+		pos.AlphaBeta(pos.Depth)
+
+		if pos.SearchStopped {
+			break
+		}
+
+		pos.ReportHelperResult()
+	}
+
+	pos.ReportHelperNodes()
+}
+
+// ReportHelperResult records the result of a completed helper iteration if it is the deepest so far
+func (pos *Position) ReportHelperResult() {
+	pv := pos.GetPv(pos.Depth)
+
+	if len(pv) == 0 {
+		return
+	}
+
+	pos.Smp.Mutex.Lock()
+	defer pos.Smp.Mutex.Unlock()
+
+	if pos.Depth > pos.Smp.BestDepth {
+		pos.Smp.BestDepth = pos.Depth
+		pos.Smp.BestScore = pos.LastRootPvScore
+		pos.Smp.BestPv = pv
+	}
+}
+
+// ReportHelperNodes adds the nodes searched by the helper since the last report to the shared counters
+func (pos *Position) ReportHelperNodes() {
+	atomic.AddInt64(&pos.Smp.Nodes, int64(pos.Nodes-pos.ReportedNodes))
+	atomic.AddInt64(&pos.Smp.QNodes, int64(pos.QNodes-pos.ReportedQNodes))
+
+	pos.ReportedNodes = pos.Nodes
+	pos.ReportedQNodes = pos.QNodes
+}
+
+// CheckHelper reports nodes and picks up the stop signal, called periodically by helpers
+func (pos *Position) CheckHelper() {
+	pos.ReportHelperNodes()
+
+	if atomic.LoadInt32(&pos.Smp.Stop) != 0 {
+		pos.SearchStopped = true
+	}
+}
+
+// StopHelpers stops the helpers and waits for them to finish
+func (pos *Position) StopHelpers() {
+	if pos.Smp == nil {
+		return
+	}
+
+	atomic.StoreInt32(&pos.Smp.Stop, 1)
+
+	pos.Smp.WaitGroup.Wait()
+}
+
+// HelperBestPv returns the pv of a helper if it completed a deeper iteration than the main searcher
+func (pos *Position) HelperBestPv(mainDepth int) ([]Move, bool) {
+	if pos.Smp == nil || pos.MultiPV > 1 {
+		return []Move{}, false
+	}
+
+	pos.Smp.Mutex.Lock()
+	defer pos.Smp.Mutex.Unlock()
+
+	if pos.Smp.BestDepth <= mainDepth {
+		return []Move{}, false
+	}
+
+	buff := []string{}
+
+	for _, move := range pos.Smp.BestPv {
+		buff = append(buff, move.UCI())
+	}
+
+	pos.ReportInfo(MultiPvInfo{
+		Index: 1,
+		Depth: pos.Smp.BestDepth,
+		Time: pos.TimeMs(),
+		Nodes: pos.TotalNodes(),
+		QNodes: pos.TotalQNodes(),
+		Nps: pos.Nps(),
+		Score: pos.Smp.BestScore,
+		Pv: pos.Smp.BestPv,
+		PvUCI: strings.Join(buff, " "),
+	})
+
+	return pos.Smp.BestPv, true
+}
+
+// TotalNodes tells the nodes searched by the main searcher and all helpers
+func (pos *Position) TotalNodes() int {
+	if pos.Smp == nil || pos.HelperIndex > 0 {
+		return pos.Nodes
+	}
+
+	return pos.Nodes + int(atomic.LoadInt64(&pos.Smp.Nodes))
+}
+
+// TotalQNodes tells the quiescence nodes searched by the main searcher and all helpers
+func (pos *Position) TotalQNodes() int {
+	if pos.Smp == nil || pos.HelperIndex > 0 {
+		return pos.QNodes
+	}
+
+	return pos.QNodes + int(atomic.LoadInt64(&pos.Smp.QNodes))
+}
@@ -4,21 +4,13 @@ package basic
 
 const NUM_RANKS = 8
 const LAST_RANK = NUM_RANKS - 1
-const NUM_FILES = 8
+const NUM_FILES = 10
 const LAST_FILE = NUM_FILES - 1
 
 const BOARD_AREA = NUM_RANKS * NUM_FILES
 
-const RANK_STORAGE_SIZE_IN_BITS = 3
-const FILE_STORAGE_SIZE_IN_BITS = 3
-
-const SQUARE_STORAGE_SIZE_IN_BITS = RANK_STORAGE_SIZE_IN_BITS + FILE_STORAGE_SIZE_IN_BITS
-
-const RANK_SHIFT_IN_BITS = FILE_STORAGE_SIZE_IN_BITS
-const FILE_SHIFT_IN_BITS = 0
-
-const RANK_MASK = ((1 << RANK_STORAGE_SIZE_IN_BITS) - 1) << RANK_SHIFT_IN_BITS
-const FILE_MASK = (1 << FILE_STORAGE_SIZE_IN_BITS) - 1
+// squares are numbered rank by rank, square = rank * NUM_FILES + file
+const SQUARE_STORAGE_SIZE_IN_BITS = 7
 
 type Rank int
 type File int
@@ -33,66 +25,82 @@ const (
    SquareF1 = Square(5)
    SquareG1 = Square(6)
    SquareH1 = Square(7)
-   SquareA2 = Square(8)
-   SquareB2 = Square(9)
-   SquareC2 = Square(10)
-   SquareD2 = Square(11)
-   SquareE2 = Square(12)
-   SquareF2 = Square(13)
-   SquareG2 = Square(14)
-   SquareH2 = Square(15)
-   SquareA3 = Square(16)
-   SquareB3 = Square(17)
-   SquareC3 = Square(18)
-   SquareD3 = Square(19)
-   SquareE3 = Square(20)
-   SquareF3 = Square(21)
-   SquareG3 = Square(22)
-   SquareH3 = Square(23)
-   SquareA4 = Square(24)
-   SquareB4 = Square(25)
-   SquareC4 = Square(26)
-   SquareD4 = Square(27)
-   SquareE4 = Square(28)
-   SquareF4 = Square(29)
-   SquareG4 = Square(30)
-   SquareH4 = Square(31)
-   SquareA5 = Square(32)
-   SquareB5 = Square(33)
-   SquareC5 = Square(34)
-   SquareD5 = Square(35)
-   SquareE5 = Square(36)
-   SquareF5 = Square(37)
-   SquareG5 = Square(38)
-   SquareH5 = Square(39)
-   SquareA6 = Square(40)
-   SquareB6 = Square(41)
-   SquareC6 = Square(42)
-   SquareD6 = Square(43)
-   SquareE6 = Square(44)
-   SquareF6 = Square(45)
-   SquareG6 = Square(46)
-   SquareH6 = Square(47)
-   SquareA7 = Square(48)
-   SquareB7 = Square(49)
-   SquareC7 = Square(50)
-   SquareD7 = Square(51)
-   SquareE7 = Square(52)
-   SquareF7 = Square(53)
-   SquareG7 = Square(54)
-   SquareH7 = Square(55)
-   SquareA8 = Square(56)
-   SquareB8 = Square(57)
-   SquareC8 = Square(58)
-   SquareD8 = Square(59)
-   SquareE8 = Square(60)
-   SquareF8 = Square(61)
-   SquareG8 = Square(62)
-   SquareH8 = Square(63)
+   SquareI1 = Square(8)
+   SquareJ1 = Square(9)
+   SquareA2 = Square(10)
+   SquareB2 = Square(11)
+   SquareC2 = Square(12)
+   SquareD2 = Square(13)
+   SquareE2 = Square(14)
+   SquareF2 = Square(15)
+   SquareG2 = Square(16)
+   SquareH2 = Square(17)
+   SquareI2 = Square(18)
+   SquareJ2 = Square(19)
+   SquareA3 = Square(20)
+   SquareB3 = Square(21)
+   SquareC3 = Square(22)
+   SquareD3 = Square(23)
+   SquareE3 = Square(24)
+   SquareF3 = Square(25)
+   SquareG3 = Square(26)
+   SquareH3 = Square(27)
+   SquareI3 = Square(28)
+   SquareJ3 = Square(29)
+   SquareA4 = Square(30)
+   SquareB4 = Square(31)
+   SquareC4 = Square(32)
+   SquareD4 = Square(33)
+   SquareE4 = Square(34)
+   SquareF4 = Square(35)
+   SquareG4 = Square(36)
+   SquareH4 = Square(37)
+   SquareI4 = Square(38)
+   SquareJ4 = Square(39)
+   SquareA5 = Square(40)
+   SquareB5 = Square(41)
+   SquareC5 = Square(42)
+   SquareD5 = Square(43)
+   SquareE5 = Square(44)
+   SquareF5 = Square(45)
+   SquareG5 = Square(46)
+   SquareH5 = Square(47)
+   SquareI5 = Square(48)
+   SquareJ5 = Square(49)
+   SquareA6 = Square(50)
+   SquareB6 = Square(51)
+   SquareC6 = Square(52)
+   SquareD6 = Square(53)
+   SquareE6 = Square(54)
+   SquareF6 = Square(55)
+   SquareG6 = Square(56)
+   SquareH6 = Square(57)
+   SquareI6 = Square(58)
+   SquareJ6 = Square(59)
+   SquareA7 = Square(60)
+   SquareB7 = Square(61)
+   SquareC7 = Square(62)
+   SquareD7 = Square(63)
+   SquareE7 = Square(64)
+   SquareF7 = Square(65)
+   SquareG7 = Square(66)
+   SquareH7 = Square(67)
+   SquareI7 = Square(68)
+   SquareJ7 = Square(69)
+   SquareA8 = Square(70)
+   SquareB8 = Square(71)
+   SquareC8 = Square(72)
+   SquareD8 = Square(73)
+   SquareE8 = Square(74)
+   SquareF8 = Square(75)
+   SquareG8 = Square(76)
+   SquareH8 = Square(77)
+   SquareI8 = Square(78)
+   SquareJ8 = Square(79)
 )
 
 const SquareMinValue = SquareA1
-const SquareMaxValue = SquareH8
+const SquareMaxValue = SquareJ8
 
 const (
    Rank1 = Rank(0)
@@ -114,10 +122,12 @@ const (
    FileF = File(5)
    FileG = File(6)
    FileH = File(7)
+   FileI = File(8)
+   FileJ = File(9)
 )
 
 var RankLetterOf = [NUM_RANKS]string{"1" , "2" , "3" , "4" , "5" , "6" , "7" , "8"}
-var FileLetterOf = [NUM_FILES]string{"a" , "b" , "c" , "d" , "e" , "f" , "g" , "h"}
+var FileLetterOf = [NUM_FILES]string{"a" , "b" , "c" , "d" , "e" , "f" , "g" , "h" , "i" , "j"}
 
 var UCIOf [BOARD_AREA]string
 var UCIToSquare map[string]Square
@@ -62,7 +62,11 @@ func NormalizedRookDirection(fromSq, toSq Square) (Delta, bool) {
 
 // Bitboard returns a bitboard that has sq set
 func (sq Square) Bitboard() Bitboard {
-	return 1 << sq
+	if sq < 64 {
+		return Bitboard{Lo: 1 << sq}
+	}
+
+	return Bitboard{Hi: 1 << (sq - 64)}
 }
 
 // RankFile constructs a square from rank and file
@@ -76,12 +80,13 @@ var RankOf [BOARD_AREA]Rank
 
 // Rank tells the rank of a square
 func (sq Square) Rank() Rank {
-	return Rank((sq & Square(RANK_MASK)) >> RANK_SHIFT_IN_BITS)
+	return Rank(sq / NUM_FILES)
 }
 
 // File tells the file of a square
 func (sq Square) File() File {
-	return File(sq & Square(FILE_MASK))
+	return File(sq % NUM_FILES)
+
 }
 
 // UCI tells the UCI representation of a square
@@ -45,7 +45,7 @@ func BenchmarkSquareArray(b *testing.B) {
 		for i := 0; i < BOARD_AREA; i++ {
 			rank, file := RankOf[Square(i)], FileOf[Square(i)]
 
-			file, rank = rank, file
+			_, _ = rank, file
 		}
 	}
 }
@@ -55,7 +55,7 @@ func BenchmarkSquareMember(b *testing.B) {
 		for i := 0; i < BOARD_AREA; i++ {
 			rank, file := Square(i).Rank(), Square(i).File()
 
-			file, rank = rank, file
+			_, _ = rank, file
 		}
 	}
 }
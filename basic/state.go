@@ -16,26 +16,156 @@ const (
 	VariantStandard = Variant(iota)
 	VariantEightPiece
 	VariantAtomic
+	VariantChess960
+	VariantCrazyhouse
+	VariantThreeCheck
+	VariantKingOfTheHill
+	VariantAntichess
+	VariantHorde
+	VariantRacingKings
+	VariantCapablanca
+	VariantGothic
+	VariantEmbassy
+	VariantDuck
 )
 
+// number of files of the standard board, variants on it do not use the further files
+const STANDARD_NUM_FILES = 8
+
+// VariantInfo describes a variant, the rules are looked up by the engine instead of the variant itself, so variants.ini can combine them
 type VariantInfo struct {
 	StartFen    string
 	DisplayName string
+	// the figures the start position may use, nil for any
+	Figures []Figure
+	// the piece set includes lancers, sentries and jailers
+	FairyPieces bool
+	PromotionFigures []Figure
+	// castling rights are given by rook files and the king may castle from any file
+	Chess960 bool
+	// castling rights of the fen are ignored
+	NoCastling bool
+	// captures explode the pieces around the target square, pawns excepted
+	Explosions bool
+	// captured pieces go to the pocket of the capturer and can be dropped
+	Drops bool
+	// number of checks that win, 0 if checks are not counted
+	CheckLimit int
+	// a king reaching one of these squares wins
+	FlagRegion Bitboard
+	// after each move the mover places the duck on an empty square
+	Duck bool
+	Win WinCondition
+	// values by figure overriding the default ones, nil if not overridden
+	PieceValues *[FigureArraySize]Accum
+	// number of files, STANDARD_NUM_FILES if not given
+	Files int
+	// the files the king castles to on the king and queen side, the rook lands next to the king, c and the last but one file if not given
+	CastlingFiles [2]File
+	// set up by Setup
+	Board Bitboard
+	FlagDistance [BOARD_AREA]int
+	MaterialTables *[PieceArraySize + 2]PieceMaterialTable
+	PocketValues [FigureArraySize]Accum
 }
 
+var STANDARD_PROMOTION_FIGURES = []Figure{Queen, Rook, Bishop, Knight}
+
+var CAPABLANCA_PROMOTION_FIGURES = []Figure{Queen, Chancellor, Archbishop, Rook, Bishop, Knight}
+
 var VariantInfos = []VariantInfo{
 	{ // standard
 		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		DisplayName: "Standard",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
 	},
 	{ // eightpiece
 		StartFen:    "jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1 -",
 		DisplayName: "Eightpiece",
+		FairyPieces: true,
+		PromotionFigures: []Figure{Queen, Rook, Bishop, Knight, LancerN, LancerNE, LancerE, LancerSE, LancerS, LancerSW, LancerW, LancerNW, Sentry, Jailer},
 	},
 	{ // atomic
 		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		DisplayName: "Atomic",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		Explosions: true,
+	},
+	{ // chess960, start position is set up by the position command, defaults to 518 ( standard )
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		DisplayName: "Chess960",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		Chess960: true,
+	},
+	{ // crazyhouse, the pockets are given in brackets after the placement
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		DisplayName: "Crazyhouse",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		Drops: true,
+	},
+	{ // three-check, the checks given are counted in the +N+M fen field
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+		DisplayName: "Three-check",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		CheckLimit: THREE_CHECK_LIMIT,
+	},
+	{ // king of the hill
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		DisplayName: "King of the Hill",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		FlagRegion: HILL,
+	},
+	{ // antichess, kings are ordinary pieces and there is no castling
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+		DisplayName: "Antichess",
+		PromotionFigures: []Figure{Queen, Rook, Bishop, Knight, King},
+		NoCastling: true,
+		Win: WinAntichess,
+	},
+	{ // horde, white has no king and wins by mating, black wins by capturing every white piece
+		StartFen:    "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1",
+		DisplayName: "Horde",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		Win: WinHorde,
+	},
+	{ // racing kings, checks are not allowed and the first king on the eighth rank wins
+		StartFen:    "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1",
+		DisplayName: "Racing Kings",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		NoCastling: true,
+		Win: WinRace,
+	},
+	{ // capablanca, on a 10x8 board with archbishop ( bishop + knight ) and chancellor ( rook + knight )
+		StartFen:    "rnabqkbcnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNABQKBCNR w KQkq - 0 1",
+		DisplayName: "Capablanca",
+		PromotionFigures: CAPABLANCA_PROMOTION_FIGURES,
+		Files: 10,
 	},
+	{ // gothic, capablanca with a different setup
+		StartFen:    "rnbqckabnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNBQCKABNR w KQkq - 0 1",
+		DisplayName: "Gothic",
+		PromotionFigures: CAPABLANCA_PROMOTION_FIGURES,
+		Files: 10,
+	},
+	{ // embassy, capablanca with the king on the e file, castling moves the king by three squares
+		StartFen:    "rnbqkcabnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNBQKCABNR w KQkq - 0 1",
+		DisplayName: "Embassy",
+		PromotionFigures: CAPABLANCA_PROMOTION_FIGURES,
+		Files: 10,
+		CastlingFiles: [2]File{FileH, FileB},
+	},
+	{ // duck, the duck placed after each move blocks every piece, kings are captured instead of mated
+		StartFen:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		DisplayName: "Duck",
+		PromotionFigures: STANDARD_PROMOTION_FIGURES,
+		Duck: true,
+		Win: WinKingCapture,
+	},
+}
+
+// Rules returns the description of the variant of the state
+func (st *State) Rules() *VariantInfo{
+	return &VariantInfos[st.Variant]
 }
 
 var VARIANT_NAMES = make([]string, len(VariantInfos))
@@ -63,6 +193,9 @@ const (
 
 type CastlingRight struct{
 	CanCastle          bool
+	// rook file given explicitly in fen ( Shredder / X-FEN letter )
+	HasRookFile        bool
+	RookFile           File
 	RookOrigSq         Square
 	RookOrigPiece      Piece
 	BetweenOrigSquares []Square
@@ -105,6 +238,7 @@ type StackBuffEntry struct{
 	Move      Move
 	IsPv      bool
 	PvIndex   int
+	IsHash    bool
 	IsCapture bool
 	Mobility  Accum
 	SubTree   int		
@@ -133,6 +267,14 @@ func (sb StackBuff) Less(i, j int) bool{
 		return sb[j].PvIndex < sb[i].PvIndex
 	}
 
+	if sb[j].IsHash && (!sb[i].IsHash){
+		return true
+	}
+
+	if (!sb[j].IsHash) && sb[i].IsHash{
+		return false
+	}
+
 	if sb[j].IsCapture && (!sb[i].IsCapture){
 		return true
 	}
@@ -152,13 +294,27 @@ func (sb StackBuff) Less(i, j int) bool{
 	return sb[j].SubTree > sb[i].SubTree
 }
 
-func (st *State) SetStackBuff(pos *Position, moves []Move){
-	st.StackBuff = []StackBuffEntry{}
+// SearchStack records the move generation state of a search node, one entry per ply, kept apart from the board state
+type SearchStack struct{
+	StackPhase            int
+	StackBuff             StackBuff
+	StackPvMoves          [MAX_PV_MOVES]Move
+	StackHashMove         Move
+	StackReduceFrom       int
+	StackReduceDepth      int
+	StackReduceFactor     int
+	StackIgnoreMoves      []Move
+}
+
+func (ss *SearchStack) SetStackBuff(pos *Position, moves []Move){
+	st := pos.Current()
+
+	ss.StackBuff = []StackBuffEntry{}
 
 	for _, move := range moves{
 		isIgnoredMove := false
 
-		for _, testMove := range st.StackIgnoreMoves{
+		for _, testMove := range ss.StackIgnoreMoves{
 			if move == testMove{
 				isIgnoredMove = true
 				break
@@ -173,7 +329,7 @@ func (st *State) SetStackBuff(pos *Position, moves []Move){
 			isPv := false
 			pvIndex := 0
 
-			for i, testMove := range st.StackPvMoves{
+			for i, testMove := range ss.StackPvMoves{
 				if move == testMove{
 					isPv = true
 					pvIndex = i
@@ -181,18 +337,19 @@ func (st *State) SetStackBuff(pos *Position, moves []Move){
 				}
 			}
 
-			st.StackBuff = append(st.StackBuff, StackBuffEntry{			
+			ss.StackBuff = append(ss.StackBuff, StackBuffEntry{			
 				Move: move, 
 				IsPv: isPv,
 				PvIndex: pvIndex,
+				IsHash: move == ss.StackHashMove,
 				IsCapture: st.PieceAtSquare(move.ToSq()) != NoPiece,
-				Mobility: st.MobilityForPieceAtSquare(st.PieceAtSquare(move.FromSq()), move.ToSq()),
+				Mobility: st.MobilityForPieceAtSquare(st.MovingPiece(move), move.ToSq()),
 				SubTree: subTree,
 			})
 		}
 	}	
 	
-	sort.Sort(st.StackBuff)
+	sort.Sort(ss.StackBuff)
 }
 
 // State records the state of a position
@@ -216,57 +373,31 @@ type State struct {
 	Material              [ColorArraySize]Accum
 	Zobrist               uint64
 	KingInfos             [ColorArraySize]KingInfo
-	StackPhase            int
-	StackBuff             StackBuff
-	StackPvMoves          [MAX_PV_MOVES]Move
-	StackReduceFrom       int
-	StackReduceDepth      int
-	StackReduceFactor     int
 	LostCastlingForColor  [ColorArraySize]bool	
-	StackIgnoreMoves      []Move
+	// crazyhouse pieces in hand, counted by figure
+	Pockets               [ColorArraySize][FigureArraySize]int
+	// crazyhouse promoted pieces, they go to the pocket as pawns when captured
+	Promoted              Bitboard
+	// three-check checks given by color
+	Checks                [ColorArraySize]int
+	// the square of the duck in duck chess, empty before the duck is placed first
+	Duck                  Bitboard
 }
 
-func (st State) AddDeltaToSquare(sq Square, delta Delta) (Square, bool){
+func (st *State) AddDeltaToSquare(sq Square, delta Delta) (Square, bool){
 	rank := RankOf[sq]
 	file := FileOf[sq]
 	
 	newRank := rank + delta.dRank
 	newFile := file + delta.dFile
 
-	if newRank >= 0 && newRank < NUM_RANKS && newFile >= 0 && newFile < NUM_FILES{
+	if newRank >= 0 && newRank < NUM_RANKS && newFile >= 0 && newFile < File(st.Rules().Files){
 		return RankFile[newRank][newFile], true
 	}
 
 	return sq, false
 }
 
-// CastlingRights.String() reports castling rights in fen format
-func (crs CastlingRights) String() string {
-	buff := ""
-
-	if crs[White][CastlingSideKing].CanCastle {
-		buff += "K"
-	}
-
-	if crs[White][CastlingSideQueen].CanCastle {
-		buff += "Q"
-	}
-
-	if crs[Black][CastlingSideKing].CanCastle {
-		buff += "k"
-	}
-
-	if crs[Black][CastlingSideQueen].CanCastle {
-		buff += "q"
-	}
-
-	if buff == "" {
-		return "-"
-	}
-
-	return buff
-}
-
 // Color.String() converts a color to "b" for black, "w" for white and "-" for no color
 func (color Color) String() string {
 	if color == Black {
@@ -294,7 +425,7 @@ func (st *State) Reset(){
 
 // ParseFen sets up state from a fen
 func (st *State) ParseFen(fen string) error {
-	fenParts := strings.Split(fen, " ")
+	fenParts := st.ParseCheckCounters(strings.Split(fen, " "))
 
 	if len(fenParts) > 0 {
 		err := st.ParsePlacementString(fenParts[0])
@@ -308,7 +439,11 @@ func (st *State) ParseFen(fen string) error {
 	}
 
 	if len(fenParts) > 2 {
-		st.ParseCastlingRights(fenParts[2])
+		if st.Rules().NoCastling{
+			st.ParseCastlingRights("-")
+		}else{
+			st.ParseCastlingRights(fenParts[2])
+		}
 	}
 
 	if len(fenParts) > 3 {
@@ -327,9 +462,53 @@ func (st *State) ParseFen(fen string) error {
 		st.ParseDisabledMove(fenParts[6])
 	}
 
+	// the setters above update the key incrementally from the previous state, so it is computed from scratch
+	st.Zobrist = st.ComputeZobrist()
+
 	return nil
 }
 
+// ParseCheckCounters sets the check counters and returns the fen parts without them
+// the counters may be given anywhere after the placement as +N+M ( checks given ) or N+M ( checks remaining, lichess style )
+func (st *State) ParseCheckCounters(fenParts []string) []string{
+	st.Checks = [ColorArraySize]int{}
+
+	rest := []string{}
+
+	for i, part := range fenParts{
+		var white, black int
+
+		if i > 0 && strings.HasPrefix(part, "+"){
+			fmt.Sscanf(part, "+%d+%d", &white, &black)
+		} else if n, _ := fmt.Sscanf(part, "%d+%d", &white, &black); i > 0 && n == 2{
+			white = st.Rules().CheckLimit - white
+			black = st.Rules().CheckLimit - black
+		} else {
+			rest = append(rest, part)
+
+			continue
+		}
+
+		st.Checks[White] = st.clampCheckCount(white)
+		st.Checks[Black] = st.clampCheckCount(black)
+	}
+
+	return rest
+}
+
+// clampCheckCount limits a check counter to the checks the variant counts
+func (st *State) clampCheckCount(checks int) int{
+	if checks < 0{
+		return 0
+	}
+
+	if checks > st.Rules().CheckLimit{
+		return st.Rules().CheckLimit
+	}
+
+	return checks
+}
+
 func (st *State) ParseDisabledMove(dms string) {
 	st.HasDisabledMove = false
 
@@ -344,18 +523,23 @@ func (st *State) ParseDisabledMove(dms string) {
 	t := Tokenizer{}
 	t.Init(dms)
 
-	st.DisableFromSquare = t.GetSquare()
-	st.DisableToSquare = t.GetSquare()
+	fromSq := t.GetSquare()
+	toSq := t.GetSquare()
 
-	if st.DisableFromSquare != st.DisableToSquare {
-		st.HasDisabledMove = true
+	if fromSq != toSq {
+		st.SetDisabledMove(fromSq, toSq)
 	}
 }
 
 func (st *State) ParseEpSquare(epsqs string) {
 	t := Tokenizer{}
 	t.Init(epsqs)
-	st.SetEpSquare(t.GetSquare())
+	epsq := t.GetSquare()
+	if !st.IsEpCapturable(epsq) {
+		// like after a move, the ep square is only kept if an en passant capture is possible
+		epsq = SquareA1
+	}
+	st.SetEpSquare(epsq)
 }
 
 func (st *State) ParseHalfmoveClock(hmcs string) {
@@ -370,7 +554,7 @@ func (st *State) ParseFullmoveNumber(fmns string) {
 	st.FullmoveNumber = t.GetInt()
 }
 
-func (st State) CastlingRank(color Color) Rank{
+func (st *State) CastlingRank(color Color) Rank{
 	if color == White{
 		return Rank1
 	}
@@ -378,17 +562,18 @@ func (st State) CastlingRank(color Color) Rank{
 	return Rank8
 }
 
-func (st State) IsCastlingPartner(fig Figure) bool{
+func (st *State) IsCastlingPartner(fig Figure) bool{
 	return fig == Rook || fig == Jailer
 }
 
 // PopulateCastlingRights determines castling rights information based on piece placement
 // also does sanity check
+// without an explicit rook file the outermost castling partner is taken ( X-FEN rule )
 func (st *State) PopulateCastlingRights(crs CastlingRights) CastlingRights{
 	for color := Black; color <= White; color++{
 		wk := st.KingInfos[color].Square		
 		cRank := st.CastlingRank(color)
-		if RankOf[wk] != cRank{
+		if RankOf[wk] != cRank || FigureOf[st.PieceAtSquare(wk)] != King{
 			// king is on illegal rank, delete castling rights
 			crs[color][CastlingSideKing].CanCastle = false
 			crs[color][CastlingSideQueen].CanCastle = false
@@ -396,11 +581,12 @@ func (st *State) PopulateCastlingRights(crs CastlingRights) CastlingRights{
 			for side := CastlingSideKing; side <= CastlingSideQueen; side++{
 				dir := File(1 - (2 * side))
 				foundCastlingPartner := false
-				betweenSquares := []Square{}
-				for testFile := FileOf[wk]; testFile >= 0 && testFile < NUM_FILES; testFile += dir{					
-					testSq := RankFile[cRank][testFile]
+				for testFile := FileOf[wk] + dir; testFile >= 0 && testFile < NUM_FILES; testFile += dir{					
+					if crs[color][side].HasRookFile && testFile != crs[color][side].RookFile{
+						continue
+					}
 
-					betweenSquares = append(betweenSquares, testSq)
+					testSq := RankFile[cRank][testFile]
 
 					testP := st.PieceAtSquare(testSq)
 					testCol := ColorOf[testP]
@@ -409,10 +595,11 @@ func (st *State) PopulateCastlingRights(crs CastlingRights) CastlingRights{
 						foundCastlingPartner = true
 						crs[color][side].RookOrigSq = testSq
 						crs[color][side].RookOrigPiece = testP						
-						crs[color][side].BetweenOrigSquares = betweenSquares
 					}					
 				}
-				if !foundCastlingPartner{
+				if foundCastlingPartner{
+					crs[color][side].BetweenOrigSquares = st.CastlingBetweenSquares(color, side, wk, crs[color][side].RookOrigSq)
+				}else{
 					crs[color][side].CanCastle = false
 				}
 			}
@@ -422,6 +609,31 @@ func (st *State) PopulateCastlingRights(crs CastlingRights) CastlingRights{
 	return crs
 }
 
+// CastlingBetweenSquares returns the squares that have to be empty ( apart from king and rook ) for castling
+// these span king, rook and their target squares, which in chess960 may lie outside the king - rook segment
+func (st *State) CastlingBetweenSquares(color Color, side int, kingSq, rookSq Square) []Square{
+	cts := st.CastlingTargetSquares(color, side)
+
+	minFile, maxFile := FileOf[kingSq], FileOf[kingSq]
+
+	for _, sq := range []Square{rookSq, cts[0], cts[1]}{
+		if FileOf[sq] < minFile{
+			minFile = FileOf[sq]
+		}
+		if FileOf[sq] > maxFile{
+			maxFile = FileOf[sq]
+		}
+	}
+
+	betweenSquares := []Square{}
+
+	for file := minFile; file <= maxFile; file++{
+		betweenSquares = append(betweenSquares, RankFile[RankOf[kingSq]][file])
+	}
+
+	return betweenSquares
+}
+
 func (st *State) ParseCastlingRights(crs string) {
 	t := Tokenizer{}
 	t.Init(crs)
@@ -429,13 +641,64 @@ func (st *State) ParseCastlingRights(crs string) {
 	st.LostCastlingForColor[White] = false
 	st.LostCastlingForColor[Black] = false
 
-	newCastlingRights := t.GetCastlingRights()
+	kingFiles := [2]File{FileOf[st.KingInfos[Black].Square], FileOf[st.KingInfos[White].Square]}
+
+	newCastlingRights := t.GetCastlingRights(kingFiles)
 
 	populatedCastlingRights := st.PopulateCastlingRights(newCastlingRights)
 
 	st.SetCastlingAbility(populatedCastlingRights)
 }
 
+// IsOutermostCastlingPartner tells whether the castling partner is the outermost one on its side
+// if so, X-FEN reports castling right with K / Q, otherwise with the rook file letter
+func (st *State) IsOutermostCastlingPartner(color Color, side int) bool{
+	cr := st.CastlingRights[color][side]
+
+	dir := File(1 - (2 * side))
+
+	for testFile := FileOf[cr.RookOrigSq] + dir; testFile >= 0 && testFile < NUM_FILES; testFile += dir{
+		testP := st.PieceAtSquare(RankFile[RankOf[cr.RookOrigSq]][testFile])
+		if st.IsCastlingPartner(FigureOf[testP]) && ColorOf[testP] == color{
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReportCastlingRights reports castling rights in X-FEN format ( KQkq unless ambiguous )
+// or in Shredder-FEN format ( rook file letters, HAha ) if shredder is true
+func (st *State) ReportCastlingRights(shredder bool) string{
+	buff := ""
+
+	for _, color := range []Color{White, Black}{
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++{
+			if !st.CastlingRights[color][side].CanCastle{
+				continue
+			}
+
+			letter := "KQ"[side : side+1]
+
+			if shredder || !st.IsOutermostCastlingPartner(color, side){
+				letter = string(rune('A' + FileOf[st.CastlingRights[color][side].RookOrigSq]))
+			}
+
+			if color == Black{
+				letter = strings.ToLower(letter)
+			}
+
+			buff += letter
+		}
+	}
+
+	if buff == "" {
+		return "-"
+	}
+
+	return buff
+}
+
 func (st *State) ParseTurnString(ts string) {
 	t := Tokenizer{}
 	t.Init(ts)
@@ -470,6 +733,8 @@ func (st *State) GenMoveBuff() {
 	sort.Sort(st.MoveBuff)
 }
 
+// UciToMove converts an uci move to a legal move
+// castling is king takes rook ( e1h1 ), outside chess960 king to target square ( e1g1 ) is also accepted
 func (st *State) UciToMove(uci string) (Move, bool){
 	st.GenMoveBuff()
 	for _, mbi := range st.MoveBuff{
@@ -478,9 +743,27 @@ func (st *State) UciToMove(uci string) (Move, bool){
 		}
 	}
 
+	if !st.Rules().Chess960{
+		for _, mbi := range st.MoveBuff{
+			if mbi.Move.MoveType() == Castling && st.CastlingKingTargetUci(mbi.Move) + mbi.Move.DuckSuffix() == uci{
+				return mbi.Move, true
+			}
+		}
+	}
+
 	return Move(0), false
 }
 
+// CastlingKingTargetUci returns the castling move in king to target square notation
+func (st *State) CastlingKingTargetUci(move Move) string{
+	side := CastlingSideKing		
+	if FileOf[move.ToSq()] < FileOf[move.FromSq()]{
+		side = CastlingSideQueen
+	}
+
+	return move.FromSq().UCI() + st.CastlingTargetSquares(ColorOf[st.PieceAtSquare(move.FromSq())], side)[0].UCI()
+}
+
 func (mb MoveBuff) PrettyPrintString() string {
 	buff := ""
 
@@ -501,11 +784,11 @@ func (mb MoveBuff) PrettyPrintString() string {
 const MOBILITY_MULTIPLIER = 10
 const ATTACK_MULTIPLIER = 25
 
-func (st State) MobilityBalance() Accum{
+func (st *State) MobilityBalance() Accum{
 	return st.MobilityForColor(White).Sub(st.MobilityForColor(Black))
 }
 
-func (st State) MobilityPOV() Accum{
+func (st *State) MobilityPOV() Accum{
 	mobBal := st.MobilityBalance()
 
 	if st.Turn == White{
@@ -515,7 +798,7 @@ func (st State) MobilityPOV() Accum{
 	return mobBal.Mult(-1)
 }
 
-func (st State) MobilityForPieceAtSquare(p Piece, sq Square) Accum{
+func (st *State) MobilityForPieceAtSquare(p Piece, sq Square) Accum{
 	color := ColorOf[p]
 	mobility := Accum{}
 
@@ -540,14 +823,22 @@ func (st State) MobilityForPieceAtSquare(p Piece, sq Square) Accum{
 		case Queen:
 			mob = QueenMobility(Violent|Quiet, sq, occupUs, occupThem)
 			break
+		case Archbishop:
+			mob = ArchbishopMobility(Violent|Quiet, sq, occupUs, occupThem)
+			break
+		case Chancellor:
+			mob = ChancellorMobility(Violent|Quiet, sq, occupUs, occupThem)
+			break
 		case LancerN, LancerNE, LancerE, LancerSE, LancerS, LancerSW, LancerW, LancerNW:
 			mob = LancerMobility(Violent|Quiet, p.LancerDirection(), sq, occupUs, occupThem)
 			break
 		}			
+		mob = mob.And(st.Rules().Board)
 		oppKingSq := st.KingInfos[color.Inverse()].Square
-		attack := mob & KingArea[oppKingSq]
+		attack := mob.And(KingArea[oppKingSq])
+
 		attackCount := attack.Count()
-		if mob & oppKingSq.Bitboard() != 0{
+		if mob.Has(oppKingSq){
 			attackCount = 9
 		}
 		mobility.Merge(Accum{Score(mob.Count() * MOBILITY_MULTIPLIER), Score(attackCount * ATTACK_MULTIPLIER)})
@@ -556,7 +847,7 @@ func (st State) MobilityForPieceAtSquare(p Piece, sq Square) Accum{
 	return mobility
 }
 
-func (st State) MobilityForColor(color Color) Accum{	
+func (st *State) MobilityForColor(color Color) Accum{	
 	mobility := Accum{}
 	occupUs := st.ByColor[color]
 	for _, sq := range occupUs.PopAll(){
@@ -566,7 +857,7 @@ func (st State) MobilityForColor(color Color) Accum{
 	return mobility
 }
 
-func (st State) MaterialPOV() Accum {
+func (st *State) MaterialPOV() Accum {
 	if st.Turn == White {
 		return st.Material[NoColor]
 	}
@@ -574,11 +865,15 @@ func (st State) MaterialPOV() Accum {
 }
 
 // PrettyPrintString returns the state pretty print string
-func (st State) PrettyPrintString() string {
+func (st *State) PrettyPrintString() string {
 	buff := st.PrettyPlacementString()
 
 	buff += fmt.Sprintf("\n%s %s\n", VariantInfos[st.Variant].DisplayName, st.ReportFen())
 
+	if st.Rules().Chess960{
+		buff += fmt.Sprintf("Shredder-FEN %s\n", st.ReportShredderFen())
+	}
+
 	buff += fmt.Sprintf("\nMat White %v Black %v Balance %v POV %v Score %d\n", st.Material[White], st.Material[Black], st.Material[NoColor], st.MaterialPOV(), st.Score())
 
 	mobW := st.MobilityForColor(White)
@@ -593,14 +888,23 @@ func (st State) PrettyPrintString() string {
 	return buff
 }
 
-// ReportFen reports the state as a fen string
-func (st State) ReportFen() string {
+// ReportFen reports the state as a fen string, castling rights in X-FEN format
+func (st *State) ReportFen() string {
+	return st.ReportFenWithCastling(false)
+}
+
+// ReportShredderFen reports the state as a fen string, castling rights in Shredder-FEN format
+func (st *State) ReportShredderFen() string {
+	return st.ReportFenWithCastling(true)
+}
+
+func (st *State) ReportFenWithCastling(shredder bool) string {
 	buff := ""
 
 	cum := 0
 
 	for rank := LAST_RANK; rank >= 0; rank-- {
-		for file := 0; file < NUM_FILES; file++ {
+		for file := 0; file < st.Rules().Files; file++ {
 			p := st.Pieces[rank][file]
 
 			if p == NoPiece {
@@ -611,6 +915,9 @@ func (st State) ReportFen() string {
 				}
 				cum = 0
 				buff += p.FenSymbol()
+				if st.Promoted.Has(RankFile[rank][file]){
+					buff += "~"
+				}
 			}
 		}
 		if cum > 0 {
@@ -622,9 +929,13 @@ func (st State) ReportFen() string {
 		}
 	}
 
+	if st.Rules().Drops{
+		buff += "[" + st.ReportPocketString() + "]"
+	}
+
 	buff += " " + st.Turn.String()
 
-	buff += " " + st.CastlingRights.String()
+	buff += " " + st.ReportCastlingRights(shredder)
 
 	if st.EpSquare == SquareA1 {
 		buff += " -"
@@ -636,7 +947,7 @@ func (st State) ReportFen() string {
 
 	buff += " " + fmt.Sprintf("%d", st.FullmoveNumber)
 
-	if st.Variant == VariantEightPiece {
+	if st.Rules().FairyPieces {
 		if st.HasDisabledMove {
 			buff += " " + st.DisableFromSquare.UCI() + st.DisableToSquare.UCI()
 		} else {
@@ -645,15 +956,19 @@ func (st State) ReportFen() string {
 
 	}
 
+	if st.Rules().CheckLimit > 0 {
+		buff += fmt.Sprintf(" +%d+%d", st.Checks[White], st.Checks[Black])
+	}
+
 	return buff
 }
 
 // PrettyPlacementString returns the pretty string representation of the board
-func (st State) PrettyPlacementString() string {
+func (st *State) PrettyPlacementString() string {
 	buff := ""
 
 	for rank := LAST_RANK; rank >= 0; rank-- {
-		for file := 0; file < NUM_FILES; file++ {
+		for file := 0; file < st.Rules().Files; file++ {
 			buff += st.Pieces[rank][file].PrettySymbol()
 		}
 		if (rank == 0 && st.Turn == White) || (rank == LAST_RANK && st.Turn == Black) {
@@ -665,12 +980,72 @@ func (st State) PrettyPlacementString() string {
 	return buff
 }
 
+// SplitPocket splits the crazyhouse pocket off a placement string
+// the pocket is either in brackets after the placement or given as a ninth rank
+func SplitPocket(ps string) (string, string){
+	if i := strings.Index(ps, "["); i >= 0{
+		return ps[:i], strings.TrimSuffix(ps[i+1:], "]")
+	}
+
+	if strings.Count(ps, "/") == NUM_RANKS{
+		i := strings.LastIndex(ps, "/")
+
+		return ps[:i], ps[i+1:]
+	}
+
+	return ps, ""
+}
+
+// max number of pieces of a figure in a pocket
+const MAX_POCKET_COUNT = 16
+
+// ParsePocketString sets the pockets from the pieces listed in the pocket string, kings and unknown symbols are ignored
+func (st *State) ParsePocketString(pocket string){
+	st.Pockets = [ColorArraySize][FigureArraySize]int{}
+
+	for _, c := range pocket{
+		p, ok := SymbolToPiece[string(c)]
+
+		if !ok || FigureOf[p] < Pawn || FigureOf[p] > Queen{
+			continue
+		}
+
+		if st.Pockets[ColorOf[p]][FigureOf[p]] < MAX_POCKET_COUNT{
+			st.Pockets[ColorOf[p]][FigureOf[p]]++
+		}
+	}
+}
+
+// ReportPocketString lists the pieces in the pockets, white pieces first
+func (st *State) ReportPocketString() string{
+	buff := ""
+
+	for _, color := range []Color{White, Black}{
+		for fig := Queen; fig >= Pawn; fig--{
+			buff += strings.Repeat(ColorFigure[color][fig].FenSymbol(), st.Pockets[color][fig])
+		}
+	}
+
+	return buff
+}
+
 // ParsePlacementString parses a placement string and sets pieces accordingly
+// a crazyhouse pocket is parsed as well, pieces followed by ~ are promoted
 // returns an error if there are not enough pieces to fill the board
 // liberal otherwise
 func (st *State) ParsePlacementString(ps string) error {
+	placement, pocket := SplitPocket(ps)
+
+	st.ParsePocketString(pocket)
+
+	st.Promoted = BbEmpty
+
+	// the files beyond the board of the variant stay empty
+	st.Pieces = [NUM_RANKS][NUM_FILES]Piece{}
+	st.Duck = BbEmpty
+
 	t := Tokenizer{}
-	t.Init(ps)
+	t.Init(placement)
 
 	rank := LAST_RANK
 	file := 0
@@ -690,8 +1065,12 @@ func (st *State) ParsePlacementString(ps string) error {
 				sq := RankFile[rank][file]
 				st.Pieces[rank][file] = NoPiece
 				st.Put(p, sq)
+				if p != NoPiece && strings.HasPrefix(t.Content, "~"){
+					t.Content = t.Content[1:]
+					st.Promoted = st.Promoted.Or(sq.Bitboard())
+				}
 				file++
-				if file > LAST_FILE {
+				if file >= st.Rules().Files {
 					file = 0
 					rank--
 				}
@@ -709,15 +1088,28 @@ func (st *State) ParsePlacementString(ps string) error {
 	return fmt.Errorf("too few pieces in placement string")
 }
 
-func (st State) PieceAtSquare(sq Square) Piece {
+func (st *State) PieceAtSquare(sq Square) Piece {
 	return st.Pieces[RankOf[sq]][FileOf[sq]]
 }
 
-func (st State) IsCapture(move Move) bool {
+// MovingPiece returns the piece the move puts on its target square, the dropped piece for drops
+func (st *State) MovingPiece(move Move) Piece{
+	if move.MoveType() == Drop{
+		return move.PromotionPiece()
+	}
+
+	return st.PieceAtSquare(move.FromSq())
+}
+
+func (st *State) IsCapture(move Move) bool {
 	return st.PieceAtSquare(move.ToSq()) != NoPiece || (FigureOf[st.PieceAtSquare(move.FromSq())] == Pawn && move.ToSq() == st.EpSquare)
 }
 
-func (st State) MoveLAN(move Move) string {
+func (st *State) MoveLAN(move Move) string {
+	if move.MoveType() == Drop{
+		return move.UCI()
+	}
+
 	fromPiece := st.PieceAtSquare(move.FromSq())
 
 	buff := fromPiece.SanLetter() + move.FromSq().UCI()
@@ -736,12 +1128,13 @@ func (st State) MoveLAN(move Move) string {
 		buff += "=" + move.PromotionPiece().SanSymbol() + "@" + move.PromotionSquare().UCI()
 	}
 
-	return buff
+	return buff + move.DuckSuffix()
 }
 
 func (st *State) CalculateOccupancyAndMaterial() {
 	st.ByFigure = [FigureArraySize]Bitboard{}
 	st.ByColor = [ColorArraySize]Bitboard{}
+	st.ByLancer = BbEmpty
 
 	st.Material[White] = Accum{}
 	st.Material[Black] = Accum{}
@@ -751,34 +1144,41 @@ func (st *State) CalculateOccupancyAndMaterial() {
 
 		p := st.PieceAtSquare(sq)
 
-		if p != NoPiece {
+		// the duck belongs to neither side
+		if p != NoPiece && p != DummyPiece {
 			fig := FigureOf[p]
 
 			col := ColorOf[p]
 
-			st.ByFigure[fig] |= bb
-			st.ByColor[col] |= bb
+			st.ByFigure[fig] = st.ByFigure[fig].Or(bb)
+			st.ByColor[col] = st.ByColor[col].Or(bb)
 
 			if p.IsLancer(){
-				st.ByLancer |= bb
+				st.ByLancer = st.ByLancer.Or(bb)
 			}
 
 			p := ColorFigure[col][fig]
 
-			mat := GetMaterialForPieceAtSquare(p, sq)
+			mat := st.PieceMaterial(p, sq)
 
 			st.Material[col].Merge(mat)
 		}
 	}
 
+	for color := Black; color <= White; color++{
+		for fig := Pawn; fig <= Queen; fig++{
+			st.Material[color].Merge(st.Rules().PocketValues[fig].Mult(Score(st.Pockets[color][fig])))
+		}
+	}
+
 	st.Material[NoColor] = st.Material[White].Sub(st.Material[Black])
 }
 
-func (st State) OccupUs() Bitboard {
+func (st *State) OccupUs() Bitboard {
 	return st.ByColor[st.Turn]
 }
 
-func (st State) OccupThem() Bitboard {
+func (st *State) OccupThem() Bitboard {
 	return st.ByColor[st.Turn.Inverse()]
 }
 
@@ -788,7 +1188,7 @@ func (st *State) MoveToSan(move Move) string {
 	return st.MoveToSanBatch(move)
 }
 
-func (st State) MoveToSanBatch(move Move) string {
+func (st *State) MoveToSanBatch(move Move) string {
 	p := st.PieceAtSquare(move.FromSq())
 
 	sanLetter := p.SanLetter()
@@ -855,35 +1255,60 @@ func (st State) MoveToSanBatch(move Move) string {
 
 	check := ""
 
-	newSt := st
+	undo := Undo{}
 
-	newSt.MakeMove(move)
+	st.MakeMove(move, &undo)
 
-	if newSt.IsCheckedUs() {
+	if end, result, _ := st.VariantEnd(); end {
+		// variant wins are marked like mates, variant draws are not marked
+		if result != 0 {
+			check = "#"
+		}
+	} else if st.Rules().StalemateWins() && !st.HasLegalMove() {
+		check = "#"
+	} else if st.IsCheckedUs() {
 		check = "+"
 
-		if !newSt.HasLegalMove() {
+		if !st.HasLegalMove() {
 			check = "#"
 		}
-	} else if !newSt.HasLegalMove() {
+	} else if !st.HasLegalMove() {
 		check = "="
 	}
 
+	st.UnmakeMove(&undo)
+
+	if move.MoveType() == Drop{
+		return move.UCI() + check
+	}
+
+	duck := move.DuckSuffix()
+
 	if move.MoveType() == Castling{
 		if FileOf[move.FromSq()] < FileOf[move.ToSq()]{
-			return "O-O" + check
+			return "O-O" + duck + check
 		}else{
-			return "O-O-O" + check
+			return "O-O-O" + duck + check
 		}
 	}
 
-	return sanLetter + orig + takes + dest + prom + check
+	return sanLetter + orig + takes + dest + prom + duck + check
 }
 
-func (st State) IsChecked(color Color) bool{
+func (st *State) IsChecked(color Color) bool{
+	if st.Rules().Win == WinAntichess || st.Rules().Win == WinKingCapture{
+		// there is no check in antichess and duck chess
+		return false
+	}
+
+	if st.Rules().Win == WinHorde && color == White{
+		// the horde has no king
+		return false
+	}
+
 	checked := st.IsCheckedSansExplosion(color)
 
-	if st.Variant != VariantAtomic{
+	if !st.Rules().Explosions{
 		return checked
 	}
 
@@ -900,7 +1325,7 @@ func (st State) IsChecked(color Color) bool{
 	return checked
 }
 
-func (st State) IsCheckedSansExplosion(color Color) bool {
+func (st *State) IsCheckedSansExplosion(color Color) bool {
 	if st.KingInfos[color].IsCaptured {
 		return true
 	}
@@ -921,11 +1346,11 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 				return true
 			}
 			_, isBishopDirection := NormalizedBishopDirection(wk, sq)
-			if fig == Bishop && isBishopDirection{
+			if ( fig == Bishop || fig == Archbishop ) && isBishopDirection{
 				return true
 			}
 			_, isRookDirection := NormalizedRookDirection(wk, sq)
-			if fig == Rook && isRookDirection{
+			if ( fig == Rook || fig == Chancellor ) && isRookDirection{
 				return true
 			}
 		}
@@ -943,7 +1368,7 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 	na := KnightAttack[wk]
 
 	// knight check
-	themKnights := st.ByColor[color.Inverse()] & st.ByFigure[Knight] & na
+	themKnights := st.ByColor[color.Inverse()].And(st.KnightJumpers()).And(na)
 	for _, sq := range themKnights.PopAll() {
 		if !st.IsSquareJailedForColor(sq, color.Inverse()){
 			return true
@@ -953,7 +1378,7 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 	ka := KingAttack[wk]
 
 	// king check
-	themKings := st.ByColor[color.Inverse()] & st.ByFigure[King] & ka
+	themKings := st.ByColor[color.Inverse()].And(st.ByFigure[King]).And(ka)
 	for _, sq := range themKings.PopAll() {
 		if !st.IsSquareJailedForColor(sq, color.Inverse()){
 			return true
@@ -961,7 +1386,7 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 	}
 
 	// lancer check
-	themLancers := st.ByColor[color.Inverse()] & st.ByLancer
+	themLancers := st.ByColor[color.Inverse()].And(st.ByLancer)
 	for _, sq := range themLancers.PopAll(){
 		ms := st.PslmsForPieceAtSquare(Violent, st.PieceAtSquare(sq), sq, st.ByColor[color.Inverse()], st.ByColor[color], color.Inverse())
 		for _, move := range ms{
@@ -972,7 +1397,7 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 	}
 
 	// sentry check
-	themSentries := st.ByColor[color.Inverse()] & st.ByFigure[Sentry]
+	themSentries := st.ByColor[color.Inverse()].And(st.ByFigure[Sentry])
 	for _, sq := range themSentries.PopAll() {		
 		sentryMoves := st.GenSentryMoves(Violent, color.Inverse(), sq, st.ByColor[color.Inverse()], st.ByColor[color], color)
 		for _, sm := range sentryMoves{
@@ -985,15 +1410,15 @@ func (st State) IsCheckedSansExplosion(color Color) bool {
 	return false
 }
 
-func (st State) IsCheckedUs() bool {
+func (st *State) IsCheckedUs() bool {
 	return st.IsChecked(st.Turn)
 }
 
-func (st State) IsCheckedThem() bool {
+func (st *State) IsCheckedThem() bool {
 	return st.IsChecked(st.Turn.Inverse())
 }
 
-func (st State) KingsAdjacent() bool{
+func (st *State) KingsAdjacent() bool{
 	for col := Black; col <= White; col++{
 		if st.KingInfos[col].IsCaptured{
 			// captured king cannot be adjacent
@@ -1003,5 +1428,6 @@ func (st State) KingsAdjacent() bool{
 
 	attack := KingAttack[st.KingInfos[White].Square]
 
-	return attack & st.KingInfos[Black].Square.Bitboard() != 0
+	return attack.Has(st.KingInfos[Black].Square)
+
 }
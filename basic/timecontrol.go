@@ -0,0 +1,172 @@
+package basic
+
+import (
+	"fmt"
+	"time"
+)
+
+// SearchLimits holds the limits of a search as given by the go command
+type SearchLimits struct {
+	WTime     int
+	BTime     int
+	WInc      int
+	BInc      int
+	MovesToGo int
+	MoveTime  int
+	Nodes     int
+	Mate      int
+	Infinite  bool
+}
+
+const DEFAULT_MOVES_TO_GO = 30
+const DEFAULT_MOVE_OVERHEAD = 30
+
+// check limits every that many nodes
+const CHECK_LIMITS_NODES_MASK = 1023
+
+func (sl SearchLimits) String() string {
+	return fmt.Sprintf("wtime %d btime %d winc %d binc %d movestogo %d movetime %d nodes %d mate %d infinite %v", sl.WTime, sl.BTime, sl.WInc, sl.BInc, sl.MovesToGo, sl.MoveTime, sl.Nodes, sl.Mate, sl.Infinite)
+}
+
+// TimeAndInc tells the remaining time and the increment of color
+func (sl SearchLimits) TimeAndInc(color Color) (int, int) {
+	if color == White {
+		return sl.WTime, sl.WInc
+	}
+
+	return sl.BTime, sl.BInc
+}
+
+// HasTimeLimit tells whether the search is limited in time for the side to move
+func (sl SearchLimits) HasTimeLimit(color Color) bool {
+	if sl.Infinite {
+		return false
+	}
+
+	if sl.MoveTime > 0 {
+		return true
+	}
+
+	time, _ := sl.TimeAndInc(color)
+
+	return time > 0
+}
+
+// AllocateTime sets the soft and hard time limit of the search
+// iterative deepening does not start a new iteration after the soft limit
+// the search is stopped at the hard limit
+func (pos *Position) AllocateTime() {
+	pos.SoftTimeMs = 0
+	pos.HardTimeMs = 0
+
+	color := pos.Current().Turn
+
+	if !pos.Limits.HasTimeLimit(color) {
+		return
+	}
+
+	overhead := pos.MoveOverhead
+
+	if pos.Limits.MoveTime > 0 {
+		pos.SoftTimeMs = pos.Limits.MoveTime - overhead
+		pos.HardTimeMs = pos.SoftTimeMs
+	} else {
+		time, inc := pos.Limits.TimeAndInc(color)
+
+		movesToGo := pos.Limits.MovesToGo
+
+		if movesToGo <= 0 {
+			movesToGo = DEFAULT_MOVES_TO_GO
+		}
+
+		available := time - overhead
+
+		pos.SoftTimeMs = available/movesToGo + inc*3/4
+		pos.HardTimeMs = pos.SoftTimeMs * 4
+
+		if movesToGo == 1 {
+			// last move before time control, may use most of the time
+			pos.SoftTimeMs = available * 3 / 4
+			pos.HardTimeMs = available * 3 / 4
+		}
+
+		if pos.HardTimeMs > available/2 && movesToGo > 1 {
+			pos.HardTimeMs = available / 2
+		}
+
+		if pos.SoftTimeMs > pos.HardTimeMs {
+			pos.SoftTimeMs = pos.HardTimeMs
+		}
+	}
+
+	if pos.SoftTimeMs < 1 {
+		pos.SoftTimeMs = 1
+	}
+
+	if pos.HardTimeMs < 1 {
+		pos.HardTimeMs = 1
+	}
+}
+
+// CheckLimits stops the search if the hard time limit or the node limit is exceeded
+func (pos *Position) CheckLimits() {
+	nodes := pos.Nodes + pos.QNodes
+
+	if nodes&CHECK_LIMITS_NODES_MASK != 0 {
+		return
+	}
+
+	if pos.HelperIndex > 0 {
+		// helpers are stopped by the main searcher
+		pos.CheckHelper()
+		return
+	}
+
+	select {
+	case <-pos.StopSignal:
+		pos.SearchStopped = true
+	default:
+	}
+
+	if pos.Limits.Nodes > 0 && pos.TotalNodes()+pos.TotalQNodes() >= pos.Limits.Nodes {
+		pos.SearchStopped = true
+	}
+
+	if pos.HardTimeMs > 0 && pos.TimeMs() >= pos.HardTimeMs {
+		pos.SearchStopped = true
+	}
+}
+
+// IterationAllowed tells whether a new iteration of iterative deepening can be started
+func (pos *Position) IterationAllowed() bool {
+	if pos.Limits.Nodes > 0 && pos.TotalNodes()+pos.TotalQNodes() >= pos.Limits.Nodes {
+		return false
+	}
+
+	if pos.Limits.Mate > 0 {
+		score := pos.OldMultiPvInfos[0].Score
+
+		if score > MAX_SCORE && int(MATE_SCORE-score+1)/2 <= pos.Limits.Mate {
+			// found mate within the requested number of moves
+			return false
+		}
+	}
+
+	if pos.SoftTimeMs > 0 {
+		// next iteration is likely to take longer than all previous ones together
+		return pos.TimeMs() < pos.SoftTimeMs/2
+	}
+
+	return true
+}
+
+// Throttle lets other goroutines run in single threaded environments during long searches without time limit
+func (pos *Position) Throttle() {
+	if pos.HardTimeMs > 0 || pos.HelperIndex > 0 {
+		return
+	}
+
+	if int(pos.CheckTime())%20 == 1 {
+		time.Sleep(time.Second)
+	}
+}
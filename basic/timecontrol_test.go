@@ -0,0 +1,155 @@
+package basic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllocateTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		turn   Color
+		limits SearchLimits
+		soft   int
+		hard   int
+	}{
+		{"wtime", White, SearchLimits{WTime: 60000, BTime: 1000}, 1999, 7996},
+		{"btime", Black, SearchLimits{WTime: 60000, BTime: 1000}, 32, 128},
+		{"winc", White, SearchLimits{WTime: 60000, WInc: 2000, BInc: 5000}, 3499, 13996},
+		{"binc", Black, SearchLimits{BTime: 60000, WInc: 5000, BInc: 2000}, 3499, 13996},
+		{"movestogo", White, SearchLimits{WTime: 10000, MovesToGo: 10}, 997, 3988},
+		{"last move before time control", White, SearchLimits{WTime: 10000, MovesToGo: 1}, 7477, 7477},
+		// the hard limit is capped at half the clock, the soft limit follows
+		{"movestogo with inc", White, SearchLimits{WTime: 1000, WInc: 1000, MovesToGo: 2}, 485, 485},
+		{"movetime", White, SearchLimits{MoveTime: 5000, WTime: 100}, 4970, 4970},
+		// clocks below the move overhead still get a budget
+		{"movetime below overhead", White, SearchLimits{MoveTime: 10}, 1, 1},
+		{"low clock", White, SearchLimits{WTime: 20, WInc: 0}, 1, 1},
+		{"one ms", Black, SearchLimits{WTime: 60000, BTime: 1}, 1, 1},
+		{"low clock last move", White, SearchLimits{WTime: 40, MovesToGo: 1}, 7, 7},
+		// no time limit
+		{"nodes", White, SearchLimits{Nodes: 1000}, 0, 0},
+		{"mate", White, SearchLimits{Mate: 3}, 0, 0},
+		{"infinite", White, SearchLimits{WTime: 60000, Infinite: true}, 0, 0},
+		{"other side only", White, SearchLimits{BTime: 60000}, 0, 0},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(VariantStandard)
+		pos.Current().Turn = test.turn
+		pos.MoveOverhead = DEFAULT_MOVE_OVERHEAD
+		pos.Limits = test.limits
+
+		pos.AllocateTime()
+
+		if pos.SoftTimeMs != test.soft || pos.HardTimeMs != test.hard {
+			t.Errorf("%s : expected soft %d hard %d, got soft %d hard %d", test.name, test.soft, test.hard, pos.SoftTimeMs, pos.HardTimeMs)
+		}
+
+		if test.limits.HasTimeLimit(test.turn) && (pos.SoftTimeMs < 1 || pos.SoftTimeMs > pos.HardTimeMs) {
+			t.Errorf("%s : expected 0 < soft <= hard, got soft %d hard %d", test.name, pos.SoftTimeMs, pos.HardTimeMs)
+		}
+	}
+}
+
+func TestAllocateTimeOrder(t *testing.T) {
+	for _, clock := range []int{0, 1, 29, 30, 31, 100, 1000, 60000} {
+		for _, inc := range []int{0, 1, 100, 5000} {
+			for _, movesToGo := range []int{0, 1, 2, 40} {
+				pos := Position{}
+				pos.Init(VariantStandard)
+				pos.MoveOverhead = DEFAULT_MOVE_OVERHEAD
+				pos.Limits = SearchLimits{WTime: clock + 1, WInc: inc, MovesToGo: movesToGo}
+
+				pos.AllocateTime()
+
+				if pos.SoftTimeMs < 1 || pos.SoftTimeMs > pos.HardTimeMs {
+					t.Errorf("%v : expected 0 < soft <= hard, got soft %d hard %d", pos.Limits, pos.SoftTimeMs, pos.HardTimeMs)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     int
+		limits    SearchLimits
+		hardMs    int
+		elapsedMs int
+		stop      bool
+		stopped   bool
+	}{
+		{"no limits", 1024, SearchLimits{}, 0, 1000, false, false},
+		{"nodes reached", 1024, SearchLimits{Nodes: 1000}, 0, 0, false, true},
+		{"nodes left", 2048, SearchLimits{Nodes: 5000}, 0, 0, false, false},
+		// limits are checked every CHECK_LIMITS_NODES_MASK + 1 nodes only
+		{"between checks", 1025, SearchLimits{Nodes: 1000}, 0, 0, false, false},
+		{"hard time", 1024, SearchLimits{WTime: 1000}, 50, 200, false, true},
+		{"within hard time", 1024, SearchLimits{WTime: 1000}, 5000, 0, false, false},
+		{"stop signal", 1024, SearchLimits{Infinite: true}, 0, 0, true, true},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(VariantStandard)
+		pos.Nodes = test.nodes
+		pos.Limits = test.limits
+		pos.HardTimeMs = test.hardMs
+		pos.Start = time.Now().Add(-time.Duration(test.elapsedMs) * time.Millisecond)
+
+		stop := make(chan struct{})
+
+		if test.stop {
+			close(stop)
+		}
+
+		pos.StopSignal = stop
+
+		pos.CheckLimits()
+
+		if pos.SearchStopped != test.stopped {
+			t.Errorf("%s : expected stopped %v, got %v", test.name, test.stopped, pos.SearchStopped)
+		}
+	}
+}
+
+func TestIterationAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     int
+		limits    SearchLimits
+		score     Score
+		softMs    int
+		elapsedMs int
+		allowed   bool
+	}{
+		{"no limits", 100000, SearchLimits{}, 0, 0, 1000, true},
+		{"nodes reached", 1000, SearchLimits{Nodes: 1000}, 0, 0, 0, false},
+		{"nodes left", 999, SearchLimits{Nodes: 1000}, 0, 0, 0, true},
+		// mate in 3 found
+		{"mate found", 0, SearchLimits{Mate: 3}, MATE_SCORE - 5, 0, 0, false},
+		{"mate found sooner", 0, SearchLimits{Mate: 4}, MATE_SCORE - 5, 0, 0, false},
+		{"mate too long", 0, SearchLimits{Mate: 2}, MATE_SCORE - 5, 0, 0, true},
+		{"mated", 0, SearchLimits{Mate: 3}, -MATE_SCORE + 4, 0, 0, true},
+		// the next iteration would not end before the soft limit
+		{"half soft time used", 0, SearchLimits{WTime: 60000}, 0, 100, 60, false},
+		{"soft time left", 0, SearchLimits{WTime: 60000}, 0, 1000, 0, true},
+	}
+
+	for _, test := range tests {
+		pos := Position{}
+		pos.Init(VariantStandard)
+		pos.Nodes = test.nodes
+		pos.Limits = test.limits
+		pos.OldMultiPvInfos[0].Score = test.score
+		pos.SoftTimeMs = test.softMs
+		pos.Start = time.Now().Add(-time.Duration(test.elapsedMs) * time.Millisecond)
+
+		if allowed := pos.IterationAllowed(); allowed != test.allowed {
+			t.Errorf("%s : expected allowed %v, got %v", test.name, test.allowed, allowed)
+		}
+	}
+}
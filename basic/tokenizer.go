@@ -66,7 +66,7 @@ func (t *Tokenizer) GetSquare() Square {
 		c := t.Content[0]
 
 		if parseFile {
-			if c >= 'a' && c <= 'z' {
+			if c >= 'a' && c < 'a'+NUM_FILES {
 				file = int(c - 'a')
 				t.Content = t.Content[1:]
 				parseFile = false
@@ -74,7 +74,7 @@ func (t *Tokenizer) GetSquare() Square {
 				return RankFile[rank][file]
 			}
 		} else {
-			if c >= '1' && c <= '9' {
+			if c >= '1' && c < '1'+NUM_RANKS {
 				rank = int(c - '1')
 				t.Content = t.Content[1:]
 			}
@@ -101,7 +101,9 @@ func (t *Tokenizer) GetInt() int {
 	}
 }
 
-func (t *Tokenizer) GetCastlingRights() CastlingRights {
+// GetCastlingRights parses castling rights in KQkq, Shredder ( HAha ) or X-FEN format
+// king files are needed to tell the side of a rook file letter
+func (t *Tokenizer) GetCastlingRights(kingFiles [2]File) CastlingRights {
 	ccrs := [2]ColorCastlingRights{}
 
 	for {
@@ -123,6 +125,21 @@ func (t *Tokenizer) GetCastlingRights() CastlingRights {
 		} else if c == 'q' {
 			ccrs[Black][CastlingSideQueen].CanCastle = true
 			t.Content = t.Content[1:]
+		} else if ( c >= 'A' && c < 'A'+NUM_FILES ) || ( c >= 'a' && c < 'a'+NUM_FILES ) {
+			color := White
+			file := File(c - 'A')
+			if c >= 'a'{
+				color = Black
+				file = File(c - 'a')
+			}
+			side := CastlingSideKing
+			if file < kingFiles[color]{
+				side = CastlingSideQueen
+			}
+			ccrs[color][side].CanCastle = true
+			ccrs[color][side].HasRookFile = true
+			ccrs[color][side].RookFile = file
+			t.Content = t.Content[1:]
 		} else {
 			return ccrs
 		}
@@ -165,9 +182,14 @@ func (t *Tokenizer) GetFenPiece() []Piece {
 	c = t.Content[0]
 
 	if c >= '0' && c <= '9' {
+		// boards wider than 9 files may have two digit empty counts
+		return make([]Piece, t.GetInt())
+	}
+
+	if c == DUCK_SYMBOL[0] {
 		t.Content = t.Content[1:]
 
-		return make([]Piece, c-'0')
+		return []Piece{DummyPiece}
 	}
 
 	if c == 'l' || c == 'L' {
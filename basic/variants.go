@@ -0,0 +1,496 @@
+package basic
+
+// variant rules and variants.ini, a configuration file in the spirit of Fairy-Stockfish variants.ini
+// each section defines a variant, derived from a built in or an earlier defined variant, standard if none given
+//
+// [Five-check:Three-check]
+// checkCounting = 5
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+type WinCondition int
+
+const (
+	// the side to move without legal moves loses if in check, otherwise it is stalemate
+	WinCheckmate = WinCondition(iota)
+	// captures are compulsory, kings are not royal, the side without pieces or legal moves wins
+	WinAntichess
+	// white has no king and loses when all its pieces are captured, white pawns may push by two from the first rank
+	WinHorde
+	// giving check is illegal, the first king on the eighth rank wins, unless black follows on the next move
+	WinRace
+	// there is no check, the side whose king is captured loses, the side without legal moves wins
+	WinKingCapture
+)
+
+var WIN_CONDITION_NAMES = []string{"checkmate", "antichess", "horde", "race", "kingCapture"}
+
+// StalemateWins tells whether the side to move without legal moves wins
+func (info *VariantInfo) StalemateWins() bool{
+	return info.Win == WinAntichess || info.Win == WinKingCapture
+}
+
+// max number of checks a variant can count
+const MAX_CHECK_LIMIT = 9
+
+// FIGURE_VALUES are the values the default material tables are built from
+var FIGURE_VALUES = [FigureArraySize]Accum{
+	Pawn:     PAWN_VALUE,
+	Knight:   KNIGHT_VALUE,
+	Bishop:   BISHOP_VALUE,
+	Rook:     ROOK_VALUE,
+	Queen:    QUEEN_VALUE,
+	LancerN:  LANCER_VALUE,
+	LancerNE: LANCER_VALUE,
+	LancerE:  LANCER_VALUE,
+	LancerSE: LANCER_VALUE,
+	LancerS:  LANCER_VALUE,
+	LancerSW: LANCER_VALUE,
+	LancerW:  LANCER_VALUE,
+	LancerNW: LANCER_VALUE,
+	Sentry:   SENTRY_VALUE,
+	Jailer:   JAILER_VALUE,
+	Archbishop: ARCHBISHOP_VALUE,
+	Chancellor: CHANCELLOR_VALUE,
+}
+
+// Setup derives the distances to the flag region and the material tables of the piece values
+// it has to be called after the default material tables are initialized
+func (info *VariantInfo) Setup(){
+	if info.Files == 0{
+		info.Files = STANDARD_NUM_FILES
+	}
+
+	if info.CastlingFiles == [2]File{}{
+		// the king castles to the file next to the last one on the king side and to the c file on the queen side
+		info.CastlingFiles = [2]File{File(info.Files - 2), FileC}
+	}
+
+	info.Board = BbEmpty
+	for file := 0; file < info.Files; file++{
+		info.Board = info.Board.Or(BbFiles[file])
+	}
+
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++{
+		info.FlagDistance[sq] = NUM_FILES
+
+		flags := info.FlagRegion
+
+		for flags != BbEmpty{
+			flagSq := flags.Pop()
+
+			dist := int(RankOf[sq] - RankOf[flagSq])
+			if dist < 0{
+				dist = -dist
+			}
+
+			fileDist := int(FileOf[sq] - FileOf[flagSq])
+			if fileDist < 0{
+				fileDist = -fileDist
+			}
+
+			if fileDist > dist{
+				dist = fileDist
+			}
+
+			if dist < info.FlagDistance[sq]{
+				info.FlagDistance[sq] = dist
+			}
+		}
+	}
+
+	info.MaterialTables = nil
+	info.PocketValues = POCKET_VALUES
+
+	// the square bonuses depend on the width of the board
+	tables := PieceMaterialTables
+	if info.Files != STANDARD_NUM_FILES{
+		tables = NewPieceMaterialTables(info.Files)
+		info.MaterialTables = &tables
+	}
+
+	if info.PieceValues == nil{
+		return
+	}
+
+	// the tables are shifted by the difference of the values, keeping the square bonuses
+
+
+	for p := PieceMinValue; p <= PieceMaxValue; p++{
+		fig := FigureOf[p]
+
+		diff := info.PieceValues[fig].Sub(FIGURE_VALUES[fig])
+
+		for sq := SquareMinValue; sq <= SquareMaxValue; sq++{
+			tables[p][sq].Merge(diff)
+		}
+	}
+
+	info.MaterialTables = &tables
+
+	for fig := Pawn; fig <= Queen; fig++{
+		value := info.PieceValues[fig]
+		value.Merge(IN_HAND_BONUS)
+		info.PocketValues[fig] = value
+	}
+}
+
+// PieceMaterial returns the material of a piece on a square with the piece values of the variant
+func (st *State) PieceMaterial(p Piece, sq Square) Accum{
+	if tables := VariantInfos[st.Variant].MaterialTables; tables != nil{
+		return tables[p][sq]
+	}
+
+	return PieceMaterialTables[p][sq]
+}
+
+// LookupVariant finds a variant by display name, case insensitive, or by pgn alias
+func LookupVariant(name string) (Variant, bool){
+	for i, vinfo := range VariantInfos{
+		if strings.EqualFold(vinfo.DisplayName, name){
+			return Variant(i), true
+		}
+	}
+
+	variant, ok := PGN_VARIANT_ALIASES[strings.ToLower(name)]
+
+	return variant, ok
+}
+
+// RegisterVariant adds a variant to VariantInfos and VARIANT_NAMES, the start position has to use only the pieces of the variant
+func RegisterVariant(info VariantInfo) (Variant, error){
+	if len(VariantInfos) >= MAX_VARIANTS{
+		return VariantStandard, fmt.Errorf("too many variants, at most %d are allowed", MAX_VARIANTS)
+	}
+
+	if _, exists := LookupVariant(info.DisplayName); exists || info.DisplayName == ""{
+		return VariantStandard, fmt.Errorf("variant name %q is empty or taken", info.DisplayName)
+	}
+
+	info.Setup()
+
+	VariantInfos = append(VariantInfos, info)
+
+	variant := Variant(len(VariantInfos) - 1)
+
+	if err := variant.checkStartFen(); err != nil{
+		VariantInfos = VariantInfos[:variant]
+
+		return VariantStandard, err
+	}
+
+	VARIANT_NAMES = append(VARIANT_NAMES, info.DisplayName)
+
+	return variant, nil
+}
+
+func (v Variant) checkStartFen() error{
+	info := VariantInfos[v]
+
+	st := State{}
+	st.Variant = v
+
+	if err := st.ParseFen(info.StartFen); err != nil{
+		return fmt.Errorf("start fen %q : %v", info.StartFen, err)
+	}
+
+	if info.Figures == nil{
+		return nil
+	}
+
+	for fig := Pawn; fig <= FigureMaxValue; fig++{
+		if st.ByFigure[fig] == BbEmpty{
+			continue
+		}
+
+		setFig := fig
+		if fig >= LancerMinValue && fig <= LancerMaxValue{
+			setFig = Lancer
+		}
+
+		found := false
+
+		for _, allowed := range info.Figures{
+			found = found || allowed == setFig
+		}
+
+		if !found{
+			return fmt.Errorf("start fen %q has %s, which is not in the piece set", info.StartFen, SymbolOf[fig])
+		}
+	}
+
+	return nil
+}
+
+// ParseFigureLetters parses figure letters like qrbn, l stands for the lancers of all directions if expandLancers is set
+func ParseFigureLetters(letters string, expandLancers bool) ([]Figure, error){
+	figs := []Figure{}
+
+	for _, c := range strings.ToLower(letters){
+		if c == ' ' || c == ','{
+			continue
+		}
+
+		fig := NoFigure
+
+		for f := Pawn; f <= FigureMaxValue; f++{
+			if SymbolOf[f] == string(c){
+				fig = f
+			}
+		}
+
+		if fig == NoFigure{
+			return figs, fmt.Errorf("unknown piece letter %c", c)
+		}
+
+		if fig == Lancer && expandLancers{
+			for l := LancerMinValue; l <= LancerMaxValue; l++{
+				figs = append(figs, l)
+			}
+
+			continue
+		}
+
+		figs = append(figs, fig)
+	}
+
+	return figs, nil
+}
+
+// parseIniSquare parses a square like e4
+func parseIniSquare(sqs string) (Square, error){
+	if len(sqs) != 2 || sqs[0] < 'a' || sqs[0] >= 'a' + NUM_FILES || sqs[1] < '1' || sqs[1] >= '1' + NUM_RANKS{
+		return SquareA1, fmt.Errorf("invalid square %s", sqs)
+	}
+
+	return RankFile[sqs[1] - '1'][sqs[0] - 'a'], nil
+}
+
+// SetIniKey sets a rule of the variant from a key = value line of variants.ini
+func (info *VariantInfo) SetIniKey(key, value string) error{
+	var err error
+
+	switch key{
+	case "startFen":
+		info.StartFen = value
+	case "pieces":
+		if info.Figures, err = ParseFigureLetters(value, false); err != nil{
+			return err
+		}
+
+		info.FairyPieces = false
+
+		for _, fig := range info.Figures{
+			info.FairyPieces = info.FairyPieces || fig == Lancer || fig == Sentry || fig == Jailer
+		}
+	case "promotionPieces":
+		if info.PromotionFigures, err = ParseFigureLetters(value, true); err != nil{
+			return err
+		}
+
+		for _, fig := range info.PromotionFigures{
+			if fig == Pawn{
+				return fmt.Errorf("pawns can not be promoted to")
+			}
+		}
+	case "castling":
+		switch value{
+		case "standard":
+			info.Chess960, info.NoCastling = false, false
+		case "chess960":
+			info.Chess960, info.NoCastling = true, false
+		case "none":
+			info.Chess960, info.NoCastling = false, true
+		default:
+			return fmt.Errorf("castling should be standard, chess960 or none, got %s", value)
+		}
+	case "maxFile":
+		if len(value) != 1 || value[0] < 'a' + STANDARD_NUM_FILES - 1 || value[0] >= 'a' + NUM_FILES{
+			return fmt.Errorf("maxFile should be between %c and %c, got %s", 'a' + STANDARD_NUM_FILES - 1, 'a' + NUM_FILES - 1, value)
+		}
+
+		info.Files = int(value[0] - 'a') + 1
+		// the castling files of the parent may not fit the new width
+		info.CastlingFiles = [2]File{}
+	case "castlingKingsideFile", "castlingQueensideFile":
+		if len(value) != 1 || value[0] < 'a' || value[0] >= 'a' + NUM_FILES{
+			return fmt.Errorf("%s should be a file letter, got %s", key, value)
+		}
+
+		if info.CastlingFiles == [2]File{}{
+			info.CastlingFiles = [2]File{File(info.Files - 2), FileC}
+		}
+
+		if key == "castlingKingsideFile"{
+			info.CastlingFiles[CastlingSideKing] = File(value[0] - 'a')
+		}else{
+			info.CastlingFiles[CastlingSideQueen] = File(value[0] - 'a')
+		}
+	case "blastOnCapture":
+
+		info.Explosions, err = strconv.ParseBool(value)
+	case "pieceDrops":
+		info.Drops, err = strconv.ParseBool(value)
+	case "duckPlacement":
+		info.Duck, err = strconv.ParseBool(value)
+	case "checkCounting":
+		info.CheckLimit, err = strconv.Atoi(value)
+
+		if err == nil && ( info.CheckLimit < 0 || info.CheckLimit > MAX_CHECK_LIMIT ){
+			return fmt.Errorf("checkCounting should be between 0 and %d", MAX_CHECK_LIMIT)
+		}
+	case "flagRegion":
+		info.FlagRegion = BbEmpty
+
+		for _, sqs := range strings.Fields(value){
+			sq, err := parseIniSquare(sqs)
+			if err != nil{
+				return err
+			}
+
+			info.FlagRegion = info.FlagRegion.Or(sq.Bitboard())
+		}
+	case "winCondition":
+		for i, name := range WIN_CONDITION_NAMES{
+			if name == value{
+				info.Win = WinCondition(i)
+
+				return nil
+			}
+		}
+
+		return fmt.Errorf("winCondition should be one of %s, got %s", strings.Join(WIN_CONDITION_NAMES, ", "), value)
+	case "pieceValueMg", "pieceValueEg":
+		// the values of the parent are kept for figures not listed
+		values := FIGURE_VALUES
+		if info.PieceValues != nil{
+			values = *info.PieceValues
+		}
+
+		for _, item := range strings.Fields(value){
+			parts := strings.Split(item, ":")
+
+			if len(parts) != 2{
+				return fmt.Errorf("piece value should be letter:value, got %s", item)
+			}
+
+			figs, err := ParseFigureLetters(parts[0], true)
+			if err != nil{
+				return err
+			}
+
+			score, err := strconv.Atoi(parts[1])
+			if err != nil{
+				return err
+			}
+
+			for _, fig := range figs{
+				if key == "pieceValueMg"{
+					values[fig].M = Score(score)
+				}else{
+					values[fig].E = Score(score)
+				}
+			}
+		}
+
+		info.PieceValues = &values
+	default:
+		return fmt.Errorf("unknown key %s", key)
+	}
+
+	return err
+}
+
+// ParseVariantsIni registers the variants defined by the content of a variants.ini file and returns their number
+// lines starting with # or ; are comments, a section [Name] or [Name:Parent] starts a variant, followed by key = value lines
+func ParseVariantsIni(content string) (int, error){
+	count := 0
+
+	var current *VariantInfo
+
+	register := func() error{
+		if current == nil{
+			return nil
+		}
+
+		if _, err := RegisterVariant(*current); err != nil{
+			return fmt.Errorf("variant %s : %v", current.DisplayName, err)
+		}
+
+		count++
+
+		return nil
+	}
+
+	for i, rawLine := range strings.Split(content, "\n"){
+		line := strings.TrimSpace(rawLine)
+
+		if line == "" || line[0] == '#' || line[0] == ';'{
+			continue
+		}
+
+		if line[0] == '['{
+			if err := register(); err != nil{
+				return count, err
+			}
+
+			if !strings.HasSuffix(line, "]"){
+				return count, fmt.Errorf("line %d : unterminated section", i + 1)
+			}
+
+			nameParts := strings.SplitN(line[1:len(line) - 1], ":", 2)
+
+			parent := VariantStandard
+
+			if len(nameParts) > 1{
+				var ok bool
+
+				if parent, ok = LookupVariant(strings.TrimSpace(nameParts[1])); !ok{
+					return count, fmt.Errorf("line %d : unknown parent variant %s", i + 1, nameParts[1])
+				}
+			}
+
+			info := VariantInfos[parent]
+			info.DisplayName = strings.TrimSpace(nameParts[0])
+
+			current = &info
+
+			continue
+		}
+
+		if current == nil{
+			return count, fmt.Errorf("line %d : key outside of a section", i + 1)
+		}
+
+		keyValue := strings.SplitN(line, "=", 2)
+
+		if len(keyValue) != 2{
+			return count, fmt.Errorf("line %d : expected key = value", i + 1)
+		}
+
+		if err := current.SetIniKey(strings.TrimSpace(keyValue[0]), strings.TrimSpace(keyValue[1])); err != nil{
+			return count, fmt.Errorf("line %d : %v", i + 1, err)
+		}
+	}
+
+	if err := register(); err != nil{
+		return count, err
+	}
+
+	return count, nil
+}
+
+// LoadVariantsIni registers the variants defined in a variants.ini file and returns their number
+func LoadVariantsIni(path string) (int, error){
+	content, err := ioutil.ReadFile(path)
+	if err != nil{
+		return 0, err
+	}
+
+	return ParseVariantsIni(string(content))
+}
@@ -0,0 +1,86 @@
+package basic
+
+import (
+	"testing"
+)
+
+// restoreVariants removes the variants registered by a test
+func restoreVariants(n int) {
+	VariantInfos = VariantInfos[:n]
+	VARIANT_NAMES = VARIANT_NAMES[:n]
+}
+
+func TestVariantsIni(t *testing.T) {
+	defer restoreVariants(len(VariantInfos))
+
+	count, err := ParseVariantsIni(`
+# comment
+[Five-check:three-check]
+checkCounting = 5
+
+; no parent, derived from standard
+[Heavy Queens]
+promotionPieces = qn
+castling = none
+pieceValueMg = q:1000
+pieceValueEg = q:1100
+`)
+
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 variants, got %d, error %v", count, err)
+	}
+
+	fiveCheck, ok := LookupVariant("five-check")
+	if !ok || VariantInfos[fiveCheck].CheckLimit != 5 {
+		t.Fatalf("five-check not registered with 5 checks")
+	}
+
+	pos := Position{}
+	pos.Init(fiveCheck)
+	pos.ParseFen("4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +4+0")
+
+	move, _ := pos.Current().UciToMove("a1a8")
+	pos.Push(move)
+
+	if result, reason := pos.GameResult(); result != "1-0" || reason != "5 checks" {
+		t.Errorf("expected 1-0 5 checks, got %s %s", result, reason)
+	}
+
+	heavy, _ := LookupVariant("Heavy Queens")
+
+	if figs := VariantInfos[heavy].PromotionFigures; len(figs) != 2 || figs[0] != Queen || figs[1] != Knight {
+		t.Errorf("expected promotion to queen and knight, got %v", figs)
+	}
+
+	standard := State{}
+	standard.Init(VariantStandard)
+
+	st := State{}
+	st.Init(heavy)
+
+	if diff := st.Material[White].Sub(standard.Material[White]); diff != (Accum{100, 180}) {
+		t.Errorf("expected queen value difference M 100 E 180, got %v", diff)
+	}
+
+	if rights := st.ReportCastlingRights(false); rights != "-" {
+		t.Errorf("castling rights %s kept in a variant without castling", rights)
+	}
+}
+
+func TestVariantsIniErrors(t *testing.T) {
+	defer restoreVariants(len(VariantInfos))
+
+	for _, content := range []string{
+		"checkCounting = 5",
+		"[Bad Key]\nfoo = bar",
+		"[Orphan:No Such Variant]",
+		"[Standard]",
+		"[No Queens]\npieces = pnbrk",
+		"[Bad Region]\nflagRegion = e9",
+		"[Bad Win]\nwinCondition = stalemate",
+	} {
+		if _, err := ParseVariantsIni(content); err == nil {
+			t.Errorf("%q : expected an error", content)
+		}
+	}
+}
@@ -11,33 +11,33 @@ func GenAttackSquares(sq Square, normFunc func(Square, Square) (Delta, bool)) ([
 	middle := BbFull
 
 	if RankOf[sq] == 0 {
-		middle &^= BbRank8
+		middle = middle.AndNot(BbRank8)
 	}
 
 	if RankOf[sq] == LAST_RANK {
-		middle &^= BbRank1
+		middle = middle.AndNot(BbRank1)
 	}
 
 	if FileOf[sq] == 0 {
-		middle &^= BbFileH
+		middle = middle.AndNot(BbFileLast)
 	}
 
 	if FileOf[sq] == LAST_FILE {
-		middle &^= BbFileA
+		middle = middle.AndNot(BbFileA)
 	}
 
 	if RankOf[sq] >= 1 && RankOf[sq] <= (LAST_RANK-1) {
-		middle &^= BbRank1
-		middle &^= BbRank8
+		middle = middle.AndNot(BbRank1)
+		middle = middle.AndNot(BbRank8)
 	}
 
 	if FileOf[sq] >= 1 && FileOf[sq] <= (LAST_FILE-1) {
-		middle &^= BbFileA
-		middle &^= BbFileH
+		middle = middle.AndNot(BbFileA)
+		middle = middle.AndNot(BbFileLast)
 	}
 
-	if (sq.Bitboard() &^ BbBorder) != 0 {
-		middle = ^BbBorder
+	if sq.Bitboard().AndNot(BbBorder) != BbEmpty {
+		middle = BbBorder.Not()
 	}
 
 	middle = BbFull
@@ -50,7 +50,7 @@ func GenAttackSquares(sq Square, normFunc func(Square, Square) (Delta, bool)) ([
 		_, ok := normFunc(sq, testSq)
 		if ok {
 			sqs = append(sqs, testSq)
-			bb |= testSq.Bitboard()
+			bb = bb.Or(testSq.Bitboard())
 		}
 	}
 
@@ -61,7 +61,7 @@ func Translate(sqs []Square, occup uint64) Bitboard {
 	bb := BbEmpty
 	for i := 0; i < len(sqs); i++ {
 		if occup&1 != 0 {
-			bb |= sqs[i].Bitboard()
+			bb = bb.Or(sqs[i].Bitboard())
 		}
 		occup >>= 1
 	}
@@ -70,13 +70,15 @@ func Translate(sqs []Square, occup uint64) Bitboard {
 
 var Rand = rand.New(rand.NewSource(1))
 
+// randMagic returns a sparse random number, all bits have to be possible, as the highest square of a mask may be on bit 63
 func randMagic() uint64 {
-	r := uint64(Rand.Int63())
-	r &= uint64(Rand.Int63())
-	r &= uint64(Rand.Int63())
-	return r << 1
+	r := Rand.Uint64()
+	r &= Rand.Uint64()
+	r &= Rand.Uint64()
+	return r
 }
 
+
 func SlidingAttack(sq Square, deltas []Delta, occup Bitboard) (Bitboard, []Square) {
 	bb := BbEmpty
 
@@ -93,14 +95,14 @@ func SlidingAttack(sq Square, deltas []Delta, occup Bitboard) (Bitboard, []Squar
 		for rank >= 0 && rank < NUM_RANKS && file >= 0 && file < NUM_FILES && ok {
 			testSq = RankFile[rank][file]
 
-			bb |= testSq.Bitboard()
+			bb = bb.Or(testSq.Bitboard())
 
 			sqs = append(sqs, testSq)
 
 			rank += delta.dRank
 			file += delta.dFile
 
-			if (testSq.Bitboard() & occup) != 0 {
+			if occup.Has(testSq) {
 				ok = false
 			}
 		}
@@ -109,41 +111,73 @@ func SlidingAttack(sq Square, deltas []Delta, occup Bitboard) (Bitboard, []Squar
 	return bb, sqs
 }
 
-func SearchMagic(sq Square, sqs []Square, deltas []Delta, tries int) (int, uint64, bool, int) {
+// MagicMask returns the squares whose occupancy decides the sliding attack, the last square of each ray does not matter
+func MagicMask(sq Square, deltas []Delta) (Bitboard, []Square) {
+	bb := BbEmpty
+
+	sqs := []Square{}
+
+	for _, delta := range deltas {
+		rank := RankOf[sq] + delta.dRank
+		file := FileOf[sq] + delta.dFile
+
+		for rank+delta.dRank >= 0 && rank+delta.dRank < NUM_RANKS && file+delta.dFile >= 0 && file+delta.dFile < NUM_FILES {
+			testSq := RankFile[rank][file]
+
+			bb = bb.Or(testSq.Bitboard())
+
+			sqs = append(sqs, testSq)
+
+			rank += delta.dRank
+			file += delta.dFile
+		}
+	}
+
+	return bb, sqs
+}
+
+// SearchMagic searches magics with decreasing shift, starting with one bit per mask square, until tries run out
+func SearchMagic(sq Square, sqs []Square, deltas []Delta, tries int) (int, uint64, uint64, bool, int) {
 	Rand = rand.New(rand.NewSource(1))
 
-	var enum uint64
-	var lastGoodMagic uint64
+	// occupancies of the mask squares and their attacks
+	occups := make([]Bitboard, 1<<len(sqs))
+	attacks := make([]Bitboard, 1<<len(sqs))
+
+	for enum := range occups {
+		occups[enum] = Translate(sqs, uint64(enum))
+		attacks[enum], _ = SlidingAttack(sq, deltas, occups[enum])
+	}
+
+	var lastGoodMagic, lastGoodMagicHi uint64
 	var lastGoodShift int
 	foundMagic := false
 	nodes := 0
-	for shift := 22; shift > 6; shift-- {
+	for shift := len(sqs); shift > 0; shift-- {
 		found := false
-		for loop := 0; loop < tries; loop++ {
+		// entries are stored with the number of the try, so that they need not be cleared
+		entries := make([]Bitboard, 1<<shift)
+		tried := make([]int, 1<<shift)
+		for loop := 1; loop <= tries; loop++ {
 			nodes++
-			magic := randMagic() >> 6 //+ uint64(64-shift)<<58
-			hash := make(map[uint64]Bitboard)
+			msq := MagicSquare{Shift: shift, Magic: randMagic(), MagicHi: randMagic()}
 			coll := 0
-			for enum = 0; enum < 1<<len(sqs); enum++ {
-				trb := Translate(sqs, enum)
-				mobility, _ := SlidingAttack(sq, deltas, trb)
-				key := (magic * uint64(trb)) >> (64 - shift)
-				storedMobility, foundKey := hash[key]
-				if foundKey {
-					if storedMobility != mobility {
+			for enum, occup := range occups {
+				key := msq.Key(occup)
+				if tried[key] == loop {
+					if entries[key] != attacks[enum] {
 						coll++
 						break
 					}
 				} else {
-					hash[key] = mobility
+					tried[key] = loop
+					entries[key] = attacks[enum]
 				}
-				//fmt.Println(Translate(sqs, enum))
-				//fmt.Println()
 			}
 			if coll == 0 {
-				//fmt.Println(shift, loop, magic, coll)
 				foundMagic = true
-				lastGoodMagic = magic
+				lastGoodMagic = msq.Magic
+				lastGoodMagicHi = msq.MagicHi
 				lastGoodShift = shift
 				found = true
 				break
@@ -154,10 +188,10 @@ func SearchMagic(sq Square, sqs []Square, deltas []Delta, tries int) (int, uint6
 		}
 	}
 	if foundMagic {
-		return lastGoodShift, lastGoodMagic, true, nodes
+		return lastGoodShift, lastGoodMagic, lastGoodMagicHi, true, nodes
 	}
 
-	return 0, 0, false, nodes
+	return 0, 0, 0, false, nodes
 }
 
 var BISHOP_DELTAS = []Delta{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
@@ -177,13 +211,13 @@ var Wizards = []Wizard{
 	{
 		Name:   "bishop",
 		Deltas: BISHOP_DELTAS,
-		Tries:  50000,
+		Tries:  10000000,
 		Magics: BISHOP_MAGICS,
 	},
 	{
 		Name:   "rook",
 		Deltas: ROOK_DELTAS,
-		Tries:  50000,
+		Tries:  10000000,
 		Magics: ROOK_MAGICS,
 	},
 }
@@ -191,94 +225,106 @@ var Wizards = []Wizard{
 const BISHOP_WIZARD_INDEX = 0
 const ROOK_WIZARD_INDEX = 1
 
+// GenAttacks searches the magics of the wizard, its output is collected in magics.txt, from which gen.js generates magics.go
 func (wiz *Wizard) GenAttacks() {
-	fmt.Println("generating attacks for", wiz.Name)
+	DefaultOutput.Println("generating attacks for " + wiz.Name)
 	maxShift := 0
 	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
-		_, sqs := SlidingAttack(sq, wiz.Deltas, BbEmpty)
-		//fmt.Println(bb)
-		shift, magic, ok, nodes := SearchMagic(sq, sqs, wiz.Deltas, wiz.Tries)
+		_, sqs := MagicMask(sq, wiz.Deltas)
+		shift, magic, magicHi, ok, nodes := SearchMagic(sq, sqs, wiz.Deltas, wiz.Tries)
 		if shift > maxShift {
 			maxShift = shift
 		}
 		if ok {
-			fmt.Printf("found %-6s magic for %v %2d shift %2d max shift %2d magic %016x nodes %6d sqs %2d\n", wiz.Name, sq, sq, shift, maxShift, magic, nodes, len(sqs))
+			DefaultOutput.Println(fmt.Sprintf("found %-6s magic for %v %2d shift %2d max shift %2d magic %016x %016x nodes %6d sqs %2d", wiz.Name, sq, sq, shift, maxShift, magic, magicHi, nodes, len(sqs)))
 		} else {
-			fmt.Println("failed", wiz.Name, "at", sq)
+			DefaultOutput.Println(fmt.Sprintf("failed %s at %v", wiz.Name, sq))
 			break
 		}
 	}
-	fmt.Println("max shift for", wiz.Name, "=", maxShift)
+	DefaultOutput.Println(fmt.Sprintf("max shift for %s = %d", wiz.Name, maxShift))
 }
 
 var TotalMagicEntries int = 0
 
+// Key returns the entry index of an occupancy of the mask squares
 func (msq MagicSquare) Key(occup Bitboard) uint64 {
-	return (msq.Magic * uint64(occup)) >> (64 - msq.Shift)
+	return (msq.Magic*occup.Lo ^ msq.MagicHi*occup.Hi) >> (64 - msq.Shift)
 }
 
 func (ms *Magics) Attack(sq Square, occup Bitboard) Bitboard {
-	msq := ms[sq]
-	return msq.Entries[msq.Key(occup)]
+	msq := &ms[sq]
+	return msq.Entries[msq.Key(occup.And(msq.Mask))]
 }
 
 func BishopMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
-	attack := Wizards[BISHOP_WIZARD_INDEX].Magics.Attack(sq, (occupUs|occupThem)&BishopAttack[sq])
+	attack := Wizards[BISHOP_WIZARD_INDEX].Magics.Attack(sq, occupUs.Or(occupThem))
 	if !kind.IsViolent() {
-		attack &^= occupThem
+		attack = attack.AndNot(occupThem)
 	}
 	if !kind.IsQuiet(){
-		attack &= occupThem
+		attack = attack.And(occupThem)
 	}
-	return attack &^ occupUs
+	return attack.AndNot(occupUs)
 }
 
 func RookMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
-	attack := Wizards[ROOK_WIZARD_INDEX].Magics.Attack(sq, (occupUs|occupThem)&RookAttack[sq])
+	attack := Wizards[ROOK_WIZARD_INDEX].Magics.Attack(sq, occupUs.Or(occupThem))
 	if !kind.IsViolent() {
-		attack &^= occupThem
+		attack = attack.AndNot(occupThem)
 	}
 	if !kind.IsQuiet(){
-		attack &= occupThem
+		attack = attack.And(occupThem)
 	}
-	return attack &^ occupUs
+	return attack.AndNot(occupUs)
 }
 
 func QueenMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
-	return BishopMobility(kind, sq, occupUs, occupThem) | RookMobility(kind, sq, occupUs, occupThem)
+	return BishopMobility(kind, sq, occupUs, occupThem).Or(RookMobility(kind, sq, occupUs, occupThem))
+}
+
+// ArchbishopMobility is the mobility of a piece moving as a bishop or a knight
+func ArchbishopMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
+	return BishopMobility(kind, sq, occupUs, occupThem).Or(KnightMobility(kind, sq, occupUs, occupThem))
+}
+
+// ChancellorMobility is the mobility of a piece moving as a rook or a knight
+func ChancellorMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
+	return RookMobility(kind, sq, occupUs, occupThem).Or(KnightMobility(kind, sq, occupUs, occupThem))
 }
 
-func LancerMobility(kind MoveKind, ld int, sq Square, occupUs, occupThem Bitboard) Bitboard {
-	attack := QueenMobility(kind, sq, BbEmpty, occupThem) & LancerAttack[sq][ld]
+func LancerMobility(
+kind MoveKind, ld int, sq Square, occupUs, occupThem Bitboard) Bitboard {
+	attack := QueenMobility(kind, sq, BbEmpty, occupThem).And(LancerAttack[sq][ld])
 	if !kind.IsViolent() {
-		attack &^= occupThem
+		attack = attack.AndNot(occupThem)
 	}
 	if !kind.IsQuiet(){
-		attack &= occupThem
+		attack = attack.And(occupThem)
 	}
-	return attack &^ occupUs
+	return attack.AndNot(occupUs)
 }
 
 func KnightMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
 	attack := KnightAttack[sq]
 	if !kind.IsViolent() {
-		attack &^= occupThem
+		attack = attack.AndNot(occupThem)
 	}
 	if !kind.IsQuiet(){
-		attack &= occupThem
+		attack = attack.And(occupThem)
 	}
-	return attack &^ occupUs
+	return attack.AndNot(occupUs)
 }
 
 func KingMobility(kind MoveKind, sq Square, occupUs, occupThem Bitboard) Bitboard {
 	attack := KingAttack[sq]
 	if !kind.IsViolent() {
-		attack &^= occupThem
+		attack = attack.AndNot(occupThem)
 	}
 	if !kind.IsQuiet(){
-		attack &= occupThem
+		attack = attack.And(occupThem)
 	}
-	return attack &^ occupUs
+	return attack.AndNot(occupUs)
 }
 
 var BishopAttack [BOARD_AREA]Bitboard
@@ -297,8 +343,8 @@ func JumpAttack(sq Square, deltas []Delta) Bitboard {
 		rank := RankOf[sq] + delta.dRank
 		file := FileOf[sq] + delta.dFile
 
-		if rank >= 0 && rank < NUM_FILES && file >= 0 && file < NUM_FILES {
-			bb |= RankFile[rank][file].Bitboard()
+		if rank >= 0 && rank < NUM_RANKS && file >= 0 && file < NUM_FILES {
+			bb = bb.Or(RankFile[rank][file].Bitboard())
 		}
 	}
 
@@ -336,7 +382,8 @@ func init() {
 			size := 1 << msq.Shift
 			Wizards[wi].Magics[i].Entries = make([]Bitboard, size)
 			TotalMagicEntries += size
-			_, sqs := SlidingAttack(msq.Square, Wizards[wi].Deltas, BbEmpty)
+			mask, sqs := MagicMask(msq.Square, Wizards[wi].Deltas)
+			Wizards[wi].Magics[i].Mask = mask
 			var enum uint64
 			for enum = 0; enum < 1<<len(sqs); enum++ {
 				trb := Translate(sqs, enum)
@@ -347,12 +394,13 @@ func init() {
 				Wizards[wi].Magics[i].Entries[key] = mobility
 			}
 
+
 			if wi == BISHOP_WIZARD_INDEX {
 				BishopAttack[msq.Square] = BishopMobility(Violent|Quiet, msq.Square, BbEmpty, BbEmpty)
 
 				KnightAttack[msq.Square] = JumpAttack(msq.Square, KNIGHT_DELTAS)
 				KingAttack[msq.Square] = JumpAttack(msq.Square, KING_DELTAS)
-				KingArea[msq.Square] = KingAttack[msq.Square] | msq.Square.Bitboard()
+				KingArea[msq.Square] = KingAttack[msq.Square].Or(msq.Square.Bitboard())
 
 				JailerAdjacent[msq.Square] = JumpAttack(msq.Square, ROOK_DELTAS)
 
@@ -425,8 +473,11 @@ func init() {
 				PawnInfos[msq.Square] = cpi
 			} else if wi == ROOK_WIZARD_INDEX {
 				RookAttack[msq.Square] = RookMobility(Violent|Quiet, msq.Square, BbEmpty, BbEmpty)
-				QueenAttack[msq.Square] = BishopAttack[msq.Square] | RookAttack[msq.Square]
+				QueenAttack[msq.Square] = BishopAttack[msq.Square].Or(RookAttack[msq.Square])
+
 			}
 		}
 	}
+
+	initBetween()
 }
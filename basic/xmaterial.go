@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 )
 
 const INITIAL_MATERIAL = 2 * 4220
@@ -18,6 +19,39 @@ var (
 	zobristEnpassant [BOARD_AREA]uint64
 	zobristCastle    [CastleArraySize]uint64
 	zobristColor     [ColorArraySize]uint64
+	// the Eightpiece disabled move is hashed by its from and to squares
+	zobristDisableFrom [BOARD_AREA]uint64
+	zobristDisableTo   [BOARD_AREA]uint64
+	// the variant is hashed, so that hash tables can not mix up positions of different variants
+	zobristVariant   [MAX_VARIANTS]uint64
+	// crazyhouse pockets are hashed by the number of pieces of each figure, 0 pieces have key 0
+	zobristPocket    [ColorArraySize][FigureArraySize][MAX_POCKET_COUNT + 1]uint64
+	zobristPromoted  [BOARD_AREA]uint64
+	// check counters, no checks have key 0
+	zobristChecks    [ColorArraySize][MAX_CHECK_LIMIT + 1]uint64
+)
+
+// upper bound of the number of variants for zobrist keys
+const MAX_VARIANTS = 32
+
+// VerifyHash turns on recomputing the zobrist key from scratch after every move, for debugging
+var VerifyHash = false
+
+// number of zobrist key mismatches found while verifying hash
+var HashMismatches int64
+
+// the polyglot* arrays contain the keys of the polyglot book format, taken from polyglotRandom
+// they differ from the zobrist* keys, so the polyglot key is computed from scratch when probing the book
+const POLYGLOT_RANDOM_SIZE = 781
+
+// polyglot keys are for the 8x8 board, squares on further files have no key
+const POLYGLOT_FILES = 8
+
+var (
+	polyglotPiece     [PieceArraySize][BOARD_AREA]uint64
+	polyglotEnpassant [POLYGLOT_FILES]uint64
+	polyglotCastle    [CastleArraySize]uint64
+	polyglotTurn      uint64
 )
 
 // Zobrist returns the zobrist key of the position, never returns 0
@@ -77,17 +111,160 @@ func (st *State) SetSideToMove(color Color) {
 
 // SetEnpassantSquare sets the en passant square correctly updating the Zobrist key
 func (st *State) SetEpSquare(epsq Square) {
-
-	// in polyglot the hash key for en passant is updated only if
-	// an en passant capture is possible next move; in other words
-	// if there is an enemy pawn next to the end square of the move
-	// TODO
-
 	st.Zobrist ^= zobristEnpassant[st.EpSquare]
 	st.EpSquare = epsq
 	st.Zobrist ^= zobristEnpassant[st.EpSquare]
 }
 
+// IsEpCapturable tells whether a pawn of the side to move stands next to the pawn that passed the en passant square
+// the en passant square is only set and hashed if so, like in polyglot
+func (st *State) IsEpCapturable(epsq Square) bool {
+	if epsq == SquareA1 {
+		return false
+	}
+
+	pawnRank := RankOf[epsq] - 1
+	if st.Turn == Black {
+		pawnRank = RankOf[epsq] + 1
+	}
+
+	if pawnRank < 0 || pawnRank > LAST_RANK {
+		return false
+	}
+
+	epFile := FileOf[epsq]
+
+	for _, file := range []File{epFile - 1, epFile + 1} {
+		if file >= 0 && file < NUM_FILES && st.Pieces[pawnRank][file] == ColorFigure[st.Turn][Pawn] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetDisabledMove sets the Eightpiece disabled move, correctly updating the Zobrist key
+func (st *State) SetDisabledMove(fromSq, toSq Square) {
+	st.ClearDisabledMove()
+
+	st.DisableFromSquare = fromSq
+	st.DisableToSquare = toSq
+	st.HasDisabledMove = true
+
+	st.Zobrist ^= zobristDisableFrom[fromSq] ^ zobristDisableTo[toSq]
+}
+
+// ClearDisabledMove deletes the Eightpiece disabled move, correctly updating the Zobrist key
+func (st *State) ClearDisabledMove() {
+	if st.HasDisabledMove {
+		st.Zobrist ^= zobristDisableFrom[st.DisableFromSquare] ^ zobristDisableTo[st.DisableToSquare]
+	}
+
+	st.HasDisabledMove = false
+}
+
+// AddToPocket puts a piece into the crazyhouse pocket of its color, correctly updating material and the Zobrist key
+func (st *State) AddToPocket(p Piece) {
+	color := ColorOf[p]
+	fig := FigureOf[p]
+
+	st.Zobrist ^= zobristPocket[color][fig][st.Pockets[color][fig]]
+	st.Pockets[color][fig]++
+	st.Zobrist ^= zobristPocket[color][fig][st.Pockets[color][fig]]
+
+	st.Material[color].Merge(st.Rules().PocketValues[fig])
+	st.UpdateMaterialBalance()
+}
+
+// RemoveFromPocket takes a piece from the crazyhouse pocket of its color, correctly updating material and the Zobrist key
+func (st *State) RemoveFromPocket(p Piece) {
+	color := ColorOf[p]
+	fig := FigureOf[p]
+
+	st.Zobrist ^= zobristPocket[color][fig][st.Pockets[color][fig]]
+	st.Pockets[color][fig]--
+	st.Zobrist ^= zobristPocket[color][fig][st.Pockets[color][fig]]
+
+	st.Material[color].UnMerge(st.Rules().PocketValues[fig])
+	st.UpdateMaterialBalance()
+}
+
+// TogglePromoted flips the crazyhouse promoted flag of a square, correctly updating the Zobrist key
+func (st *State) TogglePromoted(sq Square) {
+	st.Promoted = st.Promoted.Xor(sq.Bitboard())
+	st.Zobrist ^= zobristPromoted[sq]
+}
+
+// AddCheck counts a check given by color, correctly updating the Zobrist key
+func (st *State) AddCheck(color Color) {
+	st.Zobrist ^= zobristChecks[color][st.Checks[color]]
+	st.Checks[color] = st.clampCheckCount(st.Checks[color] + 1)
+	st.Zobrist ^= zobristChecks[color][st.Checks[color]]
+}
+
+// ComputeZobrist computes the Zobrist key of the state from scratch
+func (st *State) ComputeZobrist() uint64 {
+	key := zobristVariant[st.Variant]
+
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+		p := st.PieceAtSquare(sq)
+		if p != NoPiece {
+			key ^= zobristPiece[p][sq]
+		}
+	}
+
+	castleIndices := [ColorArraySize][2]int{
+		Black: {CastleBlackKingIndex, CastleBlackQueenIndex},
+		White: {CastleWhiteKingIndex, CastleWhiteQueenIndex},
+	}
+
+	for _, color := range []Color{White, Black} {
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++ {
+			if st.CastlingRights[color][side].CanCastle {
+				key ^= zobristCastle[castleIndices[color][side]]
+			}
+		}
+	}
+
+	key ^= zobristEnpassant[st.EpSquare]
+
+	key ^= zobristColor[st.Turn]
+
+	if st.HasDisabledMove {
+		key ^= zobristDisableFrom[st.DisableFromSquare] ^ zobristDisableTo[st.DisableToSquare]
+	}
+
+	for color := Black; color <= White; color++ {
+		for fig := Pawn; fig <= Queen; fig++ {
+			key ^= zobristPocket[color][fig][st.Pockets[color][fig]]
+		}
+	}
+
+	key ^= zobristChecks[White][st.Checks[White]] ^ zobristChecks[Black][st.Checks[Black]]
+
+	promoted := st.Promoted
+
+	for promoted != BbEmpty {
+
+		key ^= zobristPromoted[promoted.Pop()]
+	}
+
+	return key
+}
+
+// VerifyZobrist checks the incrementally updated Zobrist key against the key computed from scratch
+func (st *State) VerifyZobrist(move Move) bool {
+	if st.Zobrist == st.ComputeZobrist() {
+		return true
+	}
+
+	atomic.AddInt64(&HashMismatches, 1)
+
+	DefaultOutput.Println(fmt.Sprintf("info string hash mismatch after %s in %s", move.UCI(), st.ReportFen()))
+
+	return false
+}
+
 func init() {
 	r := rand.New(rand.NewSource(5))
 	f := func() uint64 { return uint64(r.Int63())<<32 ^ uint64(r.Int63()) }
@@ -95,6 +272,66 @@ func init() {
 	initZobristEnpassant(f)
 	initZobristCastle(f)
 	initZobristColor(f)
+	initZobristDisable(f)
+	initZobristVariant(f)
+	initZobristPocket(f)
+	initZobristChecks(f)
+	initZobristDuck(f)
+	initPolyglot()
+}
+
+func initPolyglot() {
+	// polyglot orders pieces as black pawn, white pawn, black knight, ... , white king
+	for p := BlackPawn; p <= WhiteKing; p++ {
+		for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+			if FileOf[sq] < POLYGLOT_FILES {
+				polyglotPiece[p][sq] = polyglotRandom[64 * int(p - BlackPawn) + POLYGLOT_FILES * int(RankOf[sq]) + int(FileOf[sq])]
+			}
+		}
+	}
+	for i := 0; i < CastleArraySize; i++ {
+		polyglotCastle[i] = polyglotRandom[768 + i]
+	}
+	for file := 0; file < POLYGLOT_FILES; file++ {
+		polyglotEnpassant[file] = polyglotRandom[772 + file]
+	}
+	polyglotTurn = polyglotRandom[780]
+}
+
+// PolyglotKey returns the key of the state in the polyglot book format
+func (st *State) PolyglotKey() uint64 {
+	key := uint64(0)
+
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+		p := st.PieceAtSquare(sq)
+		if p >= BlackPawn && p <= WhiteKing {
+			key ^= polyglotPiece[p][sq]
+		}
+	}
+
+	castleIndices := [ColorArraySize][2]int{
+		Black: {CastleBlackKingIndex, CastleBlackQueenIndex},
+		White: {CastleWhiteKingIndex, CastleWhiteQueenIndex},
+	}
+
+	for _, color := range []Color{White, Black} {
+		for side := CastlingSideKing; side <= CastlingSideQueen; side++ {
+			if st.CastlingRights[color][side].CanCastle {
+				key ^= polyglotCastle[castleIndices[color][side]]
+			}
+		}
+	}
+
+	// the en passant file is hashed only if a pawn of the side to move stands next to the pawn that can be captured
+	if st.IsEpCapturable(st.EpSquare) && FileOf[st.EpSquare] < POLYGLOT_FILES {
+		key ^= polyglotEnpassant[FileOf[st.EpSquare]]
+	}
+
+	if st.Turn == White {
+		key ^= polyglotTurn
+	}
+
+	return key
 }
 
 func initZobristPiece(f func() uint64) {
@@ -106,10 +343,11 @@ func initZobristPiece(f func() uint64) {
 }
 
 func initZobristEnpassant(f func() uint64) {
-	for i := 0; i < 8; i++ {
-		zobristEnpassant[SquareA3+Square(i)] = f()
-		zobristEnpassant[SquareA6+Square(i)] = f()
+	for file := 0; file < NUM_FILES; file++ {
+		zobristEnpassant[RankFile[Rank3][file]] = f()
+		zobristEnpassant[RankFile[Rank6][file]] = f()
 	}
+
 }
 
 func initZobristCastle(f func() uint64) {
@@ -123,6 +361,47 @@ func initZobristColor(f func() uint64) {
 	zobristColor[White] = f()
 }
 
+func initZobristDisable(f func() uint64) {
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+		zobristDisableFrom[sq] = f()
+		zobristDisableTo[sq] = f()
+	}
+}
+
+func initZobristVariant(f func() uint64) {
+	for i := range zobristVariant {
+		zobristVariant[i] = f()
+	}
+}
+
+func initZobristPocket(f func() uint64) {
+	for color := Black; color <= White; color++ {
+		for fig := Pawn; fig <= Queen; fig++ {
+			for count := 1; count <= MAX_POCKET_COUNT; count++ {
+				zobristPocket[color][fig][count] = f()
+			}
+		}
+	}
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+		zobristPromoted[sq] = f()
+	}
+}
+
+func initZobristChecks(f func() uint64) {
+	for color := Black; color <= White; color++ {
+		for checks := 1; checks <= MAX_CHECK_LIMIT; checks++ {
+			zobristChecks[color][checks] = f()
+		}
+	}
+}
+
+// the duck keys are drawn last, so that the keys of the other pieces stay the same
+func initZobristDuck(f func() uint64) {
+	for sq := SquareMinValue; sq <= SquareMaxValue; sq++ {
+		zobristPiece[DummyPiece][sq] = f()
+	}
+}
+
 type Score int16
 
 type Accum struct {
@@ -166,6 +445,13 @@ var LANCER_HOME_BONUS = Accum{200, 0}
 var LANCER_FACING_OUT_VALUE = Accum{0, 0}
 var SENTRY_VALUE = Accum{320, 320}
 var JAILER_VALUE = Accum{400, 420}
+var ARCHBISHOP_VALUE = Accum{825, 845}
+var CHANCELLOR_VALUE = Accum{850, 870}
+// a crazyhouse piece in hand can be dropped anywhere, so it is worth a bit more than on the board
+var IN_HAND_BONUS = Accum{20, 20}
+
+// POCKET_VALUES are the default values of crazyhouse pieces in hand by figure, set up by init
+var POCKET_VALUES [FigureArraySize]Accum
 
 type PieceMaterialTable [BOARD_AREA]Accum
 
@@ -234,7 +520,14 @@ const (
 	LANCER_DIRECTION_NW
 )
 
-func init() {
+// NewPieceMaterialTables builds the material tables for a board with the given number of files
+func NewPieceMaterialTables(numFiles int) [PieceArraySize + 2]PieceMaterialTable {
+	tables := [PieceArraySize + 2]PieceMaterialTable{}
+
+	lastFile := File(numFiles - 1)
+	// d and e files on the standard board, e and f on the 10 files board
+	centerFile := File(numFiles / 2 - 1)
+
 	var rank Rank
 	var file File
 	for color := Black; color <= White; color++ {
@@ -244,53 +537,61 @@ func init() {
 			switch fig {
 			case Pawn:
 				mt.Fill(PAWN_VALUE)
-				mt[SquareE4] = CENTER_PAWN_VALUE
-				mt[SquareE5] = CENTER_PAWN_VALUE
-				mt[SquareD4] = CENTER_PAWN_VALUE
-				mt[SquareD5] = CENTER_PAWN_VALUE
-				mt[SquareC3] = SEMI_CENTER_PAWN_VALUE
-				mt[SquareE3] = SEMI_CENTER_PAWN_VALUE
-				PieceMaterialTables[p] = mt.POV(color)
+				mt[RankFile[Rank4][centerFile + 1]] = CENTER_PAWN_VALUE
+				mt[RankFile[Rank5][centerFile + 1]] = CENTER_PAWN_VALUE
+				mt[RankFile[Rank4][centerFile]] = CENTER_PAWN_VALUE
+				mt[RankFile[Rank5][centerFile]] = CENTER_PAWN_VALUE
+				mt[RankFile[Rank3][centerFile - 1]] = SEMI_CENTER_PAWN_VALUE
+				mt[RankFile[Rank3][centerFile + 1]] = SEMI_CENTER_PAWN_VALUE
+				tables[p] = mt.POV(color)
 				break
 			case Knight:
 				mt.Fill(KNIGHT_VALUE)				
 				for rank = 0; rank < NUM_RANKS; rank++{
 					mt[RankFile[rank][0]].UnMerge(KNIGHT_ON_EDGE_DEDUCTION)
-					mt[RankFile[rank][LAST_FILE]].UnMerge(KNIGHT_ON_EDGE_DEDUCTION)
+					mt[RankFile[rank][lastFile]].UnMerge(KNIGHT_ON_EDGE_DEDUCTION)
 					if rank > 0 && rank < LAST_RANK{
 						mt[RankFile[rank][1]].UnMerge(KNIGHT_CLOSE_TO_EDGE_DEDUCTION)
-						mt[RankFile[rank][LAST_FILE-1]].UnMerge(KNIGHT_CLOSE_TO_EDGE_DEDUCTION)
+						mt[RankFile[rank][lastFile-1]].UnMerge(KNIGHT_CLOSE_TO_EDGE_DEDUCTION)
 					}
 				}
-				for file = 0; file < NUM_FILES; file++{
+				for file = 0; file <= lastFile; file++{
 					mt[RankFile[0][file]].UnMerge(KNIGHT_ON_EDGE_DEDUCTION)
 					mt[RankFile[LAST_RANK][file]].UnMerge(KNIGHT_ON_EDGE_DEDUCTION)
-					if file > 0 && file < LAST_FILE{
+					if file > 0 && file < lastFile{
 						mt[RankFile[1][file]].UnMerge(KNIGHT_CLOSE_TO_EDGE_DEDUCTION)
 						mt[RankFile[LAST_RANK-1][file]].UnMerge(KNIGHT_CLOSE_TO_EDGE_DEDUCTION)	
 					}
 				}
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
 				break
 			case Bishop:
 				mt.Fill(BISHOP_VALUE)
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
 				break
 			case Rook:
 				mt.Fill(ROOK_VALUE)
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
 				break
 			case Queen:
 				mt.Fill(QUEEN_VALUE)
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
 				break
 			case Sentry:
 				mt.Fill(SENTRY_VALUE)
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
 				break
 			case Jailer:
 				mt.Fill(JAILER_VALUE)
-				PieceMaterialTables[p] = mt.POV(color)
+				tables[p] = mt.POV(color)
+				break
+			case Archbishop:
+				mt.Fill(ARCHBISHOP_VALUE)
+				tables[p] = mt.POV(color)
+				break
+			case Chancellor:
+				mt.Fill(CHANCELLOR_VALUE)
+				tables[p] = mt.POV(color)
 				break
 			default:
 				// lancer
@@ -302,19 +603,36 @@ func init() {
 						pstr = Rank7
 					}		
 					delta := LANCER_DELTAS[ld]			
-					for file = 0; file < NUM_FILES; file++{
-						if ( file < 6 && ld == LANCER_DIRECTION_E ) || ( file > 2 && ld == LANCER_DIRECTION_W ){
+					for file = 0; file <= lastFile; file++{
+						if ( file < lastFile - 1 && ld == LANCER_DIRECTION_E ) || ( file > 2 && ld == LANCER_DIRECTION_W ){
 							mt[RankFile[pstr][file]].Merge(LANCER_HOME_BONUS)
 						}						
 						for rank = 0; rank < NUM_RANKS; rank++{
-							if (file == 0 && delta.dFile < 0) || (file == LAST_FILE && delta.dFile > 0) || (rank == 0 && delta.dRank < 0) || (rank == LAST_RANK && delta.dRank > 0){
+							if (file == 0 && delta.dFile < 0) || (file == lastFile && delta.dFile > 0) || (rank == 0 && delta.dRank < 0) || (rank == LAST_RANK && delta.dRank > 0){
 								mt[RankFile[rank][file]] = LANCER_FACING_OUT_VALUE
 							}
 						}
 					}					
-					PieceMaterialTables[p] = mt
+					tables[p] = mt
 				}
 			}
 		}
 	}
+
+	return tables
+}
+
+func init() {
+	for fig, value := range map[Figure]Accum{Pawn: PAWN_VALUE, Knight: KNIGHT_VALUE, Bishop: BISHOP_VALUE, Rook: ROOK_VALUE, Queen: QUEEN_VALUE} {
+		value.Merge(IN_HAND_BONUS)
+		POCKET_VALUES[fig] = value
+	}
+
+	PieceMaterialTables = NewPieceMaterialTables(STANDARD_NUM_FILES)
+
+
+	// the variants derive their tables from the default ones
+	for i := range VariantInfos{
+		VariantInfos[i].Setup()
+	}
 }
@@ -0,0 +1,166 @@
+package basic
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestZobristFenAndMoves(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+
+	for _, uci := range []string{"e2e4", "c7c5", "g1f3", "d7d6"} {
+		move, ok := pos.Current().UciToMove(uci)
+		if !ok {
+			t.Fatalf("illegal move %s", uci)
+		}
+		pos.Push(move)
+	}
+
+	played := pos.Current().Zobrist
+
+	if played != pos.Current().ComputeZobrist() {
+		t.Errorf("incremental key differs from computed key")
+	}
+
+	// e3 is not capturable, so the fen ep square does not change the key
+	fenPos := Position{}
+	fenPos.Init(VariantStandard)
+	fenPos.ParseFen("rnbqkbnr/pp2pppp/3p4/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 3")
+
+	if fenPos.Current().Zobrist != played {
+		t.Errorf("key of fen differs from key of played moves")
+	}
+}
+
+func TestZobristDistinguishes(t *testing.T) {
+	fens := []string{
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQk - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w - - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+	}
+
+	keys := map[uint64]string{}
+
+	for _, fen := range fens {
+		pos := Position{}
+		pos.Init(VariantStandard)
+		pos.ParseFen(fen)
+
+		if other, ok := keys[pos.Current().Zobrist]; ok {
+			t.Errorf("%s and %s have the same key", fen, other)
+		}
+
+		keys[pos.Current().Zobrist] = fen
+	}
+
+	pockets := map[uint64]string{}
+
+	for _, fen := range []string{
+		"4k3/1Q6/8/8/4b3/8/Kpp5/8[] b - - 0 1",
+		"4k3/1Q~6/8/8/4b3/8/Kpp5/8[] b - - 0 1",
+		"4k3/1Q6/8/8/4b3/8/Kpp5/8[P] b - - 0 1",
+		"4k3/1Q6/8/8/4b3/8/Kpp5/8[PP] b - - 0 1",
+		"4k3/1Q6/8/8/4b3/8/Kpp5/8[p] b - - 0 1",
+	} {
+		pos := Position{}
+		pos.Init(VariantCrazyhouse)
+		pos.ParseFen(fen)
+
+		if other, ok := pockets[pos.Current().Zobrist]; ok {
+			t.Errorf("%s and %s have the same key", fen, other)
+		}
+
+		pockets[pos.Current().Zobrist] = fen
+	}
+
+	checks := map[uint64]string{}
+
+	for _, fen := range []string{
+		"4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +0+0",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +1+0",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +0+1",
+		"4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+1",
+	} {
+		pos := Position{}
+		pos.Init(VariantThreeCheck)
+		pos.ParseFen(fen)
+
+		if other, ok := checks[pos.Current().Zobrist]; ok {
+			t.Errorf("%s and %s have the same key", fen, other)
+		}
+
+		checks[pos.Current().Zobrist] = fen
+	}
+
+	standard := Position{}
+	standard.Init(VariantStandard)
+
+	atomicPos := Position{}
+	atomicPos.Init(VariantAtomic)
+
+	if standard.Current().Zobrist == atomicPos.Current().Zobrist {
+		t.Errorf("variants have the same key")
+	}
+}
+
+func TestVerifyHash(t *testing.T) {
+	VerifyHash = true
+	defer func() { VerifyHash = false }()
+
+	atomic.StoreInt64(&HashMismatches, 0)
+
+	for _, test := range []struct {
+		variant Variant
+		fen     string
+		depth   int
+		count   int
+	}{
+		{VariantStandard, "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, 9467},
+		{VariantStandard, "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3, 2812},
+		{VariantEightPiece, "jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1 -", 2, 3322},
+		// lancers and sentries giving check
+		{VariantEightPiece, "4ls2k/8/4p3/8/4N3/Rj6/8/Ln1S1K3 w - - 0 1 -", 3, 93789},
+		{VariantEightPiece, "k7/8/8/lse7/5B2/2p5/8/4K3 w - - 0 1 -", 3, 1803},
+		{VariantEightPiece, "k7/8/8/7s/8/5N2/8/4K1R1 w - - 0 1 -", 3, 2502},
+		{VariantEightPiece, "4k3/8/8/8/8/7s/8/4K2R w K - 0 1 -", 3, 1458},
+		{VariantEightPiece, "4k3/8/8/8/8/8/7J/4K2lw w - - 0 1 -", 3, 290},
+		{VariantCrazyhouse, "4k3/1Q~6/8/8/4b3/8/Kpp5/8/ b - - 0 1", 3, 5445},
+		{VariantThreeCheck, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 1+1 0 1", 3, 97848},
+	} {
+		pos := Position{}
+		pos.Init(test.variant)
+		pos.ParseFen(test.fen)
+
+		if count := pos.Perft(test.depth); count != test.count {
+			t.Errorf("%s : expected %d leaves, got %d", test.fen, test.count, count)
+		}
+	}
+
+	if mismatches := atomic.LoadInt64(&HashMismatches); mismatches != 0 {
+		t.Errorf("%d hash mismatches", mismatches)
+	}
+}
+
+func TestPromotionOnA1(t *testing.T) {
+	pos := Position{}
+	pos.Init(VariantStandard)
+	pos.ParseFen("r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1")
+
+	for _, uci := range []string{"g1h1", "b2a1b"} {
+		move, ok := pos.Current().UciToMove(uci)
+		if !ok {
+			t.Fatalf("illegal move %s", uci)
+		}
+		pos.Push(move)
+	}
+
+	// a1 is the no ep square value, promoting there must not capture en passant
+	if pos.Current().PieceAtSquare(SquareA2) != WhitePawn {
+		t.Errorf("pawn on a2 removed by promotion on a1")
+	}
+}
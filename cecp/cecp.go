@@ -0,0 +1,530 @@
+package cecp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+// search depth if neither time control nor depth is given
+const DEFAULT_DEPTH = 10
+
+// variant names in CECP
+var CECP_VARIANTS = map[string]Variant{
+	"normal":        VariantStandard,
+	"fischerandom":  VariantChess960,
+	"atomic":        VariantAtomic,
+	"eightpiece":    VariantEightPiece,
+	"crazyhouse":    VariantCrazyhouse,
+	"3check":        VariantThreeCheck,
+	"kingofthehill": VariantKingOfTheHill,
+	"giveaway":      VariantAntichess,
+	"horde":         VariantHorde,
+	"racingkings":   VariantRacingKings,
+	"capablanca":    VariantCapablanca,
+	"gothic":        VariantGothic,
+	"embassy":       VariantEmbassy,
+}
+
+var CECP_VARIANT_NAMES = []string{"normal", "fischerandom", "atomic", "eightpiece", "crazyhouse", "3check", "kingofthehill", "giveaway", "horde", "racingkings", "capablanca", "gothic", "embassy"}
+
+type Cecp struct{
+	Name         string
+	Pos          *Position
+	Force        bool
+	EngineColor  Color
+	Analyzing    bool
+	Post         bool
+	// level, base and increment in milliseconds
+	Mps          int
+	BaseMs       int
+	IncMs        int
+	// st, in milliseconds
+	MoveTimeMs   int
+	// sd
+	MaxDepth     int
+	// time and otim, in milliseconds
+	TimeMs       int
+	OtimMs       int
+	searching    sync.WaitGroup
+	// stops the running search
+	cancelSearch context.CancelFunc
+	// set when a command aborts the search, the best move of an aborted search is not played
+	stopMutex    sync.Mutex
+	aborted      bool
+}
+
+// Init sets up the front-end to drive an already configured position
+func (c *Cecp) Init(name string, pos *Position){
+	c.Name = name
+	c.Pos = pos
+	c.New()
+}
+
+// New resets the game, engine plays black
+func (c *Cecp) New(){
+	c.StopSearch()
+	c.Pos.Init(VariantStandard)
+	c.Pos.Evals = nil
+	c.Force = false
+	c.EngineColor = Black
+	c.Analyzing = false
+	c.MaxDepth = 0
+}
+
+func (c *Cecp) Variant() Variant{
+	return c.Pos.Current().Variant
+}
+
+// MoveToCecp converts a move to CECP coordinate notation
+func (c *Cecp) MoveToCecp(st *State, move Move) string{
+	if move.MoveType() == Castling{
+		if st.Rules().Chess960{
+			if FileOf[move.ToSq()] < FileOf[move.FromSq()]{
+				return "O-O-O"
+			}
+			return "O-O"
+		}
+		return st.CastlingKingTargetUci(move)
+	}
+
+	if move.MoveType() == Promotion && st.Rules().FairyPieces{
+		return move.FromSq().UCI() + move.ToSq().UCI() + strings.ToLower(XboardLetter(move.PromotionPiece()))
+	}
+
+	// sentry push has no XBoard notation, it is reported in UCI format
+	return move.UCI()
+}
+
+// CecpToMove converts a move in CECP coordinate notation ( or SAN ) to a legal move
+func (c *Cecp) CecpToMove(st *State, cecpMove string) (Move, bool){
+	uci := cecpMove
+
+	if st.Rules().FairyPieces && len(cecpMove) >= 4{
+		fromT := Tokenizer{Content: cecpMove[0:2]}
+		mover := st.PieceAtSquare(fromT.GetSquare())
+
+		if len(cecpMove) == 5{
+			// promotion or lancer turn, letter is given for the color of the mover
+			letter := cecpMove[4]
+			if ColorOf[mover] == White{
+				letter = strings.ToUpper(cecpMove[4:5])[0]
+			}
+			p, ok := PieceOfXboardLetter(letter)
+			if ok{
+				uci = cecpMove[0:4] + SymbolOf[FigureOf[p]]
+			}
+		}else if mover.IsLancer() && len(cecpMove) == 4{
+			// lancer keeps its direction
+			uci = cecpMove + SymbolOf[FigureOf[mover]]
+		}
+	}
+
+	move, ok := st.UciToMove(uci)
+
+	if ok{
+		return move, true
+	}
+
+	return st.SanToMove(cecpMove)
+}
+
+// ReportThinking sends the thinking output of a completed iteration
+func (c *Cecp) ReportThinking(mpi MultiPvInfo){
+	if !c.Post && !c.Analyzing{
+		return
+	}
+
+	score := int(mpi.Score)
+
+	if mpi.Score.IsMateInN(){
+		// mate in n moves is reported as 100000 + n
+		if mpi.Score > 0{
+			score = 100000 + int(MATE_SCORE - mpi.Score + 1) / 2
+		}else{
+			score = -100000 - int(MATE_SCORE + mpi.Score + 1) / 2
+		}
+	}
+
+	st := *c.Pos.Current()
+
+	pv := []string{}
+
+	for _, move := range mpi.Pv{
+		pv = append(pv, c.MoveToCecp(&st, move))
+		st.MakeMove(move, &Undo{})
+	}
+
+	c.Pos.Printf("%d %d %d %d %s\n", mpi.Depth, score, mpi.Time / 10, mpi.Nodes, strings.Join(pv, " "))
+}
+
+// ReportResult sends the result if the game is over
+func (c *Cecp) ReportResult() bool{
+	result, reason := c.Pos.GameResult()
+
+	if result == "*"{
+		return false
+	}
+
+	c.Pos.Printf("%s {%s}\n", result, reason)
+
+	return true
+}
+
+// PlayMove plays the best move found by the search
+func (c *Cecp) PlayMove(pv []Move){
+	c.stopMutex.Lock()
+	defer c.stopMutex.Unlock()
+
+	if c.Analyzing || c.aborted{
+		return
+	}
+
+	if len(pv) == 0{
+		c.ReportResult()
+		return
+	}
+
+	st := c.Pos.Current()
+
+	c.Pos.Println("move " + c.MoveToCecp(st, pv[0]))
+
+	c.Pos.Push(pv[0])
+	c.Pos.Rebase(MAX_GAME_PLIES)
+
+	c.ReportResult()
+}
+
+// Limits sets up search limits from the time control
+func (c *Cecp) Limits() (SearchLimits, int){
+	limits := SearchLimits{}
+
+	depth := SEARCH_MAX_DEPTH
+
+	if c.MaxDepth > 0{
+		depth = c.MaxDepth
+	}
+
+	if c.Analyzing{
+		limits.Infinite = true
+		return limits, depth
+	}
+
+	if c.MoveTimeMs > 0{
+		limits.MoveTime = c.MoveTimeMs
+		return limits, depth
+	}
+
+	if c.TimeMs <= 0 && c.BaseMs <= 0{
+		if c.MaxDepth <= 0{
+			depth = DEFAULT_DEPTH
+		}
+		return limits, depth
+	}
+
+	timeMs := c.TimeMs
+
+	if timeMs <= 0{
+		timeMs = c.BaseMs
+	}
+
+	otimMs := c.OtimMs
+
+	if otimMs <= 0{
+		otimMs = timeMs
+	}
+
+	if c.EngineColor == White{
+		limits.WTime, limits.BTime = timeMs, otimMs
+		limits.WInc, limits.BInc = c.IncMs, c.IncMs
+	}else{
+		limits.BTime, limits.WTime = timeMs, otimMs
+		limits.BInc, limits.WInc = c.IncMs, c.IncMs
+	}
+
+	if c.Mps > 0{
+		limits.MovesToGo = c.Mps - ( ( c.Pos.Current().FullmoveNumber - 1 ) % c.Mps )
+	}
+
+	return limits, depth
+}
+
+// StartSearch starts thinking or analyzing in the background
+func (c *Cecp) StartSearch(){
+	c.StopSearch()
+
+	if !c.Analyzing && c.ReportResult(){
+		return
+	}
+
+	limits, depth := c.Limits()
+
+	c.Pos.Limits = limits
+	c.Pos.InfoHook = c.ReportThinking
+	c.Pos.BestMoveHook = c.PlayMove
+
+	c.stopMutex.Lock()
+	c.aborted = false
+	c.stopMutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c.cancelSearch = cancel
+	c.Pos.StopSignal = ctx.Done()
+
+	c.searching.Add(1)
+
+	go func(){
+		defer c.searching.Done()
+		c.Pos.Search(depth)
+	}()
+}
+
+// StopSearch aborts the search and waits for it to finish, the best move is not played
+// commands that change the game or the engine's role call it before they take effect
+func (c *Cecp) StopSearch(){
+	c.stopMutex.Lock()
+	c.aborted = true
+	c.stopMutex.Unlock()
+
+	c.MoveNow()
+
+	c.searching.Wait()
+}
+
+// MoveNow stops the search without waiting, the search plays or reports its best move
+func (c *Cecp) MoveNow(){
+	if c.cancelSearch != nil{
+		c.cancelSearch()
+	}
+}
+
+// EngineToMove tells whether the engine has to move
+func (c *Cecp) EngineToMove() bool{
+	return !c.Force && !c.Analyzing && c.Pos.Current().Turn == c.EngineColor
+}
+
+func (c *Cecp) ExecProtoverCommand(){
+	features := []string{
+		"ping=1",
+		"setboard=1",
+		"playother=1",
+		"san=0",
+		"usermove=1",
+		"time=1",
+		"draw=0",
+		"sigint=0",
+		"sigterm=0",
+		"reuse=1",
+		"analyze=1",
+		"colors=0",
+		"memory=1",
+		"smp=1",
+		fmt.Sprintf("myname=\"%s\"", c.Name),
+		fmt.Sprintf("variants=\"%s\"", strings.Join(CECP_VARIANT_NAMES, ",")),
+	}
+
+	c.Pos.Println("feature " + strings.Join(features, " "))
+	c.Pos.Println("feature done=1")
+}
+
+func (c *Cecp) ExecVariantCommand(name string){
+	variant, ok := CECP_VARIANTS[name]
+
+	if !ok{
+		c.Pos.Println("Error (unsupported variant): " + name)
+		return
+	}
+
+	c.Pos.Init(variant)
+
+	if c.Pos.Current().Rules().FairyPieces{
+		for _, command := range EightpieceSetupCommands(c.Pos.Current()){
+			c.Pos.Println(command)
+		}
+	}
+}
+
+func (c *Cecp) ExecSetboardCommand(fen string){
+	if c.Pos.Current().Rules().FairyPieces{
+		fen = EngineFen(fen)
+	}
+
+	c.Pos.ParseFen(fen)
+}
+
+func (c *Cecp) ExecUsermoveCommand(cecpMove string){
+	move, ok := c.CecpToMove(c.Pos.Current(), cecpMove)
+
+	if !ok{
+		c.Pos.Println("Illegal move: " + cecpMove)
+		return
+	}
+
+	c.Pos.Push(move)
+	c.Pos.Rebase(MAX_GAME_PLIES)
+
+	if c.Analyzing || c.EngineToMove(){
+		c.StartSearch()
+	}
+}
+
+// ParseClock parses minutes or minutes:seconds of the level command to milliseconds
+func ParseClock(clock string) int{
+	parts := strings.Split(clock, ":")
+
+	minutes, _ := strconv.ParseFloat(parts[0], 64)
+
+	ms := int(minutes * 60000)
+
+	if len(parts) > 1{
+		seconds, _ := strconv.ParseFloat(parts[1], 64)
+		ms += int(seconds * 1000)
+	}
+
+	return ms
+}
+
+func (c *Cecp) ExecLevelCommand(args []string){
+	if len(args) < 3{
+		c.Pos.Println("Error (too few parameters): level")
+		return
+	}
+
+	c.Mps, _ = strconv.Atoi(args[0])
+	c.BaseMs = ParseClock(args[1])
+	inc, _ := strconv.ParseFloat(args[2], 64)
+	c.IncMs = int(inc * 1000)
+	c.MoveTimeMs = 0
+}
+
+// ExecCommandLine executes a CECP command, returns an error on quit
+func (c *Cecp) ExecCommandLine(commandLine string) error{
+	t := Tokenizer{Content: commandLine}
+
+	command, ok := t.GetToken()
+
+	if !ok{
+		return nil
+	}
+
+	args := t.GetTokensUpTo("")
+
+	arg := ""
+
+	intArg := 0
+
+	if len(args) > 0{
+		arg = args[0]
+		intArg, _ = strconv.Atoi(arg)
+	}
+
+	switch command{
+	case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer", "name", "rating", "ics", ".", "draw":
+		// nothing to do
+	case "quit":
+		c.StopSearch()
+		return fmt.Errorf("exit")
+	case "protover":
+		c.ExecProtoverCommand()
+	case "new":
+		c.New()
+	case "variant":
+		c.StopSearch()
+		c.ExecVariantCommand(arg)
+	case "force":
+		c.StopSearch()
+		c.Force = true
+	case "go":
+		c.StopSearch()
+		c.Force = false
+		c.EngineColor = c.Pos.Current().Turn
+		c.StartSearch()
+	case "playother":
+		c.StopSearch()
+		c.Force = false
+		c.EngineColor = c.Pos.Current().Turn.Inverse()
+	case "usermove":
+		c.StopSearch()
+		c.ExecUsermoveCommand(arg)
+	case "?":
+		c.MoveNow()
+	case "ping":
+		c.Pos.Printf("pong %s\n", arg)
+	case "setboard":
+		c.StopSearch()
+		c.ExecSetboardCommand(strings.Join(args, " "))
+		if c.Analyzing{
+			c.StartSearch()
+		}
+	case "undo", "remove":
+		c.StopSearch()
+		plies := 1
+		if command == "remove"{
+			plies = 2
+		}
+		for i := 0; i < plies && c.Pos.StatePtr > 0; i++{
+			c.Pos.Pop()
+		}
+		if c.Analyzing{
+			c.StartSearch()
+		}
+	case "level":
+		c.ExecLevelCommand(args)
+	case "st":
+		seconds, _ := strconv.ParseFloat(arg, 64)
+		c.MoveTimeMs = int(seconds * 1000)
+	case "sd":
+		c.MaxDepth = intArg
+	case "time":
+		c.TimeMs = intArg * 10
+	case "otim":
+		c.OtimMs = intArg * 10
+	case "post":
+		c.Post = true
+	case "nopost":
+		c.Post = false
+	case "analyze":
+		c.StopSearch()
+		c.Analyzing = true
+		c.StartSearch()
+	case "exit":
+		c.StopSearch()
+		c.Analyzing = false
+	case "result":
+		c.StopSearch()
+		c.Force = true
+	case "memory":
+		TransTable.Resize(intArg)
+	case "cores":
+		c.Pos.Threads = intArg
+	default:
+		// a bare move is accepted as well, the search is stopped before the board is read
+		c.StopSearch()
+		if _, legal := c.CecpToMove(c.Pos.Current(), command); legal{
+			c.ExecUsermoveCommand(command)
+		}else{
+			c.Pos.Println("Error (unknown command): " + command)
+		}
+	}
+
+	return nil
+}
+
+// Loop reads commands until quit
+func (c *Cecp) Loop(scan *bufio.Scanner){
+	for scan.Scan(){
+		commandLine := strings.TrimSpace(scan.Text())
+
+		err := c.ExecCommandLine(commandLine)
+
+		if err != nil{
+			break
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cecp
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/easychessanimations/gobbit/basic"
+	"github.com/easychessanimations/gobbit/uci"
+)
+
+func TestXboardLetters(t *testing.T) {
+	for color := Black; color <= White; color++ {
+		for ld := 0; ld < NUM_LANCER_DIRECTIONS; ld++ {
+			lancer := MakeLancer(color, ld)
+			p, ok := PieceOfXboardLetter(XboardLetter(lancer)[0])
+			if !ok || p != lancer {
+				t.Errorf("lancer %s does not round trip through letter %s", lancer.FenSymbol(), XboardLetter(lancer))
+			}
+		}
+	}
+
+	st := State{}
+	st.Init(VariantEightPiece)
+
+	if placement := XboardPlacement(&st); placement != "jcsqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JCSQKBNR" {
+		t.Errorf("wrong xboard placement %s", placement)
+	}
+
+	if fen := EngineFen(XboardFen(&st)); fen != "jlsesqkbnr/pppppppp/8/8/8/8/PPPPPPPP/JLneSQKBNR w KQkq - 0 1" {
+		t.Errorf("wrong engine fen %s", fen)
+	}
+}
+
+func TestCecpMoves(t *testing.T) {
+	c := Cecp{}
+	pos := Position{}
+	c.Init("test", &pos)
+
+	st := pos.Current()
+	st.ParseFen("r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1")
+
+	move, ok := c.CecpToMove(st, "e1g1")
+
+	if !ok || move.MoveType() != Castling || c.MoveToCecp(st, move) != "e1g1" {
+		t.Errorf("castling e1g1 not converted")
+	}
+
+	c.ExecVariantCommand("fischerandom")
+	pos.ParseFen("r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1")
+
+	move, ok = c.CecpToMove(pos.Current(), "O-O-O")
+
+	if !ok || c.MoveToCecp(pos.Current(), move) != "O-O-O" {
+		t.Errorf("castling O-O-O not converted")
+	}
+}
+
+func TestCecpOutput(t *testing.T) {
+	lines := []string{}
+
+	pos := Position{}
+	pos.Out = OutputFunc(func(text string) {
+		lines = append(lines, text)
+	})
+
+	c := Cecp{}
+	c.Init("test", &pos)
+
+	c.ExecCommandLine("force")
+	c.ExecCommandLine("ping 3")
+	c.ExecCommandLine("usermove e2e5")
+
+	expected := []string{"pong 3", "Illegal move: e2e5"}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("expected output %v got %v", expected, lines)
+	}
+
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Errorf("expected %s got %s", line, lines[i])
+		}
+	}
+}
+
+func TestCecpAbortSearch(t *testing.T) {
+	mutex := sync.Mutex{}
+	moves := []string{}
+
+	// the position is configured by the uci options, as in the engine
+	u := uci.Uci{}
+	u.Init("test", "test", map[string]string{})
+	pos := &u.Pos
+
+	pos.Out = OutputFunc(func(text string) {
+		if strings.HasPrefix(text, "move ") {
+			mutex.Lock()
+			moves = append(moves, text)
+			mutex.Unlock()
+		}
+	})
+
+	c := Cecp{}
+	c.Init("test", pos)
+
+	for _, command := range []string{"force", "new", "undo", "usermove e2e4", "e2e4"} {
+		c.ExecCommandLine("new")
+		c.ExecCommandLine("st 30")
+		c.ExecCommandLine("go")
+
+		time.Sleep(100 * time.Millisecond)
+
+		c.ExecCommandLine(command)
+
+		mutex.Lock()
+
+		if len(moves) > 0 {
+			t.Errorf("%s should abort the search without a move, got %v", command, moves)
+		}
+
+		mutex.Unlock()
+	}
+
+	c.ExecCommandLine("new")
+	c.ExecCommandLine("st 30")
+	c.ExecCommandLine("go")
+
+	time.Sleep(100 * time.Millisecond)
+
+	c.ExecCommandLine("?")
+	c.searching.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(moves) != 1 || pos.StatePtr != 1 {
+		t.Errorf("? should play the best move, got %v", moves)
+	}
+}
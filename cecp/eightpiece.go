@@ -0,0 +1,164 @@
+package cecp
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+// XBoard pieces are single letters, so Eightpiece lancers get one letter per direction
+// by direction N, NE, E, SE, S, SW, W, NW
+// XBoard mirrors piece moves vertically for black, so black lancers get the letter of the mirrored direction
+const LANCER_LETTERS = "ACDEFGHI"
+
+// piece to char table of the setup command, in XBoard's internal piece order PNBRQFEACWMOHIJGDVLSUK
+const EIGHTPIECE_PIECE_TO_CHAR = "PNBRQACDEFGHISJ......K"
+
+// Betza notation of the Eightpiece figures for the piece command, moves of white pieces
+// lancers are riders in their direction, sentry push and lancer turn can not be described
+var EIGHTPIECE_BETZA = map[string]string{
+	"A": "fR",
+	"C": "frB",
+	"D": "rR",
+	"E": "brB",
+	"F": "bR",
+	"G": "blB",
+	"H": "lR",
+	"I": "flB",
+	"S": "B",
+	"J": "mR",
+}
+
+func mirrorLancerDirection(ld int) int{
+	return ( 12 - ld ) % NUM_LANCER_DIRECTIONS
+}
+
+// XboardLetter tells the XBoard letter of an Eightpiece piece
+func XboardLetter(p Piece) string{
+	fig := FigureOf[p]
+
+	letter := strings.ToUpper(SymbolOf[fig][0:1])
+
+	if fig >= LancerMinValue && fig <= LancerMaxValue{
+		ld := int(fig - LancerMinValue)
+		if ColorOf[p] == Black{
+			ld = mirrorLancerDirection(ld)
+		}
+		letter = LANCER_LETTERS[ld:ld + 1]
+	}
+
+	if ColorOf[p] == Black{
+		return strings.ToLower(letter)
+	}
+
+	return letter
+}
+
+// PieceOfXboardLetter tells the Eightpiece piece of an XBoard letter
+func PieceOfXboardLetter(letter byte) (Piece, bool){
+	color := White
+
+	if letter >= 'a' && letter <= 'z'{
+		color = Black
+		letter -= 'a' - 'A'
+	}
+
+	ld := strings.IndexByte(LANCER_LETTERS, letter)
+
+	if ld >= 0{
+		if color == Black{
+			ld = mirrorLancerDirection(ld)
+		}
+		return MakeLancer(color, ld), true
+	}
+
+	for fig := Pawn; fig <= FigureMaxValue; fig++{
+		if fig == Lancer || ( fig >= LancerMinValue && fig <= LancerMaxValue ){
+			continue
+		}
+		if SymbolOf[fig] != "" && strings.ToUpper(SymbolOf[fig]) == string(letter){
+			return ColorFigure[color][fig], true
+		}
+	}
+
+	return NoPiece, false
+}
+
+// XboardPlacement reports the placement part of the fen with XBoard letters
+func XboardPlacement(st *State) string{
+	buff := ""
+
+	for rank := LAST_RANK; rank >= 0; rank--{
+		cum := 0
+		for file := 0; file < st.Rules().Files; file++{
+			p := st.Pieces[rank][file]
+			if p == NoPiece{
+				cum++
+				continue
+			}
+			if cum > 0{
+				buff += fmt.Sprintf("%d", cum)
+			}
+			cum = 0
+			buff += XboardLetter(p)
+		}
+		if cum > 0{
+			buff += fmt.Sprintf("%d", cum)
+		}
+		if rank > 0{
+			buff += "/"
+		}
+	}
+
+	return buff
+}
+
+// XboardFen reports the fen of the state with XBoard letters, without the Eightpiece disabled move
+func XboardFen(st *State) string{
+	fenParts := strings.Split(st.ReportFen(), " ")
+
+	fenParts[0] = XboardPlacement(st)
+
+	if len(fenParts) > 6{
+		fenParts = fenParts[:6]
+	}
+
+	return strings.Join(fenParts, " ")
+}
+
+// EngineFen converts a fen with XBoard letters to an Eightpiece fen
+func EngineFen(fen string) string{
+	fenParts := strings.Split(fen, " ")
+
+	buff := ""
+
+	for i := 0; i < len(fenParts[0]); i++{
+		c := fenParts[0][i]
+
+		p, ok := PieceOfXboardLetter(c)
+
+		if ( c >= '0' && c <= '9' ) || c == '/' || !ok{
+			buff += string(c)
+		}else{
+			buff += p.FenSymbol()
+		}
+	}
+
+	fenParts[0] = buff
+
+	return strings.Join(fenParts, " ")
+}
+
+// EightpieceSetupCommands returns the setup and piece commands describing Eightpiece to the GUI
+func EightpieceSetupCommands(st *State) []string{
+	commands := []string{
+		fmt.Sprintf("setup (%s%s) 8x8+0_fairy %s", EIGHTPIECE_PIECE_TO_CHAR, strings.ToLower(EIGHTPIECE_PIECE_TO_CHAR), XboardFen(st)),
+	}
+
+	for _, letter := range LANCER_LETTERS + "SJ"{
+		commands = append(commands, fmt.Sprintf("piece %c %s", letter, EIGHTPIECE_BETZA[string(letter)]))
+	}
+
+	return commands
+}
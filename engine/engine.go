@@ -0,0 +1,245 @@
+// Package engine runs gobbit searches in process, without going through the UCI text protocol
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	. "github.com/easychessanimations/gobbit/basic"
+	"github.com/easychessanimations/gobbit/uci"
+)
+
+// ErrBusy is returned when the engine is asked to search or change options while it is searching
+var ErrBusy = errors.New("engine is searching")
+
+// Game is the position to analyze, the moves played from a start position
+type Game struct{
+	Variant      Variant
+	// start position, the start position of the variant if empty
+	Fen          string
+	// moves in UCI notation
+	Moves        []string
+}
+
+// Limits tell how long to search, a search without limits runs until its context is cancelled
+type Limits struct{
+	// max depth of iterative deepening, SEARCH_MAX_DEPTH if 0
+	Depth        int
+	// number of principal variations, the MultiPV option if 0
+	MultiPV      int
+	// time, nodes and mate limits as given by the UCI go command
+	SearchLimits
+}
+
+// Info is a message of a running search
+type Info struct{
+	// the result of a completed iteration for one principal variation, empty in the final message
+	MultiPv      MultiPvInfo
+	// the final message, the channel is closed after it
+	Final        bool
+	// the move to play and the expected reply, set in the final message, NullMove if there is none
+	BestMove     Move
+	Ponder       Move
+}
+
+// Engine searches one position at a time, engines have their own hash tables, so they can search concurrently
+// the search settings are the UCI options of the protocol front-end
+type Engine struct{
+	mutex        sync.Mutex
+	searching    bool
+	// holds the options and the position template they are applied to
+	uci          uci.Uci
+	transTable   TranspositionTable
+	pvTable      PvHash
+	posMoveTable PosMoveHash
+}
+
+// options that set globals of the process, they are not applied when an engine is created
+var PROCESS_WIDE_OPTIONS = map[string]bool{
+	"Verify Hash": true,
+}
+
+// New creates an engine with the default options
+func New() *Engine{
+	e := &Engine{}
+
+	e.uci.UciOptions = uci.NewUciOptions()
+
+	// results are sent on channels, messages of the front-end are dropped unless SetOutput asks for them
+	e.uci.SetOutput(DiscardOutput)
+
+	e.uci.Pos.Init(uci.DEFAULT_VARIANT)
+
+	e.pvTable.Alloc(HELPER_HASH_SIZE)
+	e.posMoveTable.Alloc(HELPER_HASH_SIZE)
+
+	for _, uo := range e.uci.UciOptions{
+		if !PROCESS_WIDE_OPTIONS[uo.Name]{
+			e.setOption(uo.Name, uo.StringValue())
+		}
+	}
+
+	return e
+}
+
+// SetOutput sets where the messages of the engine go, like the book moves played or errors of options
+func (e *Engine) SetOutput(out Output){
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.uci.SetOutput(out)
+}
+
+// SetOption sets an option by its UCI name, Hash and Clear Hash act on the table of the engine
+func (e *Engine) SetOption(name, value string) error{
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.searching{
+		return ErrBusy
+	}
+
+	for _, uo := range e.uci.UciOptions{
+		if uo.Name == name{
+			return e.setOption(name, value)
+		}
+	}
+
+	return fmt.Errorf("unknown option %s", name)
+}
+
+func (e *Engine) setOption(name, value string) error{
+	switch name{
+	case "Hash":
+		size, err := strconv.Atoi(value)
+
+		if err != nil{
+			return fmt.Errorf("Hash should be a number, got %s", value)
+		}
+
+		e.transTable.Resize(size)
+	case "Clear Hash":
+		e.transTable.Clear()
+	default:
+		e.uci.SetOption(name, value)
+	}
+
+	return nil
+}
+
+// Position sets up the position of the game
+func (e *Engine) Position(game Game) (*Position, error){
+	if game.Variant < 0 || int(game.Variant) >= len(VariantInfos){
+		return nil, fmt.Errorf("unknown variant %d", game.Variant)
+	}
+
+	pos := new(Position)
+
+	e.mutex.Lock()
+	*pos = e.uci.Pos
+	e.mutex.Unlock()
+
+	pos.Init(game.Variant)
+
+	if game.Fen != ""{
+		if err := pos.ParseFen(game.Fen); err != nil{
+			return nil, err
+		}
+	}
+
+	for _, uciMove := range game.Moves{
+		move, ok := pos.Current().UciToMove(uciMove)
+
+		if !ok{
+			return nil, fmt.Errorf("illegal move %s", uciMove)
+		}
+
+		pos.Push(move)
+		pos.Rebase(MAX_GAME_PLIES)
+	}
+
+	return pos, nil
+}
+
+// Analyze starts the search of the game and returns the channel of its messages
+// cancelling the context stops the search, the final message with the best move is sent anyway
+// the channel has to be read until it is closed, the search waits for the final message to be received
+func (e *Engine) Analyze(ctx context.Context, game Game, limits Limits) (<-chan Info, error){
+	pos, err := e.Position(game)
+
+	if err != nil{
+		return nil, err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.searching{
+		return nil, ErrBusy
+	}
+
+	depth := limits.Depth
+
+	if depth <= 0 || depth > SEARCH_MAX_DEPTH{
+		depth = SEARCH_MAX_DEPTH
+	}
+
+	if limits.MultiPV > 0{
+		pos.MultiPV = limits.MultiPV
+	}
+
+	if pos.MultiPV > MAX_MULTIPV{
+		pos.MultiPV = MAX_MULTIPV
+	}
+
+	pos.Limits = limits.SearchLimits
+	pos.TransTable = &e.transTable
+	pos.PvTable = &e.pvTable
+	pos.PosMoveTable = &e.posMoveTable
+	pos.StopSignal = ctx.Done()
+
+	infos := make(chan Info, MAX_MULTIPV)
+
+	pos.InfoHook = func(mpi MultiPvInfo){
+		select{
+		case infos <- Info{MultiPv: mpi}:
+		case <-ctx.Done():
+			// nobody may be listening any more
+		}
+	}
+
+	pos.BestMoveHook = func(pv []Move){
+		info := Info{
+			Final: true,
+			BestMove: NullMove,
+			Ponder: NullMove,
+		}
+
+		if len(pv) > 0{
+			info.BestMove = pv[0]
+		}
+
+		if len(pv) > 1{
+			info.Ponder = pv[1]
+		}
+
+		infos <- info
+	}
+
+	e.searching = true
+
+	go func(){
+		pos.Search(depth)
+
+		e.mutex.Lock()
+		e.searching = false
+		e.mutex.Unlock()
+
+		close(infos)
+	}()
+
+	return infos, nil
+}
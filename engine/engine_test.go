@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+// collect reads the messages of a search until the channel is closed
+func collect(infos <-chan Info) ([]MultiPvInfo, Info) {
+	mpis := []MultiPvInfo{}
+	final := Info{}
+
+	for info := range infos {
+		if info.Final {
+			final = info
+		} else {
+			mpis = append(mpis, info.MultiPv)
+		}
+	}
+
+	return mpis, final
+}
+
+func TestAnalyze(t *testing.T) {
+	e := New()
+
+	infos, err := e.Analyze(context.Background(), Game{Variant: VariantStandard, Moves: []string{"e2e4", "e7e5"}}, Limits{Depth: 4, MultiPV: 2})
+
+	if err != nil {
+		t.Fatalf("analyze failed %v", err)
+	}
+
+	mpis, final := collect(infos)
+
+	if !final.Final {
+		t.Fatalf("no final message")
+	}
+
+	if len(mpis) != 8 {
+		t.Errorf("expected 2 pvs for 4 depths, got %d infos", len(mpis))
+	}
+
+	for i, mpi := range mpis {
+		if mpi.Depth != i/2+1 || mpi.Index != i%2+1 {
+			t.Errorf("info %d has depth %d multipv %d", i, mpi.Depth, mpi.Index)
+		}
+	}
+
+	pos, _ := e.Position(Game{Variant: VariantStandard, Moves: []string{"e2e4", "e7e5"}})
+
+	if _, ok := pos.Current().UciToMove(final.BestMove.UCI()); !ok {
+		t.Errorf("best move %s is not legal", final.BestMove.UCI())
+	}
+}
+
+func TestAnalyzeCancel(t *testing.T) {
+	e := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	infos, err := e.Analyze(ctx, Game{Variant: VariantAtomic}, Limits{SearchLimits: SearchLimits{Infinite: true}})
+
+	if err != nil {
+		t.Fatalf("analyze failed %v", err)
+	}
+
+	if _, err := e.Analyze(ctx, Game{Variant: VariantStandard}, Limits{}); err != ErrBusy {
+		t.Errorf("second search should fail with busy, got %v", err)
+	}
+
+	if err := e.SetOption("Hash", "1"); err != ErrBusy {
+		t.Errorf("options should not change while searching, got %v", err)
+	}
+
+	<-infos
+
+	cancel()
+
+	_, final := collect(infos)
+
+	if !final.Final || final.BestMove == NullMove {
+		t.Errorf("cancelled search should report a best move")
+	}
+
+	if _, err := e.Analyze(context.Background(), Game{Variant: VariantStandard}, Limits{Depth: 1}); err != nil {
+		t.Errorf("engine should be idle after the search, got %v", err)
+	}
+}
+
+func TestConcurrentEngines(t *testing.T) {
+	games := []Game{
+		{Variant: VariantStandard, Fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"},
+		{Variant: VariantCrazyhouse, Moves: []string{"e2e4", "d7d5", "e4d5"}},
+		{Variant: VariantDuck, Moves: []string{"e2e4@e6"}},
+	}
+
+	wg := sync.WaitGroup{}
+
+	for _, game := range games {
+		wg.Add(1)
+
+		go func(game Game) {
+			defer wg.Done()
+
+			e := New()
+			e.SetOption("Threads", "2")
+
+			infos, err := e.Analyze(context.Background(), game, Limits{Depth: 3})
+
+			if err != nil {
+				t.Errorf("analyze failed %v", err)
+				return
+			}
+
+			if _, final := collect(infos); final.BestMove == NullMove {
+				t.Errorf("variant %s has no best move", game.Variant)
+			}
+		}(game)
+	}
+
+	wg.Wait()
+}
+
+func TestGameErrors(t *testing.T) {
+	e := New()
+
+	if _, err := e.Position(Game{Variant: VariantStandard, Moves: []string{"e2e5"}}); err == nil {
+		t.Errorf("illegal move should fail")
+	}
+
+	if _, err := e.Position(Game{Variant: Variant(-1)}); err == nil {
+		t.Errorf("unknown variant should fail")
+	}
+
+	if err := e.SetOption("No Such Option", "1"); err == nil {
+		t.Errorf("unknown option should fail")
+	}
+}
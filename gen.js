@@ -1,10 +1,10 @@
 const fs = require('fs')
 
 const NUM_RANKS = 8
-const NUM_FILES = 8
+const NUM_FILES = 10
 
 const rankLetters = ["1", "2", "3", "4", "5", "6", "7", "8"]
-const fileLetters = ["a", "b", "c", "d", "e", "f", "g", "h"]
+const fileLetters = ["a", "b", "c", "d", "e", "f", "g", "h", "i", "j"]
 
 function writeFile(name, package, content){
     let pcontent = `package ${package}
@@ -19,7 +19,7 @@ ${content}
 let sqs = []
 
 for(let rank=0; rank<NUM_RANKS; rank++) for(let file=0; file<NUM_FILES; file++) sqs.push(
-    `   Square${fileLetters[file].toUpperCase()}${rankLetters[rank]} = Square(${rank*NUM_RANKS+file})`
+    `   Square${fileLetters[file].toUpperCase()}${rankLetters[rank]} = Square(${rank*NUM_FILES+file})`
 )
 
 let ranks = []
@@ -37,21 +37,13 @@ for(let file=0; file<NUM_FILES; file++) files.push(
 const square_go = `
 const NUM_RANKS = ${NUM_RANKS}
 const LAST_RANK = NUM_RANKS - 1
-const NUM_FILES = ${NUM_RANKS}
+const NUM_FILES = ${NUM_FILES}
 const LAST_FILE = NUM_FILES - 1
 
 const BOARD_AREA = NUM_RANKS * NUM_FILES
 
-const RANK_STORAGE_SIZE_IN_BITS = 3
-const FILE_STORAGE_SIZE_IN_BITS = 3
-
-const SQUARE_STORAGE_SIZE_IN_BITS = RANK_STORAGE_SIZE_IN_BITS + FILE_STORAGE_SIZE_IN_BITS
-
-const RANK_SHIFT_IN_BITS = FILE_STORAGE_SIZE_IN_BITS
-const FILE_SHIFT_IN_BITS = 0
-
-const RANK_MASK = ((1 << RANK_STORAGE_SIZE_IN_BITS) - 1) << RANK_SHIFT_IN_BITS
-const FILE_MASK = (1 << FILE_STORAGE_SIZE_IN_BITS) - 1
+// squares are numbered rank by rank, square = rank * NUM_FILES + file
+const SQUARE_STORAGE_SIZE_IN_BITS = ${Math.ceil(Math.log2(NUM_RANKS * NUM_FILES))}
 
 type Rank int
 type File int
@@ -62,7 +54,7 @@ ${sqs.join("\n")}
 )
 
 const SquareMinValue = SquareA1
-const SquareMaxValue = SquareH8
+const SquareMaxValue = Square${fileLetters[NUM_FILES-1].toUpperCase()}${rankLetters[NUM_RANKS-1]}
 
 const (
 ${ranks.join("\n")}
@@ -99,7 +91,9 @@ const figures = [
     ["LancerW", "lw"],
     ["LancerNW", "lnw"],
     ["Sentry", "s"],
-    ["Jailer", "j"]
+    ["Jailer", "j"],
+    ["Archbishop", "a"],
+    ["Chancellor", "c"]
 ]
 
 const piece_go = `
@@ -198,13 +192,12 @@ for(let line of magicsTxt.split("\n")){
         let squareUci = m[1]
         m = line.match(/shift\s+([^\s]+)/)
         let shift = m[1]
-        m = line.match(/magic ([^\s]+)/g)
-        m = m[1].match(/magic ([^\s]+)/)
-        let magic = m[1]
+        m = line.match(/magic ([0-9a-f]{16}) ([0-9a-f]{16})/)
         magics[kind].push({
             squareUci: squareUci,
             shift: shift,
-            magic: magic
+            magic: m[1],
+            magicHi: m[2]
         })
     }
 }
@@ -212,15 +205,18 @@ for(let line of magicsTxt.split("\n")){
 let magics_go = `
 import "fmt"
 
+// the key of an occupancy is ( Lo * Magic ^ Hi * MagicHi ) >> ( 64 - Shift ), Mask is set up by init
 type MagicSquare struct{
     Square  Square
     Shift   int
     Magic   uint64
+    MagicHi uint64
+    Mask    Bitboard
     Entries []Bitboard
 }
 
 func (msq MagicSquare) String() string{
-    return fmt.Sprintf("MagicSquare %v %2d %016X %d", msq.Square, msq.Shift, msq.Magic, len(msq.Entries))
+    return fmt.Sprintf("MagicSquare %v %2d %016X %016X %d", msq.Square, msq.Shift, msq.Magic, msq.MagicHi, len(msq.Entries))
 }
 
 type Magics [BOARD_AREA]MagicSquare`
@@ -228,7 +224,7 @@ type Magics [BOARD_AREA]MagicSquare`
 for(let kind in magics){
     magics_go += "\n\nvar " + kind.toUpperCase() + "_MAGICS = Magics{\n"
     for(let msq of magics[kind]){
-        magics_go += `   {Square${msq.squareUci.toUpperCase()}, ${msq.shift.padStart(3, " ")}, 0x${msq.magic.toLowerCase()}, []Bitboard{}},\n`
+        magics_go += `   {Square${msq.squareUci.toUpperCase()}, ${msq.shift.padStart(3, " ")}, 0x${msq.magic.toLowerCase()}, 0x${msq.magicHi.toLowerCase()}, Bitboard{}, []Bitboard{}},\n`
     }
     magics_go += "}"
 }
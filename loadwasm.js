@@ -5,6 +5,9 @@ if (!WebAssembly.instantiateStreaming) { // polyfill
 		};
 }
 
+// the engine output is passed on to the page
+self.gobbitOutput = text => self.postMessage({text: text})
+
 const go = new Go();
 let mod, inst;
 WebAssembly.instantiateStreaming(fetch("main.wasm"), go.importObject).then((result) => {
@@ -25,35 +28,12 @@ async function run() {
 	inst = await WebAssembly.instantiate(mod, go.importObject); // reset instance			
 }
 
-// define a new console
-let console=(function(oldCons){
-  return {
-      log: function(text){
-        self.postMessage({text: text})
-      },
-      info: function (text) {
-        self.postMessage({text: text})
-      },
-      warn: function (text) {
-        self.postMessage({text: text})
-      },
-      error: function (text) {
-        self.postMessage({text: text})
-      }
-  }
-}(self.console))
-
-const oldCons = self.console
-
-// redefine the old console
-self.console = console
-
 self.addEventListener("message", event => {
 	let data = event.data
 
 	let command = data.command
 
-	oldCons.log("received command " + command)
+	console.log("received command " + command)
 
 	ExecUciCommandLineWasm(command)        
 })
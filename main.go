@@ -3,24 +3,61 @@
 package main
 
 import (	
-	"fmt"
+	"bufio"
+	"os"
+	"strings"
 	. "github.com/easychessanimations/gobbit/uci"
+	"github.com/easychessanimations/gobbit/cecp"
+	"github.com/easychessanimations/gobbit/server"
 )
 
 func main() {
-	fmt.Println()
-
 	uci := Uci{}
 
+	// flags decide the format of the output, so they come first
+	uci.ProcessFlags()
+
+	uci.Println()
+
 	uci.Init(ENGINE_NAME, ENGINE_AUTHOR, UCI_COMMAND_ALIASES)
 
 	uci.Welcome(" [ native build ]")
 
+	// gobbit serve [--addr host:port] [--pool engines] runs the analysis server instead of the protocol loop
+	if len(os.Args) > 1 && os.Args[1] == server.SERVE_COMMAND{
+		if err := server.Serve(os.Args[2:], uci.Pos.Output()); err != nil{
+			uci.Println(err)
+		}
+
+		return
+	}
+
 	uci.ProcessConfig()
 
 	uci.ProcessMatePuzzles()
 
 	uci.ProcessCommandLine()
 
-	uci.UciLoop()
+	scan := bufio.NewScanner(os.Stdin)
+
+	// the first line decides the protocol, xboard switches to CECP
+	if scan.Scan(){
+		firstLine := strings.TrimSpace(scan.Text())
+
+		if firstLine == "xboard"{
+			xb := cecp.Cecp{}
+
+			xb.Init(ENGINE_NAME, &uci.Pos)
+
+			xb.Loop(scan)
+
+			return
+		}
+
+		if uci.ExecUciCommandLine(firstLine) != nil{
+			return
+		}
+	}
+
+	uci.UciLoop(scan)
 }
@@ -7,4 +7,6 @@ var UCI_COMMAND_ALIASES = map[string]string{
 	"vs" : "setoption name UCI_Variant value Standard",
 	"ve" : "setoption name UCI_Variant value Eightpiece",
 	"va" : "setoption name UCI_Variant value Atomic",
+	"v3" : "setoption name UCI_Variant value Three-check",
+	"vk" : "setoption name UCI_Variant value King of the Hill",
 }
@@ -5,6 +5,7 @@ package main
 import (		
 	"syscall/js"
 	"time"	
+	"github.com/easychessanimations/gobbit/basic"
 	. "github.com/easychessanimations/gobbit/uci"
 )
 
@@ -17,6 +18,13 @@ func ExecUciCommandLineWasm(this js.Value, commandLineArray []js.Value) interfac
 }
 
 func main() {
+	// the page receives the output through the gobbitOutput callback, if it defines one, otherwise it goes to the console
+	if output := js.Global().Get("gobbitOutput"); output.Type() == js.TypeFunction{
+		basic.DefaultOutput = basic.OutputFunc(func(text string){
+			output.Invoke(text)
+		})
+	}
+
 	uci = Uci{}
 
 	uci.Init(ENGINE_NAME, ENGINE_AUTHOR, UCI_COMMAND_ALIASES)
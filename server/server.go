@@ -0,0 +1,477 @@
+// Package server serves analysis over HTTP and websocket, for web front-ends running gobbit locally
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	. "github.com/easychessanimations/gobbit/basic"
+	"github.com/easychessanimations/gobbit/engine"
+	"github.com/easychessanimations/gobbit/uci"
+)
+
+// the command of the executable that starts the server
+const SERVE_COMMAND = "serve"
+
+const DEFAULT_ADDR = "localhost:8080"
+const DEFAULT_POOL_SIZE = 4
+
+// requests of the REST endpoints are limited, websocket analysis runs until stopped if it has no depth
+const MAX_ANALYZE_DEPTH = 30
+const DEFAULT_ANALYZE_DEPTH = 12
+const MAX_PERFT_DEPTH = 5
+
+// GameRequest is the position of a request, the moves played from the start position
+type GameRequest struct{
+	// display name or pgn alias of the variant, Standard if empty
+	Variant string   `json:"variant"`
+	// start position, the start position of the variant if empty
+	Fen     string   `json:"fen"`
+	// moves in UCI notation
+	Moves   []string `json:"moves"`
+}
+
+// AnalyzeRequest is the body of POST /analyze and of the analyze websocket command
+type AnalyzeRequest struct{
+	GameRequest
+	Depth   int `json:"depth"`
+	MultiPv int `json:"multipv"`
+}
+
+type AnalyzeResponse struct{
+	Infos    []uci.JsonInfoEvent `json:"infos"`
+	BestMove string              `json:"bestmove"`
+	Ponder   string              `json:"ponder,omitempty"`
+}
+
+// PerftRequest is the body of POST /perft
+type PerftRequest struct{
+	GameRequest
+	Depth int `json:"depth"`
+}
+
+type PerftResponse struct{
+	Depth int `json:"depth"`
+	Nodes int `json:"nodes"`
+}
+
+// WsCommand is a message of the websocket client, analyze or stop
+type WsCommand struct{
+	Id      interface{} `json:"id,omitempty"`
+	Command string      `json:"command"`
+	AnalyzeRequest
+}
+
+// Game converts the request to the game of the engine
+func (gr GameRequest) Game() (engine.Game, error){
+	game := engine.Game{Variant: VariantStandard, Fen: gr.Fen, Moves: gr.Moves}
+
+	if gr.Variant != ""{
+		variant, ok := LookupVariant(gr.Variant)
+
+		if !ok{
+			return game, fmt.Errorf("unknown variant %s", gr.Variant)
+		}
+
+		game.Variant = variant
+	}
+
+	return game, nil
+}
+
+// Pool holds the engines, a request takes one and gives it back when done, so at most as many searches run as there are engines
+type Pool struct{
+	engines chan *engine.Engine
+}
+
+func NewPool(size int) *Pool{
+	if size < 1{
+		size = 1
+	}
+
+	pool := &Pool{engines: make(chan *engine.Engine, size)}
+
+	for i := 0; i < size; i++{
+		pool.engines <- engine.New()
+	}
+
+	return pool
+}
+
+// Get waits for a free engine
+func (pool *Pool) Get(ctx context.Context) (*engine.Engine, error){
+	select{
+	case e := <-pool.engines:
+		return e, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (pool *Pool) Put(e *engine.Engine){
+	pool.engines <- e
+}
+
+// Server handles the endpoints
+//   POST /analyze        search a position to a depth, the infos of all iterations and the best move
+//   GET  /legalmoves     ?variant=&fen=&moves= ( moves separated by spaces or commas ), the fen and the legal moves
+//   POST /perft          count the leaves of the move tree
+//   GET  /ws             websocket, analyze commands stream info events until the best move, stop ends the search
+// web pages of other origins can not use the server, browsers send json POST requests of other origins only after a preflight the server does not answer,
+// websocket upgrades are checked against the Origin header
+type Server struct{
+	Pool      *Pool
+	// origins besides the server's own that may open websockets, like http://localhost:3000
+	AllowedOrigins []string
+	// sets up the positions of requests that do not search, this is safe while it is used concurrently
+	positions *engine.Engine
+	mux       *http.ServeMux
+}
+
+func NewServer(poolSize int) *Server{
+	server := &Server{
+		Pool: NewPool(poolSize),
+		positions: engine.New(),
+		mux: http.NewServeMux(),
+	}
+
+	server.mux.HandleFunc("/analyze", server.HandleAnalyze)
+	server.mux.HandleFunc("/legalmoves", server.HandleLegalMoves)
+	server.mux.HandleFunc("/perft", server.HandlePerft)
+	server.mux.HandleFunc("/ws", server.HandleWebSocket)
+
+	return server
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request){
+	server.mux.ServeHTTP(w, r)
+}
+
+// CheckOrigin tells whether the websocket request comes from the server's own origin, an allowed origin or from a client that is not a browser
+func (server *Server) CheckOrigin(r *http.Request) bool{
+	origin := r.Header.Get("Origin")
+
+	if origin == ""{
+		return true
+	}
+
+	for _, allowed := range server.AllowedOrigins{
+		if allowed == "*" || strings.EqualFold(allowed, origin){
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func errorEvent(id interface{}, message string) uci.JsonErrorEvent{
+	return uci.JsonErrorEvent{
+		JsonEvent: uci.JsonEvent{Type: "error", Id: id},
+		Message: message,
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}){
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string){
+	writeJson(w, status, errorEvent(nil, message))
+}
+
+// readRequest decodes the json body of a POST request, it writes the error response if it fails
+func readRequest(w http.ResponseWriter, r *http.Request, request interface{}) bool{
+	if r.Method != http.MethodPost{
+		writeError(w, http.StatusMethodNotAllowed, "POST expected")
+		return false
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json"{
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type application/json expected")
+		return false
+	}
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, WEBSOCKET_MAX_MESSAGE)).Decode(request); err != nil{
+		writeError(w, http.StatusBadRequest, "invalid request: " + err.Error())
+		return false
+	}
+
+	return true
+}
+
+// analyze runs the search with an engine of the pool, every message is passed to the callback
+func (server *Server) analyze(ctx context.Context, request AnalyzeRequest, callback func(info engine.Info)) error{
+	game, err := request.Game()
+
+	if err != nil{
+		return err
+	}
+
+	e, err := server.Pool.Get(ctx)
+
+	if err != nil{
+		return err
+	}
+
+	defer server.Pool.Put(e)
+
+	limits := engine.Limits{Depth: request.Depth, MultiPV: request.MultiPv}
+
+	if limits.Depth <= 0{
+		limits.SearchLimits.Infinite = true
+	}
+
+	infos, err := e.Analyze(ctx, game, limits)
+
+	if err != nil{
+		return err
+	}
+
+	for info := range infos{
+		callback(info)
+	}
+
+	return nil
+}
+
+func (server *Server) HandleAnalyze(w http.ResponseWriter, r *http.Request){
+	request := AnalyzeRequest{}
+
+	if !readRequest(w, r, &request){
+		return
+	}
+
+	if request.Depth <= 0{
+		request.Depth = DEFAULT_ANALYZE_DEPTH
+	}
+
+	if request.Depth > MAX_ANALYZE_DEPTH{
+		request.Depth = MAX_ANALYZE_DEPTH
+	}
+
+	response := AnalyzeResponse{Infos: []uci.JsonInfoEvent{}}
+
+	err := server.analyze(r.Context(), request, func(info engine.Info){
+		if info.Final{
+			bestMove := uci.JsonBestMove(nil, info.BestMove, info.Ponder)
+
+			response.BestMove, response.Ponder = bestMove.BestMove, bestMove.Ponder
+		}else{
+			response.Infos = append(response.Infos, uci.JsonInfo(nil, info.MultiPv))
+		}
+	})
+
+	if err != nil{
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+func (server *Server) position(request GameRequest) (*Position, error){
+	game, err := request.Game()
+
+	if err != nil{
+		return nil, err
+	}
+
+	return server.positions.Position(game)
+}
+
+func (server *Server) HandleLegalMoves(w http.ResponseWriter, r *http.Request){
+	if r.Method != http.MethodGet{
+		writeError(w, http.StatusMethodNotAllowed, "GET expected")
+		return
+	}
+
+	query := r.URL.Query()
+
+	request := GameRequest{
+		Variant: query.Get("variant"),
+		Fen: query.Get("fen"),
+		Moves: strings.FieldsFunc(query.Get("moves"), func(c rune) bool{
+			return c == ' ' || c == ','
+		}),
+	}
+
+	pos, err := server.position(request)
+
+	if err != nil{
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJson(w, http.StatusOK, uci.JsonPosition(nil, pos))
+}
+
+func (server *Server) HandlePerft(w http.ResponseWriter, r *http.Request){
+	request := PerftRequest{}
+
+	if !readRequest(w, r, &request){
+		return
+	}
+
+	if request.Depth < 1 || request.Depth > MAX_PERFT_DEPTH{
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("depth should be 1 - %d", MAX_PERFT_DEPTH))
+		return
+	}
+
+	pos, err := server.position(request.GameRequest)
+
+	if err != nil{
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the perft hash table is shared by the process
+	pos.PerftHash = false
+
+	// perft takes a search slot
+	e, err := server.Pool.Get(r.Context())
+
+	if err != nil{
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	defer server.Pool.Put(e)
+
+	writeJson(w, http.StatusOK, PerftResponse{request.Depth, pos.Perft(request.Depth)})
+}
+
+// HandleWebSocket runs a session, one analysis at a time
+// {"id": 1, "command": "analyze", "variant": "Atomic", "moves": ["e2e4"], "multipv": 2} starts an analysis, without depth it runs until stopped
+// {"command": "stop"} stops it, the best move is sent anyway
+func (server *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request){
+	if !server.CheckOrigin(r){
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+
+	wc, err := UpgradeWebSocket(w, r)
+
+	if err != nil{
+		return
+	}
+
+	emit := func(event interface{}){
+		buff, _ := json.Marshal(event)
+
+		wc.WriteText(string(buff))
+	}
+
+	ctx, cancelSession := context.WithCancel(r.Context())
+
+	mutex := sync.Mutex{}
+	var cancelSearch context.CancelFunc
+	searches := sync.WaitGroup{}
+
+	// a search still running is stopped when the client goes away
+	defer func(){
+		cancelSession()
+		searches.Wait()
+		wc.Close()
+	}()
+
+	for{
+		message, err := wc.ReadText()
+
+		if err != nil{
+			return
+		}
+
+		command := WsCommand{}
+
+		if err := json.Unmarshal([]byte(message), &command); err != nil{
+			emit(errorEvent(nil, "invalid request: " + err.Error()))
+			continue
+		}
+
+		switch command.Command{
+		case "analyze":
+			mutex.Lock()
+
+			if cancelSearch != nil{
+				mutex.Unlock()
+				emit(errorEvent(command.Id, "already analyzing"))
+				continue
+			}
+
+			searchCtx, cancel := context.WithCancel(ctx)
+			cancelSearch = cancel
+
+			mutex.Unlock()
+
+			searches.Add(1)
+
+			go func(command WsCommand){
+				defer searches.Done()
+
+				err := server.analyze(searchCtx, command.AnalyzeRequest, func(info engine.Info){
+					if info.Final{
+						emit(uci.JsonBestMove(command.Id, info.BestMove, info.Ponder))
+					}else{
+						emit(uci.JsonInfo(command.Id, info.MultiPv))
+					}
+				})
+
+				if err != nil{
+					emit(errorEvent(command.Id, err.Error()))
+				}
+
+				mutex.Lock()
+				cancelSearch()
+				cancelSearch = nil
+				mutex.Unlock()
+			}(command)
+		case "stop":
+			mutex.Lock()
+
+			if cancelSearch != nil{
+				cancelSearch()
+			}
+
+			mutex.Unlock()
+		default:
+			emit(errorEvent(command.Id, "unknown command " + command.Command))
+		}
+	}
+}
+
+// Serve parses the arguments of the serve command and serves until the listener fails
+func Serve(args []string, out Output) error{
+	flags := flag.NewFlagSet(SERVE_COMMAND, flag.ContinueOnError)
+
+	addr := flags.String("addr", DEFAULT_ADDR, "address to listen on")
+	poolSize := flags.Int("pool", DEFAULT_POOL_SIZE, "number of engines, the max number of concurrent searches")
+	origins := flags.String("origins", "", "comma separated origins of web pages besides the server's own that may open websockets, * for any")
+
+	if err := flags.Parse(args); err != nil{
+		return err
+	}
+
+	server := NewServer(*poolSize)
+
+	for _, origin := range strings.Split(*origins, ","){
+		if origin = strings.TrimSpace(origin); origin != ""{
+			server.AllowedOrigins = append(server.AllowedOrigins, origin)
+		}
+	}
+
+	out.Println(fmt.Sprintf("serving on %s with %d engines", *addr, *poolSize))
+
+	return http.ListenAndServe(*addr, server)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func post(t *testing.T, url string, body string, response interface{}) int {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+
+	if err != nil {
+		t.Fatalf("post %s failed %v", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		t.Fatalf("post %s invalid response %v", url, err)
+	}
+
+	return resp.StatusCode
+}
+
+func TestRest(t *testing.T) {
+	ts := httptest.NewServer(NewServer(2))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/legalmoves?variant=Atomic&moves=e2e4,e7e5")
+
+	if err != nil {
+		t.Fatalf("legalmoves failed %v", err)
+	}
+
+	position := struct {
+		Fen   string
+		Moves []struct{ Uci string }
+	}{}
+
+	json.NewDecoder(resp.Body).Decode(&position)
+	resp.Body.Close()
+
+	if position.Fen != "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2" || len(position.Moves) == 0 {
+		t.Errorf("wrong position %v", position)
+	}
+
+	perft := PerftResponse{}
+
+	if status := post(t, ts.URL+"/perft", `{"variant": "Crazyhouse", "depth": 3}`, &perft); status != http.StatusOK || perft.Nodes != 8902 {
+		t.Errorf("perft got %d %v", status, perft)
+	}
+
+	// concurrent analyses share the pool
+	wg := sync.WaitGroup{}
+
+	for _, body := range []string{
+		`{"moves": ["e2e4"], "depth": 3, "multipv": 2}`,
+		`{"variant": "Three-check", "depth": 3}`,
+		`{"variant": "duck chess", "depth": 2}`,
+	} {
+		wg.Add(1)
+
+		go func(body string) {
+			defer wg.Done()
+
+			analysis := AnalyzeResponse{}
+
+			if status := post(t, ts.URL+"/analyze", body, &analysis); status != http.StatusOK || analysis.BestMove == "" || len(analysis.Infos) == 0 {
+				t.Errorf("%s analysis got %d %v", body, status, analysis)
+			}
+		}(body)
+	}
+
+	wg.Wait()
+
+	errorEvent := struct{ Type, Message string }{}
+
+	if status := post(t, ts.URL+"/analyze", `{"variant": "No Such Variant"}`, &errorEvent); status != http.StatusBadRequest || errorEvent.Type != "error" {
+		t.Errorf("unknown variant got %d %v", status, errorEvent)
+	}
+
+	if status := post(t, ts.URL+"/perft", `{"moves": ["e2e5"], "depth": 1}`, &errorEvent); status != http.StatusBadRequest {
+		t.Errorf("illegal move got %d %v", status, errorEvent)
+	}
+
+	// forms of other web pages can post without a preflight, but not json
+	resp, err = http.Post(ts.URL+"/analyze", "text/plain", strings.NewReader(`{"depth": 30}`))
+
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("analyze without json content type should be refused")
+	}
+
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	ts := httptest.NewServer(NewServer(1))
+	defer ts.Close()
+
+	wsUrl := strings.Replace(ts.URL, "http://", "ws://", 1) + "/ws"
+
+	// pages of other origins are refused
+	if _, err := DialWebSocket(wsUrl, "http://example.com"); err == nil {
+		t.Errorf("websocket of another origin should be refused")
+	}
+
+	wc, err := DialWebSocket(wsUrl, ts.URL)
+
+	if err != nil {
+		t.Fatalf("dial failed %v", err)
+	}
+
+	defer wc.Close()
+
+	read := func() (event struct {
+		Type     string
+		Id       interface{}
+		Depth    int
+		BestMove string
+	}) {
+		text, err := wc.ReadText()
+
+		if err != nil {
+			t.Fatalf("read failed %v", err)
+		}
+
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			t.Fatalf("invalid event %s", text)
+		}
+
+		return
+	}
+
+	// without depth the analysis runs until stopped
+	wc.WriteText(`{"id": 7, "command": "analyze", "variant": "Atomic"}`)
+
+	if event := read(); event.Type != "info" || event.Id != 7.0 || event.Depth != 1 {
+		t.Fatalf("expected info of depth 1 got %v", event)
+	}
+
+	wc.WriteText(`{"id": 8, "command": "analyze"}`)
+	wc.WriteText(`{"command": "stop"}`)
+
+	gotError := false
+
+	for {
+		event := read()
+
+		if event.Type == "error" && event.Id == 8.0 {
+			gotError = true
+		}
+
+		if event.Type == "bestmove" {
+			if event.Id != 7.0 || event.BestMove == "" {
+				t.Errorf("wrong best move %v", event)
+			}
+
+			break
+		}
+	}
+
+	if !gotError {
+		t.Errorf("second analysis should be refused while analyzing")
+	}
+
+	// the session can analyze again after the stop
+	wc.WriteText(`{"id": "again", "command": "analyze", "depth": 2}`)
+
+	for {
+		if event := read(); event.Type == "bestmove" {
+			if event.Id != "again" {
+				t.Errorf("wrong id %v", event.Id)
+			}
+
+			break
+		}
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	server := NewServer(1)
+	server.AllowedOrigins = []string{"http://localhost:3000"}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	wsUrl := strings.Replace(ts.URL, "http://", "ws://", 1) + "/ws"
+
+	for origin, allowed := range map[string]bool{
+		"":                      true,
+		"http://localhost:3000": true,
+		"http://localhost:3001": false,
+	} {
+		wc, err := DialWebSocket(wsUrl, origin)
+
+		if (err == nil) != allowed {
+			t.Errorf("origin %s allowed %v got error %v", origin, allowed, err)
+		}
+
+		if err == nil {
+			wc.Close()
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// a minimal websocket ( RFC 6455 ), text messages, ping and close, enough for the analysis stream
+
+const WEBSOCKET_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// messages longer than this close the connection
+const WEBSOCKET_MAX_MESSAGE = 1 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var ErrMessageTooLong = errors.New("websocket message too long")
+
+// WsConn is a websocket connection, reads from one goroutine and writes from any
+type WsConn struct{
+	conn       net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+	// clients mask their frames, servers do not
+	client     bool
+}
+
+// WebSocketAccept computes the Sec-WebSocket-Accept header for the key of the client
+func WebSocketAccept(key string) string{
+	hash := sha1.Sum([]byte(key + WEBSOCKET_GUID))
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func headerContains(r *http.Request, name, token string) bool{
+	for _, value := range r.Header.Values(name){
+		for _, item := range strings.Split(value, ","){
+			if strings.EqualFold(strings.TrimSpace(item), token){
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// UpgradeWebSocket answers the handshake and takes over the connection of the request
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WsConn, error){
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if r.Method != http.MethodGet || !headerContains(r, "Upgrade", "websocket") || !headerContains(r, "Connection", "upgrade") || key == ""{
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, fmt.Errorf("not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13"{
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("unsupported websocket version %s", r.Header.Get("Sec-WebSocket-Version"))
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+
+	if !ok{
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("connection can not be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+
+	if err != nil{
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + WebSocketAccept(key) + "\r\n\r\n")
+
+	if err := rw.Flush(); err != nil{
+		conn.Close()
+		return nil, err
+	}
+
+	return &WsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// DialWebSocket connects to a websocket url ( ws://host/path ), for tests and tools, the Origin header is sent if origin is not empty
+func DialWebSocket(url string, origin string) (*WsConn, error){
+	if !strings.HasPrefix(url, "ws://"){
+		return nil, fmt.Errorf("only ws:// urls are supported, got %s", url)
+	}
+
+	hostPath := strings.TrimPrefix(url, "ws://")
+	host, path := hostPath, "/"
+
+	if i := strings.Index(hostPath, "/"); i >= 0{
+		host, path = hostPath[:i], hostPath[i:]
+	}
+
+	conn, err := net.Dial("tcp", host)
+
+	if err != nil{
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	originHeader := ""
+
+	if origin != ""{
+		originHeader = "Origin: " + origin + "\r\n"
+	}
+
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\n%sUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", path, host, originHeader, key)
+
+	reader := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(reader, nil)
+
+	if err != nil{
+		conn.Close()
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != WebSocketAccept(key){
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed %s", resp.Status)
+	}
+
+	return &WsConn{conn: conn, reader: reader, client: true}, nil
+}
+
+func (wc *WsConn) writeFrame(opcode byte, payload []byte) error{
+	wc.writeMutex.Lock()
+	defer wc.writeMutex.Unlock()
+
+	header := []byte{0x80 | opcode}
+
+	maskBit := byte(0)
+
+	if wc.client{
+		maskBit = 0x80
+	}
+
+	length := len(payload)
+
+	switch{
+	case length < 126:
+		header = append(header, maskBit | byte(length))
+	case length < 1 << 16:
+		header = append(header, maskBit | 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, maskBit | 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if wc.client{
+		mask := make([]byte, 4)
+		rand.Read(mask)
+
+		header = append(header, mask...)
+
+		masked := make([]byte, length)
+
+		for i := range payload{
+			masked[i] = payload[i] ^ mask[i % 4]
+		}
+
+		payload = masked
+	}
+
+	if _, err := wc.conn.Write(append(header, payload...)); err != nil{
+		return err
+	}
+
+	return nil
+}
+
+// WriteText sends a text message
+func (wc *WsConn) WriteText(text string) error{
+	return wc.writeFrame(opText, []byte(text))
+}
+
+func (wc *WsConn) readFrame() (fin bool, opcode byte, payload []byte, err error){
+	header := make([]byte, 2)
+
+	if _, err = io.ReadFull(wc.reader, header); err != nil{
+		return
+	}
+
+	fin = header[0] & 0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1] & 0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	switch length{
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(wc.reader, ext); err != nil{
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(wc.reader, ext); err != nil{
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > WEBSOCKET_MAX_MESSAGE{
+		err = ErrMessageTooLong
+		return
+	}
+
+	mask := make([]byte, 4)
+
+	if masked{
+		if _, err = io.ReadFull(wc.reader, mask); err != nil{
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+
+	if _, err = io.ReadFull(wc.reader, payload); err != nil{
+		return
+	}
+
+	if masked{
+		for i := range payload{
+			payload[i] ^= mask[i % 4]
+		}
+	}
+
+	return
+}
+
+// ReadText returns the next data message, pings are answered on the way, io.EOF when the peer closes
+func (wc *WsConn) ReadText() (string, error){
+	message := []byte{}
+
+	for{
+		fin, opcode, payload, err := wc.readFrame()
+
+		if err != nil{
+			return "", err
+		}
+
+		switch opcode{
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+
+			if len(message) > WEBSOCKET_MAX_MESSAGE{
+				return "", ErrMessageTooLong
+			}
+
+			if fin{
+				return string(message), nil
+			}
+		case opPing:
+			wc.writeFrame(opPong, payload)
+		case opClose:
+			wc.writeFrame(opClose, payload)
+			return "", io.EOF
+		}
+	}
+}
+
+// Close sends a close frame and closes the connection
+func (wc *WsConn) Close() error{
+	wc.writeFrame(opClose, nil)
+
+	return wc.conn.Close()
+}
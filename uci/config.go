@@ -13,6 +13,9 @@ const DEFAULT_VARIANT = VariantStandard
 
 const DEFAULT_DEPTH = 10
 
+// variants defined in this file are added to the built in ones at startup
+const VARIANTS_FILE = "variants.ini"
+
 type UciOption struct{
 	Name string
 	Type string
@@ -23,6 +26,19 @@ type UciOption struct{
 	Max int
 }
 
+// NewUciOptions copies UCI_OPTIONS for a front-end, UCI_Variant lists the variants registered so far
+func NewUciOptions() []UciOption{
+	options := append([]UciOption{}, UCI_OPTIONS...)
+
+	for i, uo := range options{
+		if uo.Name == "UCI_Variant"{
+			options[i].Vars = append([]string{}, VARIANT_NAMES...)
+		}
+	}
+
+	return options
+}
+
 var UCI_OPTIONS = []UciOption{
 	{
 		Name: "UCI_Variant",
@@ -30,6 +46,29 @@ var UCI_OPTIONS = []UciOption{
 		Vars: VARIANT_NAMES,
 		Default: VARIANT_NAMES[DEFAULT_VARIANT],
 	},
+	{
+		Name: "UCI_Chess960",
+		Type: "check",
+		Default: "false",
+	},
+	{
+		Name: "Hash",
+		Type: "spin",
+		Min: 1,
+		Max: 4096,
+		Default: fmt.Sprintf("%d", DEFAULT_HASH_SIZE_IN_MB),
+	},
+	{
+		Name: "Threads",
+		Type: "spin",
+		Min: 1,
+		Max: MAX_THREADS,
+		Default: "1",
+	},
+	{
+		Name: "Clear Hash",
+		Type: "button",
+	},
 	{
 		Name: "MultiPV",
 		Type: "spin",		
@@ -37,6 +76,35 @@ var UCI_OPTIONS = []UciOption{
 		Max: 20,
 		Default: "1",
 	},
+	{
+		Name: "Move Overhead",
+		Type: "spin",
+		Min: 0,
+		Max: 5000,
+		Default: fmt.Sprintf("%d", DEFAULT_MOVE_OVERHEAD),
+	},
+	{
+		Name: "OwnBook",
+		Type: "check",
+		Default: "false",
+	},
+	{
+		Name: "Book File",
+		Type: "string",
+		Default: DEFAULT_BOOK_FILE,
+	},
+	{
+		Name: "Book Depth",
+		Type: "spin",
+		Min: 1,
+		Max: DEFAULT_BOOK_DEPTH,
+		Default: fmt.Sprintf("%d", DEFAULT_BOOK_DEPTH),
+	},
+	{
+		Name: "Best Book Move",
+		Type: "check",
+		Default: "false",
+	},
 	{
 		Name: "Null Move Pruning",
 		Type: "check",		
@@ -75,11 +143,33 @@ var UCI_OPTIONS = []UciOption{
 		Max: 3,
 		Default: "3",
 	},
+	{
+		Name: "Duck Squares",
+		Type: "spin",		
+		Min: 1,
+		Max: 20,
+		Default: "3",
+	},
 	{
 		Name: "Aspiration Window",
 		Type: "check",		
 		Default: "true",
 	},
+	{
+		Name: "Perft Hash",
+		Type: "check",
+		Default: "true",
+	},
+	{
+		Name: "Verify Hash",
+		Type: "check",
+		Default: "false",
+	},
+	{
+		Name: "Quiescence",
+		Type: "check",		
+		Default: "true",
+	},
 	{
 		Name: "Verbose",
 		Type: "check",		
@@ -115,6 +205,10 @@ func (uo UciOption) IntValue() int{
 }
 
 func (uo UciOption) UciCommandOutputString() string{
+	if uo.Type == "button"{
+		return fmt.Sprintf("option name %s type %s", uo.Name, uo.Type)
+	}
+
 	buff := fmt.Sprintf("option name %s type %s default %s", uo.Name, uo.Type, uo.Default)
 
 	vbuff := []string{}
@@ -0,0 +1,307 @@
+package uci
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+// in json mode every output of the engine is a json object on a line of its own, tagged by type
+// requests are json objects with the command line and an id, the events caused by a request carry its id
+
+// JsonRequest is a command sent in json mode
+type JsonRequest struct{
+	// echoed in the events caused by the command, any json value
+	Id      interface{} `json:"id,omitempty"`
+	Command string      `json:"command"`
+}
+
+// JsonEvent is the part common to all events
+type JsonEvent struct{
+	Type string      `json:"type"`
+	Id   interface{} `json:"id,omitempty"`
+}
+
+// JsonTextEvent carries output that has no event type of its own
+type JsonTextEvent struct{
+	JsonEvent
+	Text string `json:"text"`
+}
+
+// JsonErrorEvent reports an invalid command or request
+type JsonErrorEvent struct{
+	JsonEvent
+	Message string `json:"message"`
+}
+
+type JsonMove struct{
+	Uci string `json:"uci"`
+	San string `json:"san"`
+	Lan string `json:"lan"`
+}
+
+// JsonPositionEvent is the printout of the position with its legal moves
+type JsonPositionEvent struct{
+	JsonEvent
+	Variant string     `json:"variant"`
+	Fen     string     `json:"fen"`
+	Moves   []JsonMove `json:"moves"`
+}
+
+// JsonInfoEvent is the result of a completed iteration for one principal variation
+type JsonInfoEvent struct{
+	JsonEvent
+	MultiPv int      `json:"multipv"`
+	Depth   int      `json:"depth"`
+	Time    int      `json:"time"`
+	Nodes   int      `json:"nodes"`
+	QNodes  int      `json:"qnodes"`
+	Nps     float32  `json:"nps"`
+	Score   int      `json:"score"`
+	Pv      []string `json:"pv"`
+}
+
+type JsonBestMoveEvent struct{
+	JsonEvent
+	BestMove string `json:"bestmove"`
+	Ponder   string `json:"ponder,omitempty"`
+}
+
+type JsonOption struct{
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Default string   `json:"default,omitempty"`
+	Value   string   `json:"value,omitempty"`
+	Min     int      `json:"min,omitempty"`
+	Max     int      `json:"max,omitempty"`
+	Vars    []string `json:"vars,omitempty"`
+}
+
+// JsonOptionsEvent answers the uci command
+type JsonOptionsEvent struct{
+	JsonEvent
+	Name    string       `json:"name"`
+	Author  string       `json:"author"`
+	Options []JsonOption `json:"options"`
+}
+
+// JsonOutput writes events as json lines, output from the search goroutine and the command loop are serialized
+type JsonOutput struct{
+	mutex sync.Mutex
+	// where the json lines go
+	Out   Output
+	// id of the request being executed
+	id    interface{}
+}
+
+// Emit writes the event as a json line
+func (jo *JsonOutput) Emit(event interface{}){
+	jo.mutex.Lock()
+	defer jo.mutex.Unlock()
+
+	jo.emit(event)
+}
+
+func (jo *JsonOutput) emit(event interface{}){
+	buff, err := json.Marshal(event)
+
+	if err != nil{
+		buff, _ = json.Marshal(JsonErrorEvent{JsonEvent{"error", jo.id}, err.Error()})
+	}
+
+	jo.Out.Println(string(buff))
+}
+
+// Println wraps plain text output into text events
+func (jo *JsonOutput) Println(text string){
+	jo.mutex.Lock()
+	defer jo.mutex.Unlock()
+
+	jo.emit(JsonTextEvent{JsonEvent{"text", jo.id}, text})
+}
+
+// Id returns the id of the current request
+func (jo *JsonOutput) Id() interface{}{
+	jo.mutex.Lock()
+	defer jo.mutex.Unlock()
+
+	return jo.id
+}
+
+func (jo *JsonOutput) setId(id interface{}){
+	jo.mutex.Lock()
+	defer jo.mutex.Unlock()
+
+	jo.id = id
+}
+
+// SetJsonMode turns json mode on or off
+func (uci *Uci) SetJsonMode(on bool){
+	if ( uci.json != nil ) == on{
+		return
+	}
+
+	if on{
+		uci.json = &JsonOutput{Out: uci.Pos.Output()}
+		uci.SetOutput(uci.json)
+		uci.Pos.PrintHook = uci.EmitPosition
+	}else{
+		uci.SetOutput(uci.json.Out)
+		uci.Pos.PrintHook = nil
+		uci.json = nil
+	}
+}
+
+// JsonMode tells whether the engine talks json
+func (uci *Uci) JsonMode() bool{
+	return uci.json != nil
+}
+
+// ExecJsonRequest executes the command of a json request
+func (uci *Uci) ExecJsonRequest(line string) error{
+	request := JsonRequest{}
+
+	if err := json.Unmarshal([]byte(line), &request); err != nil{
+		uci.Error("invalid request: " + err.Error())
+		return nil
+	}
+
+	uci.json.setId(request.Id)
+	defer uci.json.setId(nil)
+
+	if strings.TrimSpace(request.Command) == ""{
+		uci.Error("request without command")
+		return nil
+	}
+
+	return uci.ExecUciCommandLine(request.Command)
+}
+
+// Error reports an invalid command, as an error event in json mode
+func (uci *Uci) Error(message string){
+	if uci.json == nil{
+		uci.Println(message)
+		return
+	}
+
+	uci.json.Emit(JsonErrorEvent{JsonEvent{"error", uci.json.Id()}, message})
+}
+
+// JsonPosition describes the position with its legal moves
+func JsonPosition(id interface{}, pos *Position) JsonPositionEvent{
+	st := pos.Current()
+
+	st.GenMoveBuff()
+
+	moves := []JsonMove{}
+
+	for _, mbi := range st.MoveBuff{
+		moves = append(moves, JsonMove{mbi.Uci, mbi.San, mbi.Lan})
+	}
+
+	return JsonPositionEvent{
+		JsonEvent: JsonEvent{"position", id},
+		Variant: VariantInfos[st.Variant].DisplayName,
+		Fen: st.ReportFen(),
+		Moves: moves,
+	}
+}
+
+// JsonInfo converts the result of an iteration
+func JsonInfo(id interface{}, mpi MultiPvInfo) JsonInfoEvent{
+	pv := []string{}
+
+	for _, move := range mpi.Pv{
+		pv = append(pv, move.UCI())
+	}
+
+	return JsonInfoEvent{
+		JsonEvent: JsonEvent{"info", id},
+		MultiPv: mpi.Index,
+		Depth: mpi.Depth,
+		Time: mpi.Time,
+		Nodes: mpi.Nodes,
+		QNodes: mpi.QNodes,
+		Nps: mpi.Nps,
+		Score: int(mpi.Score),
+		Pv: pv,
+	}
+}
+
+// JsonBestMove converts the best move and the expected reply, NullMove is left out
+func JsonBestMove(id interface{}, bestMove, ponder Move) JsonBestMoveEvent{
+	event := JsonBestMoveEvent{
+		JsonEvent: JsonEvent{"bestmove", id},
+	}
+
+	if bestMove != NullMove{
+		event.BestMove = bestMove.UCI()
+	}
+
+	if ponder != NullMove{
+		event.Ponder = ponder.UCI()
+	}
+
+	return event
+}
+
+// EmitPosition emits the position with its legal moves
+func (uci *Uci) EmitPosition(pos *Position){
+	uci.json.Emit(JsonPosition(uci.json.Id(), pos))
+}
+
+// EmitOptions answers the uci command with the engine id and the options
+func (uci *Uci) EmitOptions(){
+	options := []JsonOption{}
+
+	for _, uo := range uci.UciOptions{
+		options = append(options, JsonOption{
+			Name: uo.Name,
+			Type: uo.Type,
+			Default: uo.Default,
+			Value: uo.Value,
+			Min: uo.Min,
+			Max: uo.Max,
+			Vars: uo.Vars,
+		})
+	}
+
+	uci.json.Emit(JsonOptionsEvent{
+		JsonEvent: JsonEvent{"options", uci.json.Id()},
+		Name: uci.Name,
+		Author: uci.Author,
+		Options: options,
+	})
+}
+
+// SetJsonSearchHooks makes the search report its info and best move as events with the id of the go request
+func (uci *Uci) SetJsonSearchHooks(){
+	if uci.json == nil{
+		uci.Pos.InfoHook = nil
+		uci.Pos.BestMoveHook = nil
+		return
+	}
+
+	jo := uci.json
+	id := jo.Id()
+
+	uci.Pos.InfoHook = func(mpi MultiPvInfo){
+		jo.Emit(JsonInfo(id, mpi))
+	}
+
+	uci.Pos.BestMoveHook = func(pv []Move){
+		bestMove, ponder := NullMove, NullMove
+
+		if len(pv) > 0{
+			bestMove = pv[0]
+		}
+
+		if len(pv) > 1{
+			ponder = pv[1]
+		}
+
+		jo.Emit(JsonBestMove(id, bestMove, ponder))
+	}
+}
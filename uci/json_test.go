@@ -0,0 +1,95 @@
+package uci
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+type testEvent struct {
+	Type     string      `json:"type"`
+	Id       interface{} `json:"id"`
+	Fen      string      `json:"fen"`
+	Depth    int         `json:"depth"`
+	BestMove string      `json:"bestmove"`
+	Message  string      `json:"message"`
+}
+
+func TestJsonMode(t *testing.T) {
+	mutex := sync.Mutex{}
+	events := []testEvent{}
+	done := make(chan bool, 1)
+
+	uci := Uci{}
+	uci.SetOutput(OutputFunc(func(text string) {
+		event := testEvent{}
+
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			t.Errorf("output is not json : %s", text)
+		}
+
+		mutex.Lock()
+		events = append(events, event)
+		mutex.Unlock()
+
+		if event.Type == "bestmove" {
+			done <- true
+		}
+	}))
+	// json mode is set before Init, as main does for the --json flag
+	uci.SetJsonMode(true)
+	uci.Init("test", "test", map[string]string{})
+
+	uci.ExecUciCommandLine(`{"id":1,"command":"position startpos moves e2e4"}`)
+	uci.ExecUciCommandLine(`{"id":2,"command":"setoption name No Such Option value 1"}`)
+	uci.ExecUciCommandLine(`{"id":3`)
+	uci.ExecUciCommandLine(`{"id":4,"command":"nosuchcommand"}`)
+	uci.ExecUciCommandLine(`{"id":"go","command":"go depth 2"}`)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no best move")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	expected := []struct {
+		typ string
+		id  interface{}
+	}{
+		{"position", 1.0},
+		{"error", 2.0},
+		{"error", nil},
+		{"error", 4.0},
+		{"info", "go"},
+		{"info", "go"},
+		{"bestmove", "go"},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events got %v", len(expected), events)
+	}
+
+	for i, e := range expected {
+		if events[i].Type != e.typ || events[i].Id != e.id {
+			t.Errorf("event %d expected %s id %v got %s id %v", i, e.typ, e.id, events[i].Type, events[i].Id)
+		}
+	}
+
+	if events[0].Fen != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1" {
+		t.Errorf("wrong fen %s", events[0].Fen)
+	}
+
+	if events[3].Message != "warning : unknown command or illegal move" {
+		t.Errorf("unknown command should be an error, got %v", events[3])
+	}
+
+	if events[5].Depth != 2 || events[6].BestMove == "" {
+		t.Errorf("search events %v %v", events[5], events[6])
+	}
+}
@@ -2,10 +2,12 @@ package uci
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"math/rand"
+	"sync"
 	"time"
 
 	. "github.com/easychessanimations/gobbit/basic"
@@ -17,6 +19,15 @@ type Puzzle struct{
 	Clue   string
 }
 
+// commands that can run while searching, they do not touch the position
+var SEARCH_SAFE_COMMANDS = map[string]bool{
+	"h": true,
+	"help": true,
+	"uci": true,
+	"l": true,
+	"pmt": true,
+}
+
 type Uci struct{
 	Name         string
 	Author       string
@@ -24,25 +35,78 @@ type Uci struct{
 	Pos          Position
 	Aliases      map[string]string
 	MatePuzzles  []Puzzle
+	// stops the running search
+	cancelSearch context.CancelFunc
+	// the running search, Pos belongs to it until it is done
+	searching    sync.WaitGroup
+	// set in json mode
+	json         *JsonOutput
+}
+
+// SetOutput routes the output of the front-end and its position
+func (uci *Uci) SetOutput(out Output){
+	uci.Pos.Out = out
+}
+
+// Println writes the operands separated by spaces as a line
+func (uci *Uci) Println(a ...interface{}){
+	uci.Pos.Println(a...)
+}
+
+// Printf writes the formatted text, the final newline of the format is implied
+func (uci *Uci) Printf(format string, a ...interface{}){
+	uci.Pos.Printf(format, a...)
+}
+
+// SetLogFile appends the search log to the file at path, an empty path turns the log off
+func (uci *Uci) SetLogFile(path string){
+	if uci.Pos.LogFile != nil{
+		if uci.Pos.LogFile.Path == path{
+			return
+		}
+
+		uci.Pos.LogFile.Close()
+		uci.Pos.LogFile = nil
+	}
+
+	if path == ""{
+		return
+	}
+
+	logFile, err := OpenLogFile(path)
+
+	if err != nil{
+		uci.Error(err.Error())
+		return
+	}
+
+	uci.Pos.LogFile = logFile
 }
 
-func (uci Uci) Id() string{
+func (uci *Uci) Id() string{
 	return fmt.Sprintf("%s multi variant uci engine by %s", uci.Name, uci.Author)
 }
 
-func (uci Uci) ExecUciCommand(){
-	fmt.Printf("id name %s\n", uci.Name)
-	fmt.Printf("id author %s\n\n", uci.Author)
+func (uci *Uci) ExecUciCommand(){
+	if uci.JsonMode(){
+		uci.EmitOptions()
+		return
+	}
+
+	uci.Printf("id name %s\n", uci.Name)
+	uci.Printf("id author %s\n\n", uci.Author)
 
 	for _, uo := range uci.UciOptions{
-		fmt.Println(uo.UciCommandOutputString())
+		uci.Println(uo.UciCommandOutputString())
 	}
 
-	fmt.Println("uciok")
+	uci.Println("uciok")
 }
 
+// SetVariant starts a new game of the variant
 func (uci *Uci) SetVariant(variant Variant){
 	uci.Pos.Init(variant)
+	uci.Pos.Evals = nil
 }
 
 func (uci *Uci) SetOption(name, value string){
@@ -55,6 +119,26 @@ func (uci *Uci) SetOption(name, value string){
 				uci.SetVariant(VariantNameToVariant(value))
 			}
 
+			if name == "UCI_Chess960"{
+				if uo.BooleanValue(){
+					uci.SetVariant(VariantChess960)
+				}else if uci.Pos.Current().Variant == VariantChess960{
+					uci.SetVariant(VariantStandard)
+				}
+			}
+
+			if name == "Hash"{
+				TransTable.Resize(uo.IntValue())
+			}
+
+			if name == "Threads"{
+				uci.Pos.Threads = uo.IntValue()
+			}
+
+			if name == "Clear Hash"{
+				TransTable.Clear()
+			}
+
 			if  name == "MultiPV"{
 				uci.Pos.MultiPV = uo.IntValue()
 			}
@@ -79,6 +163,34 @@ func (uci *Uci) SetOption(name, value string){
 				uci.Pos.AspirationWindow = uo.BooleanValue()
 			}
 
+			if name == "Quiescence"{
+				uci.Pos.Quiescence = uo.BooleanValue()
+			}
+
+			if name == "OwnBook"{
+				uci.Pos.OwnBook = uo.BooleanValue()
+			}
+
+			if name == "Book File"{
+				uci.Pos.BookFile = uo.StringValue()
+			}
+
+			if name == "Book Depth"{
+				uci.Pos.BookDepth = uo.IntValue()
+			}
+
+			if name == "Best Book Move"{
+				uci.Pos.BestBookMove = uo.BooleanValue()
+			}
+
+			if name == "Verify Hash"{
+				VerifyHash = uo.BooleanValue()
+			}
+
+			if name == "Perft Hash"{
+				uci.Pos.PerftHash = uo.BooleanValue()
+			}
+
 			if name == "Verbose"{
 				uci.Pos.Verbose = uo.BooleanValue()
 			}
@@ -91,29 +203,37 @@ func (uci *Uci) SetOption(name, value string){
 				uci.Pos.PruningReduction = uo.IntValue()
 			}
 
+			if name == "Duck Squares"{
+				uci.Pos.DuckSquares = uo.IntValue()
+			}
+
+			if name == "Move Overhead"{
+				uci.Pos.MoveOverhead = uo.IntValue()
+			}
+
 			if name == "Log File"{
-				uci.Pos.LogFilePath = uo.Value
+				uci.SetLogFile(uo.Value)
 			}
 
 			return
 		}
 	}
 
-	fmt.Println("unknown option")
+	uci.Error("unknown option")
 }
 
 func (uci *Uci) ExecSetOptionCommand(t *Tokenizer){
 	nameToken, ok := t.GetToken()
 
 	if (!ok) || nameToken != "name"{
-		fmt.Println("expected name")
+		uci.Error("expected name")
 		return
 	}
 
 	nameParts := t.GetTokensUpTo("value")
 
 	if len(nameParts) == 0{
-		fmt.Println("option name missing")
+		uci.Error("option name missing")
 		return
 	}
 
@@ -128,20 +248,29 @@ func (uci *Uci) ExecPositionCommand(t *Tokenizer){
 	token, ok := t.GetToken()
 
 	if !ok{
-		fmt.Println("missing position specifier")
+		uci.Error("missing position specifier")
 		return
 	}
 	if token == "startpos" || token == "s"{
 		uci.Pos.Reset()				
+	}else if token == "960"{
+		// chess960 start position by Scharnagl number, random if no number is given
+		numParts := t.GetTokensUpTo("moves")
+		fen := RandomChess960StartFen()
+		if len(numParts) > 0{
+			numT := Tokenizer{Content: numParts[0]}
+			fen = Chess960StartFen(numT.GetInt())
+		}
+		uci.Pos.ParseFen(fen)
 	}else if token == "fen" || token == "f"{
 		fenParts := t.GetTokensUpTo("moves")
 		if len(fenParts) < 4{
-			fmt.Println("too few fen fields")
+			uci.Error("too few fen fields")
 			return
 		}
 		uci.Pos.ParseFen(strings.Join(fenParts, " "))
 	}else{
-		fmt.Println("unknown position specifier")		
+		uci.Error("unknown position specifier")		
 	}
 
 	moves := t.GetTokensUpTo("")
@@ -156,6 +285,8 @@ func (uci *Uci) ExecPositionCommand(t *Tokenizer){
 func (uci *Uci) ExecGoCommand(t *Tokenizer){
 	depth := DEFAULT_DEPTH
 
+	limits := SearchLimits{}
+
 	for true{
 		token, ok := t.GetToken()
 
@@ -186,17 +317,123 @@ func (uci *Uci) ExecGoCommand(t *Tokenizer){
 			}
 		}
 
+		switch token{
+		case "wtime":
+			limits.WTime = t.GetInt()
+		case "btime":
+			limits.BTime = t.GetInt()
+		case "winc":
+			limits.WInc = t.GetInt()
+		case "binc":
+			limits.BInc = t.GetInt()
+		case "movestogo":
+			limits.MovesToGo = t.GetInt()
+		case "movetime":
+			limits.MoveTime = t.GetInt()
+		case "nodes":
+			limits.Nodes = t.GetInt()
+		case "mate":
+			limits.Mate = t.GetInt()
+		}
+
 		if token == "infinite"{
+			limits.Infinite = true
+		}
+	}
+
+	if limits.Infinite || limits.HasTimeLimit(uci.Pos.Current().Turn) || limits.Nodes > 0 || limits.Mate > 0{
+		if depth == DEFAULT_DEPTH{
+			// unless depth is given explicitly, search is bounded by the limits only
 			depth = SEARCH_MAX_DEPTH
 		}
 	}
 
-	go uci.Pos.Search(depth)
+	uci.Pos.Limits = limits
+
+	uci.StartSearch(depth)
+}
+
+// StartSearch starts the search of the current position in the background, a running search is stopped first
+func (uci *Uci) StartSearch(depth int){
+	uci.StopSearch()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	uci.cancelSearch = cancel
+	uci.Pos.StopSignal = ctx.Done()
+
+	uci.SetJsonSearchHooks()
+
+	uci.searching.Add(1)
+
+	go func(){
+		defer uci.searching.Done()
+
+		uci.Pos.Search(depth)
+	}()
+}
+
+// StopSearch stops the running search and waits until it reported its best move
+func (uci *Uci) StopSearch(){
+	if uci.cancelSearch != nil{
+		uci.cancelSearch()
+	}
+
+	uci.searching.Wait()
+}
+
+func (uci *Uci) ExecLoadPgnCommand(t *Tokenizer){
+	path, ok := t.GetToken()
+
+	if !ok{
+		uci.Error("missing pgn file")
+		return
+	}
+
+	index := 1
+
+	indexToken, ok := t.GetToken()
+
+	if ok{
+		indexT := Tokenizer{Content: indexToken}
+		index = indexT.GetInt()
+	}
+
+	game, err := LoadPgnFile(path, index)
+
+	if err != nil{
+		uci.Error(err.Error())
+		return
+	}
+
+	uci.SetVariant(game.Variant())
+
+	uci.Pos.LoadPgnGame(game)
+
+	uci.Pos.Print()
 }
 
-func (uci Uci) ListUciOptionValues(){
+func (uci *Uci) ExecSavePgnCommand(t *Tokenizer){
+	path, ok := t.GetToken()
+
+	if !ok{
+		uci.Error("missing pgn file")
+		return
+	}
+
+	err := uci.Pos.SavePgnFile(path)
+
+	if err != nil{
+		uci.Error(err.Error())
+		return
+	}
+
+	uci.Println("saved", path)
+}
+
+func (uci *Uci) ListUciOptionValues(){
 	for _, uo := range uci.UciOptions{
-		fmt.Printf("%-30s = %s\n", uo.Name, uo.StringValue())
+		uci.Printf("%-30s = %s\n", uo.Name, uo.StringValue())
 	}
 }
 
@@ -207,44 +444,72 @@ func (uci *Uci) NextPuzzle(){
 		fen := puzzle.Fen
 		uci.Pos.ParseFen(fen)
 		uci.Pos.Print()
-		fmt.Println(puzzle.Event)
-		fmt.Println(puzzle.Clue)
+		uci.Println(puzzle.Event)
+		uci.Println(puzzle.Clue)
 	}else{
-		fmt.Println("no mate puzzle")
+		uci.Println("no mate puzzle")
 	}
 }
 
 func (uci *Uci) ExecUciCommandLine(commandLine string) error{
+	if uci.JsonMode() && strings.HasPrefix(commandLine, "{"){
+		return uci.ExecJsonRequest(commandLine)
+	}
+
 	alias, ok := uci.Aliases[commandLine]
 
 	if ok{
 		commandLine = alias
-		fmt.Println(commandLine)
+		uci.Println(commandLine)
 	}
 
-	t := Tokenizer{commandLine}
+	t := Tokenizer{Content: commandLine}
 
 	command, ok := t.GetToken()
 
 	if !ok{
-		fmt.Println("no command")
+		uci.Error("no command")
 		return nil
 	}
 
+	if !SEARCH_SAFE_COMMANDS[command]{
+		// the search reads Pos all the time, other commands wait until it stopped
+		uci.StopSearch()
+	}
+
 	if command == "x" || command == "q" || command == "quit" {
+		if uci.Pos.LogFile != nil{
+			uci.Pos.LogFile.Flush()
+		}
+
 		return fmt.Errorf("exit")
 	} else if command == "h" || command == "help" {
-		fmt.Println("h, help = help")
-		fmt.Println("x, q, quit = quit")
-		fmt.Println("i = print position")
-		fmt.Println("l = list uci option values")
-		fmt.Println("pmt = print material table")
-		fmt.Println("g = go depth 20")
-		fmt.Println("s = stop")
-		fmt.Println("d = del")
-		fmt.Println("f = forward")
-		fmt.Println("b = to begin")
-		fmt.Println("u = next puzzle")
+		uci.Println("h, help = help")
+		uci.Println("x, q, quit = quit")
+		uci.Println("i = print position")
+		uci.Println("l = list uci option values")
+		uci.Println("pmt = print material table")
+		uci.Println("g = go depth 20")
+		uci.Println("s = stop")
+		uci.Println("d = del")
+		uci.Println("f = forward")
+		uci.Println("b = to begin")
+		uci.Println("u = next puzzle")
+		uci.Println("result = print game result with reason")
+		uci.Println("book = list book moves")
+		uci.Println("perft <depth> = count leaves of the move tree")
+		uci.Println("divide <depth> = perft for each move")
+		uci.Println("perftsuite <file> [maxdepth] = run perft tests of an epd file")
+		uci.Println("verifyhash <depth> = perft checking zobrist keys against keys computed from scratch")
+		uci.Println("loadpgn <file> [game#] = load game from pgn file")
+		uci.Println("savepgn <file> = save current line with evals to pgn file")
+		uci.Println("json [on|off] = json mode, events and requests as json objects")
+	}else if command == "json"{
+		mode, _ := t.GetToken()
+		uci.SetJsonMode(mode != "off")
+	}else if command == "ucinewgame"{
+		// evaluations of the previous game do not belong to the next one
+		uci.Pos.Evals = nil
 	}else if command == "uci"{
 		uci.ExecUciCommand()
 	}else if command == "position" || command == "p"{
@@ -258,18 +523,54 @@ func (uci *Uci) ExecUciCommandLine(commandLine string) error{
 	}else if command == "l"{
 		uci.ListUciOptionValues()
 	} else if command == "pmt" {
-		fmt.Println(PieceMaterialTablesString())
+		uci.Println(PieceMaterialTablesString())
 	} else if command == "g" {
-		go uci.Pos.Search(20)
+		uci.Pos.Limits = SearchLimits{}
+		uci.StartSearch(20)
 	} else if command == "s" || command == "stop" {
-		uci.Pos.SearchStopped = true
+		uci.StopSearch()
 	} else if command == "b" {
-		uci.Pos.StatePtr = 0
+		uci.Pos.PopAll()
 		uci.Pos.Print()
 	} else if command == "u"{
 		uci.NextPuzzle()
+	} else if command == "result"{
+		uci.Pos.ExecResultCommand()
+	} else if command == "book"{
+		uci.Pos.ExecBookCommand()
+	} else if command == "perft" || command == "divide" || command == "verifyhash"{
+		depth := t.GetInt()
+		if err := uci.Pos.CheckPerftDepth(depth); err != nil{
+			uci.Error(err.Error())
+		}else if command == "perft"{
+			uci.Pos.ExecPerftCommand(depth)
+		}else if command == "verifyhash"{
+			uci.Pos.ExecVerifyHashCommand(depth)
+		}else{
+			uci.Pos.ExecDivideCommand(depth)
+		}
+	} else if command == "perftsuite"{
+		path, ok := t.GetToken()
+		if !ok{
+			uci.Error("missing epd file")
+		}else{
+			maxDepth := t.GetInt()
+			if maxDepth < 1{
+				maxDepth = PERFT_SUITE_MAX_DEPTH
+			}
+			if maxDepth >= MAX_STATES{
+				maxDepth = MAX_STATES - 1
+			}
+			uci.Pos.ExecPerftSuiteCommand(path, maxDepth)
+		}
+	} else if command == "loadpgn"{
+		uci.ExecLoadPgnCommand(&t)
+	} else if command == "savepgn"{
+		uci.ExecSavePgnCommand(&t)
 	} else {
-		uci.Pos.ExecCommand(command)
+		if err := uci.Pos.ExecCommand(command); err != nil{
+			uci.Error(err.Error())
+		}
 	}
 
 	return nil
@@ -278,10 +579,15 @@ func (uci *Uci) ExecUciCommandLine(commandLine string) error{
 func (uci *Uci) Init(name string, author string, aliases map[string]string){
 	uci.Name = name
 	uci.Author = author
-	uci.UciOptions = UCI_OPTIONS
 	uci.Aliases = aliases
 
-	uci.Pos = Position{}
+	uci.ProcessVariants()
+
+	// the options of the front-end are its own, the variants of the variants file are listed
+	uci.UciOptions = NewUciOptions()
+
+	// the output and the json mode set before Init are kept
+	uci.Pos = Position{Out: uci.Pos.Out, PrintHook: uci.Pos.PrintHook}
 
 	uci.SetVariant(DEFAULT_VARIANT)
 
@@ -290,9 +596,9 @@ func (uci *Uci) Init(name string, author string, aliases map[string]string){
 	}
 }
 
-func (uci Uci) Welcome(build string){	
-	fmt.Println(uci.Id() + build)
-	fmt.Println()
+func (uci *Uci) Welcome(build string){	
+	uci.Println(uci.Id() + build)
+	uci.Println()
 }
 
 func (uci *Uci) ProcessConfigLine(line string){
@@ -305,6 +611,27 @@ func (uci *Uci) ProcessConfigLine(line string){
 	uci.ExecUciCommandLine(line)	
 }
 
+// ProcessVariants registers the variants of the variants file and reports an invalid file
+func (uci *Uci) ProcessVariants(){
+	if err := LoadVariants(); err != nil{
+		uci.Error(VARIANTS_FILE + " " + err.Error())
+	}
+}
+
+var loadVariantsOnce sync.Once
+var loadVariantsErr error
+
+// LoadVariants registers the variants of the variants file once per process, a missing file is not an error
+func LoadVariants() error{
+	loadVariantsOnce.Do(func(){
+		if _, err := LoadVariantsIni(VARIANTS_FILE); err != nil && !os.IsNotExist(err){
+			loadVariantsErr = err
+		}
+	})
+
+	return loadVariantsErr
+}
+
 func (uci *Uci) ProcessConfig(){
 	IterateTextFile("engineconfig.txt", uci.ProcessConfigLine)
 }
@@ -334,11 +661,29 @@ func (uci *Uci) ProcessMatePuzzles(){
 	IterateTextFile("matein4.txt", uci.ProcessMatePuzzleLine)
 }
 
+// the command line flag that turns json mode on
+const JSON_FLAG = "--json"
+
+// ProcessFlags handles the flags of the command line, it has to be called before Init and before anything is written
+func (uci *Uci) ProcessFlags(){
+	for _, arg := range os.Args[1:]{
+		if arg == JSON_FLAG{
+			uci.SetJsonMode(true)
+		}
+	}
+}
+
 func (uci *Uci) ProcessCommandLine(){
-	args := os.Args[1:]	
+	args := []string{}
+
+	for _, arg := range os.Args[1:]{
+		if arg != JSON_FLAG{
+			args = append(args, arg)
+		}
+	}
 
 	if len(args) > 0{
-		fmt.Println("++++ processing command line")
+		uci.Println("++++ processing command line")
 
 		if args[0] == "a"{
 			fen := strings.Join(args[1:], " ")
@@ -346,17 +691,15 @@ func (uci *Uci) ProcessCommandLine(){
 			uci.Pos.ParseFen(fen)
 			uci.Pos.Print()
 
-			fmt.Println("++++ analyzing fen", fen)
-			fmt.Println()
+			uci.Println("++++ analyzing fen", fen)
+			uci.Println()
 
-			go uci.Pos.Search(20)
+			uci.StartSearch(20)
 		}
 	}
 }
 
-func (uci *Uci) UciLoop(){	
-	scan := bufio.NewScanner(os.Stdin)
-
+func (uci *Uci) UciLoop(scan *bufio.Scanner){	
 	for scan.Scan() {
 		line := scan.Text()
 
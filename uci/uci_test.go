@@ -0,0 +1,152 @@
+package uci
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/easychessanimations/gobbit/basic"
+)
+
+func TestInitTwice(t *testing.T) {
+	lines := []string{}
+
+	ucis := []*Uci{{}, {}}
+
+	for _, uci := range ucis {
+		uci.SetOutput(OutputFunc(func(text string) {
+			lines = append(lines, text)
+		}))
+		uci.Init("test", "test", map[string]string{})
+	}
+
+	if len(lines) > 0 {
+		t.Errorf("init should not report anything, got %v", lines)
+	}
+
+	ucis[0].SetOption("UCI_Variant", "Atomic")
+
+	for _, options := range [][]UciOption{UCI_OPTIONS, ucis[1].UciOptions} {
+		for _, uo := range options {
+			if uo.Name == "UCI_Variant" && uo.Value == "Atomic" {
+				t.Errorf("options of a front-end should be its own")
+			}
+		}
+	}
+
+	if ucis[1].Pos.Current().Variant != VariantStandard {
+		t.Errorf("the second front-end should play standard")
+	}
+}
+
+func TestStopBeforePosition(t *testing.T) {
+	mutex := sync.Mutex{}
+	bestMoves := 0
+
+	uci := Uci{}
+	uci.SetOutput(OutputFunc(func(text string) {
+		if strings.HasPrefix(text, "bestmove") {
+			mutex.Lock()
+			bestMoves++
+			mutex.Unlock()
+		}
+	}))
+	uci.Init("test", "test", map[string]string{})
+
+	for _, commandLine := range []string{
+		"setoption name Threads value 4",
+		"setoption name MultiPV value 2",
+		"go infinite",
+		"stop",
+		"position startpos moves e2e4",
+		"go depth 5",
+		"go infinite",
+		"position startpos moves d2d4",
+		"go depth 4",
+	} {
+		uci.ExecUciCommandLine(commandLine)
+
+		if commandLine == "go infinite" {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	uci.StopSearch()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// every search reported its best move before the next command went on
+	if bestMoves != 4 {
+		t.Errorf("expected 4 best moves got %d", bestMoves)
+	}
+}
+
+func TestThreadsNodes(t *testing.T) {
+	mutex := sync.Mutex{}
+	infoNodes := []int{}
+
+	uci := Uci{}
+	uci.SetOutput(OutputFunc(func(text string) {
+		fields := strings.Fields(text)
+
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[0] == "info" && fields[i] == "nodes" {
+				nodes, _ := strconv.Atoi(fields[i+1])
+
+				mutex.Lock()
+				infoNodes = append(infoNodes, nodes)
+				mutex.Unlock()
+			}
+		}
+	}))
+	uci.Init("test", "test", map[string]string{})
+	uci.ExecUciCommandLine("setoption name Threads value 4")
+
+	for _, commandLine := range []string{"go infinite", "stop", "position startpos moves e2e4", "go depth 5"} {
+		if strings.HasPrefix(commandLine, "go") {
+			mutex.Lock()
+			infoNodes = []int{}
+			mutex.Unlock()
+		}
+
+		uci.ExecUciCommandLine(commandLine)
+
+		if commandLine == "go infinite" {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+
+		if commandLine == "position startpos moves e2e4" {
+			continue
+		}
+
+		// the search stopped or ended by itself
+		uci.searching.Wait()
+
+		smp := uci.Pos.Smp
+
+		if smp == nil || len(smp.Helpers) != 3 {
+			t.Fatalf("%s : expected 3 helpers", commandLine)
+		}
+
+		nodes, qnodes := uci.Pos.Nodes, uci.Pos.QNodes
+
+		for _, helper := range smp.Helpers {
+			nodes += helper.Nodes
+			qnodes += helper.QNodes
+		}
+
+		if uci.Pos.TotalNodes() != nodes || uci.Pos.TotalQNodes() != qnodes {
+			t.Errorf("%s : expected %d nodes %d qnodes over the helpers, got %d %d", commandLine, nodes, qnodes, uci.Pos.TotalNodes(), uci.Pos.TotalQNodes())
+		}
+
+		mutex.Lock()
+		if len(infoNodes) == 0 || infoNodes[len(infoNodes)-1] > nodes {
+			t.Errorf("%s : reported nodes %v exceed the %d nodes searched", commandLine, infoNodes, nodes)
+		}
+		mutex.Unlock()
+	}
+}